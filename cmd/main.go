@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"gobankapi/internal/config"
+	"gobankapi/internal/events"
+	"gobankapi/internal/repositories"
 	"gobankapi/internal/router"
 	"gobankapi/internal/scheduler"
+	"gobankapi/internal/services"
 )
 
 func main() {
@@ -19,15 +28,77 @@ func main() {
 		FullTimestamp: true,
 	})
 
-	r := router.SetupRouter()
+	// ctx отменяется по SIGINT/SIGTERM — с него начинается остановка приложения
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Стартуем автоматический шедулер
-	go scheduler.StartScheduler(config.DB, 12) // каждые 12 часов
+	centralBank := services.NewCentralBankClient()
+	pricing := services.NewCreditPricingService(
+		repositories.NewCreditProductRepository(config.DB),
+		repositories.NewCreditRepository(config.DB),
+		repositories.NewUserRepository(config.DB),
+		centralBank,
+		services.NewMailer(),
+	)
 
-	addr := fmt.Sprintf(":%s", config.AppConfig.Port)
-	logrus.Infof("Сервер запущен на %s", addr)
-	err := http.ListenAndServe(addr, r)
-	if err != nil {
-		logrus.Fatalf("Ошибка запуска сервера: %v", err)
+	// Реестр фоновых задач: по расписанию они выполняются, только если включены для этого
+	// экземпляра, вручную (POST /api/admin/jobs/{job}/run) — в любом
+	jobs := scheduler.NewRegistry(config.DB)
+	if err := scheduler.RegisterJobs(jobs, config.DB, centralBank, pricing); err != nil {
+		logrus.Fatalf("Ошибка регистрации фоновых задач: %v", err)
 	}
+	if config.AppConfig.RunScheduler {
+		jobs.Start()
+	}
+
+	bus := events.NewBus()
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%s", config.AppConfig.Port),
+		Handler: router.SetupRouter(ctx, jobs, bus, centralBank),
+	}
+	go func() {
+		logrus.Infof("Сервер запущен на %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Fatalf("Ошибка запуска сервера: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	shutdown(srv, jobs, bus)
+}
+
+// shutdown останавливает приложение за SHUTDOWN_TIMEOUT_SECONDS: сервер перестаёт принимать
+// соединения и дожидается текущих запросов, фоновые задачи прерываются между записями,
+// обработчики событий завершаются, после чего закрывается пул соединений с БД
+func shutdown(srv *http.Server, jobs *scheduler.Registry, bus *events.Bus) {
+	timeout := time.Duration(config.AppConfig.ShutdownTimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logrus.Info("Остановка сервера...")
+	if err := srv.Shutdown(ctx); err != nil {
+		logrus.Errorf("Не все запросы завершились вовремя: %v", err)
+	}
+
+	logrus.Info("Остановка фоновых задач...")
+	if err := jobs.Shutdown(ctx); err != nil {
+		logrus.Errorf("Фоновые задачи не завершились вовремя: %v", err)
+	}
+
+	handled := make(chan struct{})
+	go func() {
+		bus.Wait()
+		close(handled)
+	}()
+	select {
+	case <-handled:
+	case <-ctx.Done():
+		logrus.Errorf("Обработчики событий не завершились вовремя: %v", ctx.Err())
+	}
+
+	if err := config.DB.Close(); err != nil {
+		logrus.Errorf("Ошибка закрытия соединений с БД: %v", err)
+	}
+	logrus.Info("Сервер остановлен")
 }
@@ -0,0 +1,45 @@
+// Сверка балансов счетов с журналом двойной записи.
+// Выводит счета, у которых сохранённый баланс не равен сумме проводок, и завершается с кодом 1,
+// если такие есть.
+//
+//	go run ./cmd/reconcile
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/repositories"
+)
+
+func main() {
+	config.LoadConfig()
+	config.InitDB()
+	defer config.DB.Close()
+
+	// Ctrl+C прерывает запрос сверки
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ledgerRepo := repositories.NewLedgerRepository(config.DB)
+	mismatches, err := ledgerRepo.Reconcile(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка сверки: %v\n", err)
+		os.Exit(2)
+	}
+
+	if len(mismatches) == 0 {
+		fmt.Println("Расхождений нет: балансы всех счетов совпадают с журналом проводок")
+		return
+	}
+
+	fmt.Printf("%-10s %-8s %15s %15s %15s\n", "account", "currency", "stored", "ledger", "difference")
+	for _, m := range mismatches {
+		fmt.Printf("%-10d %-8s %15s %15s %15s\n", m.AccountID, m.Currency, m.StoredBalance, m.LedgerBalance, m.Difference)
+	}
+	fmt.Printf("Счетов с расхождениями: %d\n", len(mismatches))
+	os.Exit(1)
+}
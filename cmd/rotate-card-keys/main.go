@@ -0,0 +1,47 @@
+// Перешифрование данных карт под активным мастер-ключом.
+// Карты со старой версией ключа и карты, выпущенные до шифрования, получают новый ключ данных,
+// обёрнутый мастер-ключом CARD_KEY_VERSION (по умолчанию наибольшей версии из CARD_MASTER_KEYS).
+// Старую версию можно убрать из конфигурации только после успешного запуска.
+//
+//	go run ./cmd/rotate-card-keys        # карты не под активной версией
+//	go run ./cmd/rotate-card-keys -all   # все карты: после смены CARD_HMAC_KEY или для заполнения first6/brand
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
+)
+
+func main() {
+	all := flag.Bool("all", false, "перешифровать все карты, а не только со старой версией ключа")
+	flag.Parse()
+
+	config.LoadConfig()
+	config.InitDB()
+	defer config.DB.Close()
+
+	// Ctrl+C останавливает перешифрование после текущей карты; повторный запуск продолжит
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cards, err := services.NewCardService(repositories.NewCardRepository(config.DB),
+		repositories.NewUserRepository(config.DB), repositories.NewCardControlRepository(config.DB))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка настройки шифрования карт: %v\n", err)
+		os.Exit(2)
+	}
+
+	rotated, err := cards.RotateKeys(ctx, *all)
+	fmt.Printf("Перешифровано карт: %d (версия ключа %d)\n", rotated, cards.Keys.ActiveVersion())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка перешифрования: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -11,10 +13,83 @@ type Config struct {
 	Port      string
 	JWTSecret string
 	DB_DSN    string
-	SMTPHost  string
-	SMTPPort  string
-	SMTPUser  string
-	SMTPPass  string
+	// Режим разработки: ключи шифрования, которые не заданы, выводятся из JWT_SECRET
+	DevMode  bool
+	SMTPHost string
+	SMTPPort string
+	SMTPUser string
+	SMTPPass string
+
+	// Параметры двухфакторной аутентификации
+	TwoFACodeTTLMinutes int
+	TwoFAMaxAttempts    int
+	TwoFALockoutMinutes int
+	TwoFAThreshold      float64 // операции на сумму выше порога требуют подтверждения кодом
+	TwoFALoginEmail     bool    // вход без TOTP подтверждается кодом из письма
+
+	TOTPIssuer        string
+	TOTPEncryptionKey string // base64, 32 байта; ключ шифрования секретов TOTP
+
+	AccessTokenTTLMinutes int
+	RefreshTokenTTLHours  int
+
+	// Подпись JWT: RS256 или EdDSA (ключи с ротацией, публикуются в JWKS), HS256 — JWTSecret
+	JWTAlgorithm         string
+	JWTKeyRotationHours  int
+	JWTKeyOverlapHours   int    // сколько старый ключ ещё принимается после ротации
+	JWTKeyEncryptionKey  string // base64, 32 байта; шифрование закрытых ключей в БД
+	JWTKeyRefreshMinutes int    // период проверки ротации и перечитывания ключей из БД
+
+	// Шифрование данных карт: мастер-ключи «версия:base64» через запятую или файл с ними
+	// (по одному на строку, файл важнее). Новые карты шифруются версией CardKeyVersion (0 — наибольшая).
+	CardMasterKeys     string
+	CardMasterKeysFile string
+	CardKeyVersion     int
+	CardHMACKey        string // ключ HMAC номеров карт для поиска без расшифровки
+
+	// Авторизации по картам: ключ платёжной системы для /card-network (пустой — маршруты закрыты)
+	// и срок холда, после которого несписанная авторизация истекает
+	CardNetworkAPIKey string
+	CardHoldTTLHours  int
+
+	FXRateMaxAgeHours       int // курс старше этого возраста не используется для конвертации
+	RatesFetchIntervalHours int // период загрузки официальных курсов ЦБ
+
+	// Веб-сервис ЦБ РФ
+	CBRURL               string
+	CBRTimeoutSeconds    int
+	CBRRetries           int
+	CBRRetryBackoffMs    int
+	CBRCacheTTLMinutes   int
+	CBRKeyRateWindowDays int
+	BankRateMargin       float64 // маржа банка к ключевой ставке, п.п.
+
+	// Скоринг заявок на кредит
+	CreditMaxDTI       float64 // предел ежемесячных платежей (текущие + новый кредит) к месячному доходу
+	CreditIncomeMonths int     // за сколько месяцев считается средний доход
+
+	// Законный предел неустойки, % годовых от просроченной суммы (353-ФЗ: 20%, пока начисляются проценты)
+	PenaltyMaxAnnualRate float64
+
+	// Очерёдность погашения частей просроченного платежа, через запятую
+	CollectionWaterfall string
+
+	// Фоновые задачи (шедулер платежей, начисления, загрузка курсов) запускаются только при true.
+	// Несколько процессов с true безопасны: каждый запуск задачи берёт advisory lock в PostgreSQL.
+	RunScheduler bool
+
+	// Расписание фоновых задач: пять полей cron (минута час день месяц день-недели) или «@every 6h»
+	CronPayments      string // списание просроченных платежей
+	CronPenalties     string // неустойка — после списания
+	CronAccruals      string // начисление процентов
+	CronRates         string // курсы ЦБ; по умолчанию раз в RatesFetchIntervalHours
+	CronStatements    string // выписки за прошлый месяц
+	CronCardExpiry    string // истечение срока карт
+	CronCardHolds     string // истечение холдов по авторизациям карт
+	CronRevokedTokens string // удаление истёкших отозванных токенов
+
+	// Сколько ждать завершения запросов и фоновых задач при остановке по SIGTERM
+	ShutdownTimeoutSeconds int
 }
 
 var AppConfig *Config
@@ -25,14 +100,74 @@ func LoadConfig() {
 		log.Println(".env не найден — будут использованы переменные окружения")
 	}
 
+	ratesFetchIntervalHours := getEnvAsInt("RATES_FETCH_INTERVAL_HOURS", 6)
+
 	AppConfig = &Config{
 		Port:      getEnv("PORT", "8080"),
 		JWTSecret: getEnv("JWT_SECRET", "defaultsecret"),
 		DB_DSN:    getEnv("DB_DSN", ""),
+		DevMode:   getEnvAsBool("DEV_MODE", false),
 		SMTPHost:  getEnv("SMTP_HOST", ""),
 		SMTPPort:  getEnv("SMTP_PORT", ""),
 		SMTPUser:  getEnv("SMTP_USER", ""),
 		SMTPPass:  getEnv("SMTP_PASS", ""),
+
+		TwoFACodeTTLMinutes: getEnvAsInt("TWOFA_CODE_TTL_MINUTES", 5),
+		TwoFAMaxAttempts:    getEnvAsInt("TWOFA_MAX_ATTEMPTS", 5),
+		TwoFALockoutMinutes: getEnvAsInt("TWOFA_LOCKOUT_MINUTES", 15),
+		TwoFAThreshold:      getEnvAsFloat("TWOFA_AMOUNT_THRESHOLD", 10000),
+		TwoFALoginEmail:     getEnvAsBool("TWOFA_LOGIN_EMAIL", true),
+
+		TOTPIssuer:        getEnv("TOTP_ISSUER", "GoBank"),
+		TOTPEncryptionKey: getEnv("TOTP_ENCRYPTION_KEY", ""),
+
+		AccessTokenTTLMinutes: getEnvAsInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTLHours:  getEnvAsInt("REFRESH_TOKEN_TTL_HOURS", 720),
+
+		JWTAlgorithm:         getEnv("JWT_ALG", "RS256"),
+		JWTKeyRotationHours:  getEnvAsInt("JWT_KEY_ROTATION_HOURS", 720),
+		JWTKeyOverlapHours:   getEnvAsInt("JWT_KEY_OVERLAP_HOURS", 24),
+		JWTKeyEncryptionKey:  getEnv("JWT_KEY_ENCRYPTION_KEY", ""),
+		JWTKeyRefreshMinutes: getEnvAsInt("JWT_KEY_REFRESH_MINUTES", 5),
+
+		CardMasterKeys:     getEnv("CARD_MASTER_KEYS", ""),
+		CardMasterKeysFile: getEnv("CARD_MASTER_KEYS_FILE", ""),
+		CardKeyVersion:     getEnvAsInt("CARD_KEY_VERSION", 0),
+		CardHMACKey:        getEnv("CARD_HMAC_KEY", ""),
+
+		CardNetworkAPIKey: getEnv("CARD_NETWORK_API_KEY", ""),
+		CardHoldTTLHours:  getEnvAsInt("CARD_HOLD_TTL_HOURS", 168),
+
+		FXRateMaxAgeHours:       getEnvAsInt("FX_RATE_MAX_AGE_HOURS", 72),
+		RatesFetchIntervalHours: ratesFetchIntervalHours,
+
+		CBRURL:               getEnv("CBR_URL", "https://cbr.ru/DailyInfoWebServ/DailyInfo.asmx"),
+		CBRTimeoutSeconds:    getEnvAsInt("CBR_TIMEOUT_SECONDS", 10),
+		CBRRetries:           getEnvAsInt("CBR_RETRIES", 2),
+		CBRRetryBackoffMs:    getEnvAsInt("CBR_RETRY_BACKOFF_MS", 500),
+		CBRCacheTTLMinutes:   getEnvAsInt("CBR_CACHE_TTL_MINUTES", 30),
+		CBRKeyRateWindowDays: getEnvAsInt("CBR_KEY_RATE_WINDOW_DAYS", 30),
+		BankRateMargin:       getEnvAsFloat("BANK_RATE_MARGIN", 5),
+
+		CreditMaxDTI:       getEnvAsFloat("CREDIT_MAX_DTI", 0.5),
+		CreditIncomeMonths: getEnvAsInt("CREDIT_INCOME_MONTHS", 3),
+
+		PenaltyMaxAnnualRate: getEnvAsFloat("PENALTY_MAX_ANNUAL_RATE", 20),
+
+		CollectionWaterfall: getEnv("COLLECTION_WATERFALL", "penalty,interest,principal"),
+
+		RunScheduler: getEnvAsBool("RUN_SCHEDULER", true),
+
+		CronPayments:      getEnv("CRON_PAYMENTS", "0 */12 * * *"),
+		CronPenalties:     getEnv("CRON_PENALTIES", "30 0 * * *"),
+		CronAccruals:      getEnv("CRON_ACCRUALS", "0 1 * * *"),
+		CronRates:         getEnv("CRON_RATES", fmt.Sprintf("@every %dh", ratesFetchIntervalHours)),
+		CronStatements:    getEnv("CRON_STATEMENTS", "0 3 1 * *"),
+		CronCardExpiry:    getEnv("CRON_CARD_EXPIRY", "0 2 * * *"),
+		CronCardHolds:     getEnv("CRON_CARD_HOLDS", "*/15 * * * *"),
+		CronRevokedTokens: getEnv("CRON_REVOKED_TOKENS", "0 4 * * *"),
+
+		ShutdownTimeoutSeconds: getEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 30),
 	}
 }
 
@@ -42,3 +177,33 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvAsInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if num, err := strconv.Atoi(value); err == nil {
+			return num
+		}
+		log.Printf("Некорректное значение %s=%q — используется %d", key, value, fallback)
+	}
+	return fallback
+}
+
+func getEnvAsFloat(key string, fallback float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if num, err := strconv.ParseFloat(value, 64); err == nil {
+			return num
+		}
+		log.Printf("Некорректное значение %s=%q — используется %v", key, value, fallback)
+	}
+	return fallback
+}
+
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		log.Printf("Некорректное значение %s=%q — используется %v", key, value, fallback)
+	}
+	return fallback
+}
@@ -0,0 +1,20 @@
+package events
+
+import "gobankapi/internal/money"
+
+const AccountFundedEvent = "account.funded"
+
+// Источники зачисления
+const (
+	FundingDeposit  = "deposit"
+	FundingTransfer = "transfer"
+)
+
+// AccountFunded — на счёт зачислены деньги (транзакция уже закоммичена)
+type AccountFunded struct {
+	AccountID int
+	Amount    money.Money // зачисленная сумма в валюте счёта
+	Source    string      // deposit | transfer
+}
+
+func (AccountFunded) Name() string { return AccountFundedEvent }
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Event — доменное событие. Name — ключ подписки.
+type Event interface {
+	Name() string
+}
+
+// Handler получает контекст публикации без отмены: обработчик работает и после того,
+// как запрос, в котором произошло событие, завершился, но видит его значения
+type Handler func(ctx context.Context, e Event)
+
+// Bus — внутренняя шина доменных событий. Обработчики вызываются асинхронно, каждый в своей
+// горутине: публикующий (например, репозиторий после коммита транзакции) их не ждёт,
+// а паника одного обработчика не затрагивает остальных.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	wg       sync.WaitGroup
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe регистрирует обработчик событий с именем name
+func (b *Bus) Subscribe(name string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], h)
+}
+
+// Publish передаёт событие всем подписчикам. Безопасен для nil-шины — событие просто теряется.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	b.mu.RLock()
+	handlers := b.handlers[e.Name()]
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		b.wg.Add(1)
+		go func(h Handler) {
+			defer b.wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Паника в обработчике события %s: %v", e.Name(), r)
+				}
+			}()
+			h(ctx, e)
+		}(h)
+	}
+}
+
+// Wait ждёт завершения запущенных обработчиков
+func (b *Bus) Wait() {
+	b.wg.Wait()
+}
@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"gobankapi/internal/middleware"
 	"gobankapi/internal/models"
+	"gobankapi/internal/money"
 	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -16,32 +22,64 @@ type AccountHandler struct {
 	AccountRepo     *repositories.AccountRepository
 	TransactionRepo *repositories.TransactionRepository
 	ScheduleRepo    *repositories.PaymentScheduleRepository
+	LedgerRepo      *repositories.LedgerRepository
+	StatementRepo   *repositories.StatementRepository
+	TwoFA           *services.TwoFactorService
+	FX              *services.ExchangeService
 }
 
 func NewAccountHandler(
 	accRepo *repositories.AccountRepository,
 	txRepo *repositories.TransactionRepository,
 	schedRepo *repositories.PaymentScheduleRepository,
+	ledgerRepo *repositories.LedgerRepository,
+	statementRepo *repositories.StatementRepository,
+	twoFA *services.TwoFactorService,
+	fx *services.ExchangeService,
 ) *AccountHandler {
 	return &AccountHandler{
 		AccountRepo:     accRepo,
 		TransactionRepo: txRepo,
 		ScheduleRepo:    schedRepo,
+		LedgerRepo:      ledgerRepo,
+		StatementRepo:   statementRepo,
+		TwoFA:           twoFA,
+		FX:              fx,
 	}
 }
 
+type CreateAccountRequest struct {
+	Currency string `json:"currency"` // ISO 4217, по умолчанию RUB
+}
+
 // POST /accounts
 func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
 	userID, _ := strconv.Atoi(userIDStr)
 
+	// Тело необязательно: без него открывается рублёвый счёт
+	var req CreateAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if !money.IsSupported(currency) {
+		http.Error(w, "Unsupported currency", http.StatusBadRequest)
+		return
+	}
+
 	account := &models.Account{
-		UserID:  userID,
-		Number:  generateAccountNumber(userID),
-		Balance: 0,
+		UserID:   userID,
+		Number:   generateAccountNumber(userID),
+		Currency: currency,
+		Balance:  money.Zero(currency),
 	}
 
-	err := h.AccountRepo.Create(account)
+	err := h.AccountRepo.Create(r.Context(), account)
 	if err != nil {
 		http.Error(w, "Could not create account", http.StatusInternalServerError)
 		return
@@ -56,7 +94,7 @@ func (h *AccountHandler) GetUserAccounts(w http.ResponseWriter, r *http.Request)
 	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
 	userID, _ := strconv.Atoi(userIDStr)
 
-	accounts, err := h.AccountRepo.FindByUserID(userID)
+	accounts, err := h.AccountRepo.FindByUserID(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Could not fetch accounts", http.StatusInternalServerError)
 		return
@@ -71,9 +109,12 @@ func generateAccountNumber(userID int) string {
 	return "ACC" + strconv.Itoa(userID) + time.Now().Format("20060102150405")
 }
 
+// Сумма указывается в валюте счёта; currency, если передана, должна с ней совпадать
 type BalanceRequest struct {
-	AccountID int     `json:"account_id"`
-	Amount    float64 `json:"amount"`
+	AccountID int         `json:"account_id"`
+	Amount    json.Number `json:"amount"`
+	Currency  string      `json:"currency,omitempty"`
+	StepUp
 }
 
 // POST /accounts/deposit
@@ -82,22 +123,21 @@ func (h *AccountHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 	userID, _ := strconv.Atoi(userIDStr)
 
 	var req BalanceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
-
-	err := h.AccountRepo.UpdateBalance(req.AccountID, userID, req.Amount)
+	amount, err := h.requestAmount(r.Context(), req.AccountID, userID, req.Amount, req.Currency)
 	if err != nil {
-		http.Error(w, "Deposit failed", http.StatusInternalServerError)
+		writeBalanceError(w, "Invalid request", err)
 		return
 	}
 
-	_ = h.TransactionRepo.Log(&models.Transaction{
-		ToAccountID: &req.AccountID,
-		Amount:      req.Amount,
-		Type:        "deposit",
-	})
+	err = h.AccountRepo.Deposit(r.Context(), req.AccountID, userID, amount)
+	if err != nil {
+		writeBalanceError(w, "Deposit failed", err)
+		return
+	}
 
 	w.Write([]byte(`{"status":"ok","action":"deposit"}`))
 }
@@ -108,31 +148,37 @@ func (h *AccountHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	userID, _ := strconv.Atoi(userIDStr)
 
 	var req BalanceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
-
-	// Отрицательное значение для списания
-	err := h.AccountRepo.UpdateBalance(req.AccountID, userID, -req.Amount)
+	amount, err := h.requestAmount(r.Context(), req.AccountID, userID, req.Amount, req.Currency)
 	if err != nil {
-		http.Error(w, "Withdraw failed", http.StatusInternalServerError)
+		writeBalanceError(w, "Invalid request", err)
 		return
 	}
 
-	_ = h.TransactionRepo.Log(&models.Transaction{
-		FromAccountID: &req.AccountID,
-		Amount:        -req.Amount,
-		Type:          "withdraw",
-	})
+	purpose := services.OperationPurpose("withdraw", req.AccountID, amount, amount.Currency)
+	if !confirmOperation(r.Context(), w, h.TwoFA, userID, amount, purpose, req.StepUp) {
+		return
+	}
+
+	err = h.AccountRepo.Withdraw(r.Context(), req.AccountID, userID, amount)
+	if err != nil {
+		writeBalanceError(w, "Withdraw failed", err)
+		return
+	}
 
 	w.Write([]byte(`{"status":"ok","action":"withdraw"}`))
 }
 
+// Сумма указывается в валюте счёта отправителя и при необходимости конвертируется
 type TransferRequest struct {
-	FromAccountID int     `json:"from_account_id"`
-	ToAccountID   int     `json:"to_account_id"`
-	Amount        float64 `json:"amount"`
+	FromAccountID int         `json:"from_account_id"`
+	ToAccountID   int         `json:"to_account_id"`
+	Amount        json.Number `json:"amount"`
+	Currency      string      `json:"currency,omitempty"`
+	StepUp
 }
 
 func (h *AccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
@@ -140,30 +186,26 @@ func (h *AccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 	userID, _ := strconv.Atoi(userIDStr)
 
 	var req TransferRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
-
-	err := h.AccountRepo.Transfer(req.FromAccountID, req.ToAccountID, userID, req.Amount)
+	amount, err := h.requestAmount(r.Context(), req.FromAccountID, userID, req.Amount, req.Currency)
 	if err != nil {
-		http.Error(w, "Transfer failed: "+err.Error(), http.StatusBadRequest)
+		writeBalanceError(w, "Invalid request", err)
 		return
 	}
 
-	// списание со счёта отправителя
-	_ = h.TransactionRepo.Log(&models.Transaction{
-		FromAccountID: &req.FromAccountID,
-		Amount:        -req.Amount,
-		Type:          "transfer",
-	})
+	purpose := services.OperationPurpose("transfer", req.FromAccountID, req.ToAccountID, amount, amount.Currency)
+	if !confirmOperation(r.Context(), w, h.TwoFA, userID, amount, purpose, req.StepUp) {
+		return
+	}
 
-	// пополнение счёта получателя
-	_ = h.TransactionRepo.Log(&models.Transaction{
-		ToAccountID: &req.ToAccountID,
-		Amount:      req.Amount,
-		Type:        "transfer",
-	})
+	err = h.AccountRepo.Transfer(r.Context(), req.FromAccountID, req.ToAccountID, userID, amount, h.FX)
+	if err != nil {
+		writeBalanceError(w, "Transfer failed", err)
+		return
+	}
 
 	w.Write([]byte(`{"status":"ok","action":"transfer"}`))
 }
@@ -187,7 +229,7 @@ func (h *AccountHandler) PredictBalance(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Текущий баланс
-	currentBalance, err := h.AccountRepo.GetBalance(accountID, userID)
+	currentBalance, err := h.AccountRepo.GetBalance(r.Context(), accountID, userID)
 	if err != nil {
 		http.Error(w, "Could not fetch balance", http.StatusInternalServerError)
 		return
@@ -195,13 +237,13 @@ func (h *AccountHandler) PredictBalance(w http.ResponseWriter, r *http.Request)
 
 	// Платежи в течение N дней
 	until := time.Now().AddDate(0, 0, days)
-	scheduled, err := h.ScheduleRepo.GetScheduledPayments(accountID, until)
+	scheduled, err := h.ScheduleRepo.GetScheduledPayments(r.Context(), accountID, until)
 	if err != nil {
 		http.Error(w, "Could not fetch payments", http.StatusInternalServerError)
 		return
 	}
 
-	expectedBalance := currentBalance - scheduled
+	expectedBalance := currentBalance.Sub(scheduled)
 
 	resp := map[string]interface{}{
 		"account_id":               accountID,
@@ -212,3 +254,96 @@ func (h *AccountHandler) PredictBalance(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// GET /accounts/{accountId}/ledger — сверка баланса счёта с журналом проводок
+func (h *AccountHandler) LedgerBalance(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	accountID, err := strconv.Atoi(mux.Vars(r)["accountId"])
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	stored, err := h.AccountRepo.GetBalance(r.Context(), accountID, userID)
+	if err != nil {
+		http.Error(w, "Could not fetch balance", http.StatusNotFound)
+		return
+	}
+
+	ledger, err := h.LedgerRepo.AccountBalance(r.Context(), accountID)
+	if err != nil {
+		http.Error(w, "Could not fetch ledger balance", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"account_id":     accountID,
+		"stored_balance": stored,
+		"ledger_balance": ledger,
+		"consistent":     stored.Cmp(ledger) == 0,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /accounts/{accountId}/statements — ежемесячные выписки по счёту, новые первыми
+func (h *AccountHandler) GetStatements(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	accountID, err := strconv.Atoi(mux.Vars(r)["accountId"])
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	statements, err := h.StatementRepo.FindByAccountID(r.Context(), accountID, userID)
+	if err != nil {
+		http.Error(w, "Could not fetch statements", http.StatusInternalServerError)
+		return
+	}
+	if statements == nil {
+		statements = []*models.Statement{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statements)
+}
+
+// requestAmount разбирает сумму запроса в валюте счёта владельца
+func (h *AccountHandler) requestAmount(ctx context.Context, accountID, userID int, raw json.Number, currency string) (money.Money, error) {
+	accountCurrency, err := h.AccountRepo.GetCurrency(ctx, accountID, userID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if currency != "" && !strings.EqualFold(currency, accountCurrency) {
+		return money.Money{}, repositories.ErrCurrencyMismatch
+	}
+	amount, err := money.Parse(raw.String(), accountCurrency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if !amount.IsPositive() {
+		return money.Money{}, money.ErrInvalidAmount
+	}
+	return amount, nil
+}
+
+func writeBalanceError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, money.ErrInvalidAmount),
+		errors.Is(err, repositories.ErrCurrencyMismatch),
+		errors.Is(err, repositories.ErrSameAccount):
+		http.Error(w, msg+": "+err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrRateUnavailable):
+		http.Error(w, msg+": "+err.Error(), http.StatusConflict)
+	case errors.Is(err, repositories.ErrInsufficientFunds):
+		http.Error(w, msg+": "+err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrAccountNotFound):
+		http.Error(w, msg+": "+err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gobankapi/internal/models"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/scheduler"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Сколько запусков отдаёт история по умолчанию и максимум
+const (
+	defaultJobRunsLimit = 50
+	maxJobRunsLimit     = 500
+)
+
+type AdminHandler struct {
+	Jobs *scheduler.Registry
+	Runs *repositories.SchedulerRunRepository
+}
+
+func NewAdminHandler(jobs *scheduler.Registry, runs *repositories.SchedulerRunRepository) *AdminHandler {
+	return &AdminHandler{Jobs: jobs, Runs: runs}
+}
+
+// GET /admin/jobs — зарегистрированные задачи, их расписание и ближайший запуск
+func (h *AdminHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Jobs.Jobs())
+}
+
+// POST /admin/jobs/{job}/run — запуск задачи вне расписания (выполняется в фоне)
+func (h *AdminHandler) RunJob(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["job"]
+	err := h.Jobs.Trigger(name)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		http.Error(w, "Unknown job", http.StatusNotFound)
+		return
+	case errors.Is(err, scheduler.ErrSchedulerStopped):
+		http.Error(w, "Scheduler is shutting down", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		http.Error(w, "Could not start job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"job":    name,
+		"status": "triggered",
+	})
+}
+
+// GET /admin/jobs/runs?job=payments&limit=50 — история запусков, новые первыми
+func (h *AdminHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultJobRunsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxJobRunsLimit)
+	}
+
+	runs, err := h.Runs.Recent(r.Context(), r.URL.Query().Get("job"), limit)
+	if err != nil {
+		http.Error(w, "Could not fetch job runs", http.StatusInternalServerError)
+		return
+	}
+	if runs == nil {
+		runs = []*models.SchedulerRun{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
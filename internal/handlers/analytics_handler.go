@@ -24,7 +24,7 @@ func (h *AnalyticsHandler) GetCreditLoad(w http.ResponseWriter, r *http.Request)
 	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
 	userID, _ := strconv.Atoi(userIDStr)
 
-	total, err := h.CreditRepo.GetActiveCreditLoad(userID)
+	total, err := h.CreditRepo.GetActiveCreditLoad(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Could not fetch credit load", http.StatusInternalServerError)
 		return
@@ -1,22 +1,59 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"gobankapi/internal/middleware"
 	"gobankapi/internal/models"
+	"gobankapi/internal/money"
 	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
 	"gobankapi/internal/utils"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/gorilla/mux"
 )
 
 type CardHandler struct {
 	CardRepo *repositories.CardRepository
+	Cards    *services.CardService
+}
+
+func NewCardHandler(repo *repositories.CardRepository, cards *services.CardService) *CardHandler {
+	return &CardHandler{CardRepo: repo, Cards: cards}
 }
 
-func NewCardHandler(repo *repositories.CardRepository) *CardHandler {
-	return &CardHandler{CardRepo: repo}
+// CardResponse — карта без расшифровки: по токену, номер маскирован, срок — только дата окончания
+type CardResponse struct {
+	Token           string     `json:"token"`
+	AccountID       int        `json:"account_id"`
+	MaskedPAN       string     `json:"masked_pan"`
+	Brand           string     `json:"brand"`
+	Status          string     `json:"status"`
+	BlockReason     string     `json:"block_reason,omitempty"`
+	ReissuedFrom    string     `json:"reissued_from,omitempty"`
+	ExpiresOn       *time.Time `json:"expires_on,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	CreatedAt       string     `json:"created_at"`
+}
+
+func newCardResponse(c *models.Card) CardResponse {
+	return CardResponse{
+		Token:           c.Token,
+		AccountID:       c.AccountID,
+		MaskedPAN:       utils.MaskPAN(c.First6, c.Last4),
+		Brand:           c.Brand,
+		Status:          c.Status,
+		BlockReason:     c.BlockReason,
+		ReissuedFrom:    c.ReissuedFromToken,
+		ExpiresOn:       c.ExpiresOn,
+		StatusChangedAt: c.StatusChangedAt,
+		CreatedAt:       c.CreatedAt.Format(time.RFC3339),
+	}
 }
 
 type CreateCardRequest struct {
@@ -34,76 +71,251 @@ func (h *CardHandler) CreateCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cardNumber := utils.GenerateCardNumber()
-	expiry := utils.GenerateExpiryDate()
-	cvv := utils.GenerateCVV()
+	issued, err := h.Cards.Issue(r.Context(), userID, req.AccountID)
+	if errors.Is(err, repositories.ErrAccountNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Выпуск карты: %v\n", err)
+		http.Error(w, "Could not create card", http.StatusInternalServerError)
+		return
+	}
+
+	writeIssuedCard(w, issued)
+}
+
+// writeIssuedCard — ответ о выпуске карты. Номер, срок и CVV отдаются открыто только здесь;
+// CVV больше получить нельзя.
+func writeIssuedCard(w http.ResponseWriter, issued *services.IssuedCard) {
+	resp := map[string]interface{}{
+		"token":     issued.Card.Token,
+		"brand":     issued.Card.Brand,
+		"number":    issued.Number,
+		"expiry":    issued.Expiry,
+		"cvv":       issued.CVV,
+		"createdAt": issued.Card.CreatedAt,
+	}
+	if issued.Card.ReissuedFromToken != "" {
+		resp["reissued_from"] = issued.Card.ReissuedFromToken
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *CardHandler) GetUserCards(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
 
-	cvvHash, err := utils.HashCVV(cvv)
+	cards, err := h.CardRepo.FindByUserID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "CVV hash error", http.StatusInternalServerError)
+		http.Error(w, "Could not fetch cards", http.StatusInternalServerError)
 		return
 	}
 
-	hmac := utils.ComputeHMAC(cardNumber, []byte("super-secret-hmac-key")) // TODO: заменить на config
+	resp := []CardResponse{}
+	for _, c := range cards {
+		resp = append(resp, newCardResponse(c))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type RevealCardRequest struct {
+	Password string `json:"password"`
+}
+
+// POST /cards/{cardToken}/reveal — расшифрованные номер и срок действия карты после ввода пароля
+func (h *CardHandler) RevealCard(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	var req RevealCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "Password is required", http.StatusBadRequest)
+		return
+	}
 
-	card := &models.Card{
+	secret, err := h.Cards.Reveal(r.Context(), services.RevealRequest{
+		Token:     mux.Vars(r)["cardToken"],
 		UserID:    userID,
-		AccountID: req.AccountID,
-		NumberPGP: cardNumber, // временно без шифрования
-		ExpiryPGP: expiry,
-		CVVHash:   cvvHash,
-		HMAC:      hmac,
+		Password:  req.Password,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	switch {
+	case errors.Is(err, repositories.ErrCardNotFound):
+		http.Error(w, "Card not found", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrInvalidPassword):
+		http.Error(w, "Invalid password", http.StatusForbidden)
+		return
+	case err != nil:
+		log.Printf("Просмотр номера карты: %v\n", err)
+		http.Error(w, "Could not reveal card", http.StatusInternalServerError)
+		return
 	}
 
-	err = h.CardRepo.Create(card)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(secret)
+}
+
+// POST /cards/{cardToken}/freeze — временная заморозка карты
+func (h *CardHandler) FreezeCard(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, func(ctx context.Context, token string, userID int) (*models.Card, error) {
+		return h.Cards.Freeze(ctx, token, userID)
+	})
+}
+
+// POST /cards/{cardToken}/unfreeze
+func (h *CardHandler) UnfreezeCard(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, func(ctx context.Context, token string, userID int) (*models.Card, error) {
+		return h.Cards.Unfreeze(ctx, token, userID)
+	})
+}
+
+type BlockCardRequest struct {
+	Reason string `json:"reason"`
+}
+
+// POST /cards/{cardToken}/block — блокировка навсегда с причиной: lost, stolen, compromised, closed
+func (h *CardHandler) BlockCard(w http.ResponseWriter, r *http.Request) {
+	var req BlockCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	h.changeStatus(w, r, func(ctx context.Context, token string, userID int) (*models.Card, error) {
+		return h.Cards.Block(ctx, token, userID, req.Reason)
+	})
+}
+
+// POST /cards/{cardToken}/reissue — новая карта к тому же счёту вместо текущей
+func (h *CardHandler) ReissueCard(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	issued, err := h.Cards.Reissue(r.Context(), mux.Vars(r)["cardToken"], userID)
 	if err != nil {
-		http.Error(w, "Could not create card", http.StatusInternalServerError)
+		writeCardError(w, err, "Could not reissue card")
 		return
 	}
+	writeIssuedCard(w, issued)
+}
 
-	resp := map[string]interface{}{
-		"card_id":   card.ID,
-		"number":    cardNumber,
-		"expiry":    expiry,
-		"cvv":       cvv,
-		"createdAt": card.CreatedAt,
+func (h *CardHandler) changeStatus(w http.ResponseWriter, r *http.Request,
+	change func(ctx context.Context, token string, userID int) (*models.Card, error)) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	card, err := change(r.Context(), mux.Vars(r)["cardToken"], userID)
+	if err != nil {
+		writeCardError(w, err, "Could not change card status")
+		return
 	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(newCardResponse(card))
 }
 
-func (h *CardHandler) GetUserCards(w http.ResponseWriter, r *http.Request) {
+// writeCardError переводит ошибки операций с картой в HTTP-статусы
+func writeCardError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repositories.ErrCardNotFound):
+		http.Error(w, "Card not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidBlockReason):
+		http.Error(w, "Reason must be one of: lost, stolen, compromised, closed", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidCardControls):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidCardTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v\n", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// GET /cards/{cardToken}/controls
+func (h *CardHandler) GetControls(w http.ResponseWriter, r *http.Request) {
 	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
 	userID, _ := strconv.Atoi(userIDStr)
 
-	cards, err := h.CardRepo.FindByUserID(userID)
+	controls, err := h.Cards.Controls(r.Context(), mux.Vars(r)["cardToken"], userID)
 	if err != nil {
-		http.Error(w, "Could not fetch cards", http.StatusInternalServerError)
+		writeCardError(w, err, "Could not fetch card controls")
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(controls)
+}
+
+// Лимиты — в валюте счёта карты; отсутствующий лимит снимается
+type CardControlsRequest struct {
+	TransactionLimit *json.Number `json:"transaction_limit"`
+	DailyLimit       *json.Number `json:"daily_limit"`
+	MonthlyLimit     *json.Number `json:"monthly_limit"`
+	AllowedMCCs      []string     `json:"allowed_mccs"`
+	BlockedMCCs      []string     `json:"blocked_mccs"`
+	EcommerceOnly    bool         `json:"ecommerce_only"`
+	SingleUse        bool         `json:"single_use"`
+}
+
+// PUT /cards/{cardToken}/controls — замена ограничений карты целиком
+func (h *CardHandler) UpdateControls(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+	token := mux.Vars(r)["cardToken"]
 
-	// Ответ без зашифрованных полей
-	type CardResponse struct {
-		ID        int    `json:"id"`
-		AccountID int    `json:"account_id"`
-		Number    string `json:"number"`
-		Expiry    string `json:"expiry"`
-		HMAC      string `json:"hmac"`
-		CreatedAt string `json:"created_at"`
+	var req CardControlsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	current, err := h.Cards.Controls(r.Context(), token, userID)
+	if err != nil {
+		writeCardError(w, err, "Could not update card controls")
+		return
 	}
 
-	var resp []CardResponse
-	for _, c := range cards {
-		resp = append(resp, CardResponse{
-			ID:        c.ID,
-			AccountID: c.AccountID,
-			Number:    c.NumberPGP, // пока без расшифровки
-			Expiry:    c.ExpiryPGP, // пока без PGP
-			HMAC:      c.HMAC,
-			CreatedAt: c.CreatedAt.Format(time.RFC3339),
-		})
+	controls := &models.CardControls{
+		AllowedMCCs:   req.AllowedMCCs,
+		BlockedMCCs:   req.BlockedMCCs,
+		EcommerceOnly: req.EcommerceOnly,
+		SingleUse:     req.SingleUse,
+	}
+	if controls.AllowedMCCs == nil {
+		controls.AllowedMCCs = []string{}
+	}
+	if controls.BlockedMCCs == nil {
+		controls.BlockedMCCs = []string{}
+	}
+	limits := []struct {
+		raw    *json.Number
+		target **money.Money
+	}{
+		{req.TransactionLimit, &controls.TransactionLimit},
+		{req.DailyLimit, &controls.DailyLimit},
+		{req.MonthlyLimit, &controls.MonthlyLimit},
+	}
+	for _, l := range limits {
+		if l.raw == nil {
+			continue
+		}
+		limit, err := money.Parse(l.raw.String(), current.Currency)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		*l.target = &limit
 	}
 
+	if err := h.Cards.SetControls(r.Context(), token, userID, controls); err != nil {
+		writeCardError(w, err, "Could not update card controls")
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(controls)
 }
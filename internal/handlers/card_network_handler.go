@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
+	"gobankapi/internal/utils"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// CardNetworkHandler — маршруты для платёжной системы: авторизация, списание и отмена холдов
+type CardNetworkHandler struct {
+	Processing *services.CardProcessingService
+}
+
+func NewCardNetworkHandler(processing *services.CardProcessingService) *CardNetworkHandler {
+	return &CardNetworkHandler{Processing: processing}
+}
+
+type AuthorizeRequest struct {
+	Number   string      `json:"number"`
+	Expiry   string      `json:"expiry"` // MM/YY
+	CVV      string      `json:"cvv"`
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+	Merchant string      `json:"merchant"`
+	MCC      string      `json:"mcc"`
+	Channel  string      `json:"channel"` // ecommerce или pos
+}
+
+// POST /card-network/authorizations — 201 с холдом или 402 с причиной отказа
+func (h *CardNetworkHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	h.process(w, r, h.Processing.Authorize)
+}
+
+// POST /card-network/charges — оплата с немедленным списанием: 201 или 402 с причиной отказа
+func (h *CardNetworkHandler) Charge(w http.ResponseWriter, r *http.Request) {
+	h.process(w, r, h.Processing.Charge)
+}
+
+func (h *CardNetworkHandler) process(w http.ResponseWriter, r *http.Request,
+	run func(ctx context.Context, req services.AuthorizationRequest) (*models.CardAuthorization, error)) {
+	var req AuthorizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Number == "" || req.Expiry == "" || req.CVV == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	currency := strings.ToUpper(req.Currency)
+	if !money.IsSupported(currency) {
+		http.Error(w, "Unsupported currency", http.StatusBadRequest)
+		return
+	}
+	amount, err := money.Parse(req.Amount.String(), currency)
+	if err != nil || !amount.IsPositive() {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+	if !models.IsChannel(req.Channel) {
+		http.Error(w, "Channel must be ecommerce or pos", http.StatusBadRequest)
+		return
+	}
+	if req.MCC != "" && !utils.IsMCC(req.MCC) {
+		http.Error(w, "MCC must be 4 digits", http.StatusBadRequest)
+		return
+	}
+
+	auth, err := run(r.Context(), services.AuthorizationRequest{
+		Number:   req.Number,
+		Expiry:   req.Expiry,
+		CVV:      req.CVV,
+		Amount:   amount,
+		Merchant: req.Merchant,
+		MCC:      req.MCC,
+		Channel:  req.Channel,
+	})
+	if err != nil {
+		log.Println("Ошибка авторизации по карте:", err)
+		http.Error(w, "Could not authorize", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusCreated
+	if auth.Status == models.AuthDeclined {
+		status = http.StatusPaymentRequired
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(auth)
+}
+
+type CaptureRequest struct {
+	Amount json.Number `json:"amount,omitempty"` // пусто — вся сумма холда
+}
+
+// POST /card-network/authorizations/{authId}/capture — полное или частичное списание
+func (h *CardNetworkHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["authId"])
+	if err != nil {
+		http.Error(w, "Invalid authorization ID", http.StatusBadRequest)
+		return
+	}
+	var req CaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	auth, err := h.Processing.Capture(r.Context(), id, req.Amount.String())
+	writeAuthorizationResult(w, auth, err)
+}
+
+// POST /card-network/authorizations/{authId}/reverse — отмена авторизации и снятие холда
+func (h *CardNetworkHandler) Reverse(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["authId"])
+	if err != nil {
+		http.Error(w, "Invalid authorization ID", http.StatusBadRequest)
+		return
+	}
+	auth, err := h.Processing.Reverse(r.Context(), id)
+	writeAuthorizationResult(w, auth, err)
+}
+
+func writeAuthorizationResult(w http.ResponseWriter, auth *models.CardAuthorization, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrAuthorizationNotFound):
+		http.Error(w, "Authorization not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repositories.ErrAuthorizationState):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, repositories.ErrCaptureExceedsHold), errors.Is(err, money.ErrInvalidAmount):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Println("Ошибка операции по авторизации:", err)
+		http.Error(w, "Could not process authorization", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth)
+}
@@ -2,13 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"gobankapi/internal/middleware"
 	"gobankapi/internal/models"
+	"gobankapi/internal/money"
 	"gobankapi/internal/repositories"
-	"gobankapi/internal/utils"
+	"gobankapi/internal/services"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -16,68 +17,141 @@ import (
 type CreditHandler struct {
 	CreditRepo   *repositories.CreditRepository
 	ScheduleRepo *repositories.PaymentScheduleRepository
+	AccrualRepo  *repositories.InterestAccrualRepository
+	PenaltyRepo  *repositories.PenaltyRepository
+	AccountRepo  *repositories.AccountRepository
+	Pricing      *services.CreditPricingService
+	Applications *services.CreditApplicationService
+	TwoFA        *services.TwoFactorService
 }
 
-func NewCreditHandler(c *repositories.CreditRepository, s *repositories.PaymentScheduleRepository) *CreditHandler {
+func NewCreditHandler(
+	c *repositories.CreditRepository,
+	s *repositories.PaymentScheduleRepository,
+	ia *repositories.InterestAccrualRepository,
+	pr *repositories.PenaltyRepository,
+	a *repositories.AccountRepository,
+	p *services.CreditPricingService,
+	ap *services.CreditApplicationService,
+	tf *services.TwoFactorService,
+) *CreditHandler {
 	return &CreditHandler{
 		CreditRepo:   c,
 		ScheduleRepo: s,
+		AccrualRepo:  ia,
+		PenaltyRepo:  pr,
+		AccountRepo:  a,
+		Pricing:      p,
+		Applications: ap,
+		TwoFA:        tf,
 	}
 }
 
+// Ставку назначает банк по продукту: ключевая ставка ЦБ + маржа банка + спред продукта
 type CreateCreditRequest struct {
-	AccountID  int     `json:"account_id"`
-	Amount     float64 `json:"amount"`
-	TermMonths int     `json:"term_months"`
-	AnnualRate float64 `json:"annual_rate"`
+	AccountID  int         `json:"account_id"`
+	Amount     money.Money `json:"amount"`
+	TermMonths int         `json:"term_months"`
+	Product    string      `json:"product"`        // код продукта, по умолчанию consumer
+	Repayment  string      `json:"repayment_type"` // annuity (по умолчанию) | differentiated
+	StepUp
 }
 
+// GET /credit-products — продукты и текущие ставки по ним
+func (h *CreditHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	offers, err := h.Pricing.Offers(r.Context())
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(offers)
+}
+
+// POST /credits — заявка на кредит: скоринг, решение и выдача на счёт в одном запросе
 func (h *CreditHandler) CreateCredit(w http.ResponseWriter, r *http.Request) {
 	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
 	userID, _ := strconv.Atoi(userIDStr)
 
 	var req CreateCreditRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Amount.IsPositive() || req.TermMonths <= 0 {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	if req.Product == "" {
+		req.Product = "consumer"
+	}
+	if req.Repayment == "" {
+		req.Repayment = models.RepaymentAnnuity
+	}
+	if req.Repayment != models.RepaymentAnnuity && req.Repayment != models.RepaymentDifferentiated {
+		http.Error(w, "Invalid repayment_type: expected annuity or differentiated", http.StatusBadRequest)
+		return
+	}
 
-	monthlyPayment := utils.CalculateAnnuity(req.Amount, req.AnnualRate, req.TermMonths)
-
-	credit := &models.Credit{
-		UserID:         userID,
-		AccountID:      req.AccountID,
-		Amount:         req.Amount,
-		TermMonths:     req.TermMonths,
-		AnnualRate:     req.AnnualRate,
-		MonthlyPayment: monthlyPayment,
+	purpose := services.OperationPurpose("credit", req.AccountID, req.Amount, req.TermMonths, req.Product, req.Repayment)
+	if !confirmOperation(r.Context(), w, h.TwoFA, userID, req.Amount, purpose, req.StepUp) {
+		return
 	}
 
-	err := h.CreditRepo.Create(credit)
+	app, err := h.Applications.Submit(r.Context(), userID, req.AccountID, req.Amount, req.TermMonths, req.Product, req.Repayment)
 	if err != nil {
-		http.Error(w, "Could not create credit", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, repositories.ErrAccountNotFound):
+			writeBalanceError(w, "Invalid account", err)
+		case app != nil:
+			// Заявка сохранена, но не дошла до конца процесса — её статус виден в /credit-applications
+			http.Error(w, "Could not process credit application", http.StatusInternalServerError)
+		default:
+			writeCreditError(w, err)
+		}
 		return
 	}
 
-	// Сгенерируем график платежей
-	for i := 1; i <= req.TermMonths; i++ {
-		schedule := &models.PaymentSchedule{
-			CreditID: credit.ID,
-			DueDate:  time.Now().AddDate(0, i, 0), // каждый месяц
-			Amount:   monthlyPayment,
-			Paid:     false,
-			Penalty:  0,
-		}
-		_ = h.ScheduleRepo.Create(schedule) // упрощённо без обработки ошибки
+	status := http.StatusCreated
+	if app.Status == models.ApplicationRejected {
+		status = http.StatusUnprocessableEntity
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(app)
+}
 
-	resp := map[string]interface{}{
-		"credit_id":       credit.ID,
-		"monthly_payment": monthlyPayment,
-		"created_at":      credit.CreatedAt,
+// GET /credit-applications — заявки пользователя
+func (h *CreditHandler) ListApplications(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	apps, err := h.Applications.AppRepo.FindByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Could not fetch applications", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apps)
+}
+
+// GET /credit-applications/{applicationId}
+func (h *CreditHandler) GetApplication(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	id, err := strconv.Atoi(mux.Vars(r)["applicationId"])
+	if err != nil {
+		http.Error(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+	app, err := h.Applications.AppRepo.FindByID(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Could not fetch application", http.StatusInternalServerError)
+		return
+	}
+	if app == nil {
+		http.Error(w, "Application not found", http.StatusNotFound)
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(app)
 }
 
 func (h *CreditHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
@@ -88,7 +162,7 @@ func (h *CreditHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	schedule, err := h.ScheduleRepo.FindByCreditID(creditID)
+	schedule, err := h.ScheduleRepo.FindByCreditID(r.Context(), creditID)
 	if err != nil {
 		http.Error(w, "Could not fetch schedule", http.StatusInternalServerError)
 		return
@@ -97,3 +171,156 @@ func (h *CreditHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(schedule)
 }
+
+// GET /credits/{creditId}/accruals — ежедневные начисления процентов и их сумма
+func (h *CreditHandler) GetAccruals(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	creditID, err := strconv.Atoi(mux.Vars(r)["creditId"])
+	if err != nil {
+		http.Error(w, "Invalid credit ID", http.StatusBadRequest)
+		return
+	}
+
+	accruals, err := h.AccrualRepo.FindByCreditID(r.Context(), creditID, userID)
+	if err != nil {
+		http.Error(w, "Could not fetch accruals", http.StatusInternalServerError)
+		return
+	}
+	total := money.Zero(money.DefaultCurrency)
+	for _, a := range accruals {
+		total = total.Add(a.Amount)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"credit_id": creditID,
+		"total":     total,
+		"accruals":  accruals,
+	})
+}
+
+// GET /credits/{creditId}/penalties — события начисления неустойки
+func (h *CreditHandler) GetPenalties(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	creditID, err := strconv.Atoi(mux.Vars(r)["creditId"])
+	if err != nil {
+		http.Error(w, "Invalid credit ID", http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.PenaltyRepo.FindByCreditID(r.Context(), creditID, userID)
+	if err != nil {
+		http.Error(w, "Could not fetch penalties", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// Досрочное погашение: без amount — полное, с amount — частичное с пересчётом графика по mode
+type RepayCreditRequest struct {
+	Amount json.Number `json:"amount,omitempty"`
+	Mode   string      `json:"mode"` // shorten_term (по умолчанию) | reduce_payment
+	StepUp
+}
+
+// POST /credits/{creditId}/repay — возвращает новый график платежей
+func (h *CreditHandler) RepayCredit(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	creditID, err := strconv.Atoi(mux.Vars(r)["creditId"])
+	if err != nil {
+		http.Error(w, "Invalid credit ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RepayCreditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.RepayShortenTerm
+	}
+	if req.Mode != models.RepayShortenTerm && req.Mode != models.RepayReducePayment {
+		http.Error(w, "Invalid mode: expected shorten_term or reduce_payment", http.StatusBadRequest)
+		return
+	}
+
+	outstanding, err := h.CreditRepo.Outstanding(r.Context(), creditID, userID)
+	if err != nil {
+		writeRepayError(w, err)
+		return
+	}
+	amount := outstanding
+	if req.Amount != "" {
+		// Сумма — в валюте кредита
+		if amount, err = money.Parse(req.Amount.String(), outstanding.Currency); err != nil || !amount.IsPositive() {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
+	}
+	if !amount.IsPositive() {
+		writeRepayError(w, repositories.ErrCreditClosed)
+		return
+	}
+
+	purpose := services.OperationPurpose("repay", creditID, amount, req.Mode)
+	if !confirmOperation(r.Context(), w, h.TwoFA, userID, amount, purpose, req.StepUp) {
+		return
+	}
+
+	credit, err := h.CreditRepo.Repay(r.Context(), creditID, userID, amount, req.Mode)
+	if err != nil {
+		writeRepayError(w, err)
+		return
+	}
+
+	schedule, err := h.ScheduleRepo.FindByCreditID(r.Context(), creditID)
+	if err != nil {
+		http.Error(w, "Could not fetch schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"credit_id":       credit.ID,
+		"repaid":          amount,
+		"closed":          !credit.MonthlyPayment.IsPositive(),
+		"monthly_payment": credit.MonthlyPayment,
+		"schedule":        schedule,
+	})
+}
+
+func writeRepayError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrCreditNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrCreditClosed),
+		errors.Is(err, repositories.ErrCreditOverdue):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repositories.ErrRepaymentExceedsDebt),
+		errors.Is(err, repositories.ErrRepaymentBelowInterest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeBalanceError(w, "Could not repay credit", err)
+	}
+}
+
+func writeCreditError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrCreditProductNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrCreditTermsOutOfRange),
+		errors.Is(err, services.ErrCreditCurrency),
+		errors.Is(err, services.ErrUnknownRepaymentType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Could not price credit: key rate unavailable", http.StatusServiceUnavailable)
+	}
+}
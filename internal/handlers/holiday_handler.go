@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gobankapi/internal/models"
+	"gobankapi/internal/repositories"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// HolidayHandler — ведение производственного календаря: по нему даты платежей
+// переносятся с праздничных дней
+type HolidayHandler struct {
+	Repo *repositories.HolidayRepository
+}
+
+func NewHolidayHandler(repo *repositories.HolidayRepository) *HolidayHandler {
+	return &HolidayHandler{Repo: repo}
+}
+
+type PutHolidayRequest struct {
+	Name string `json:"name"`
+}
+
+// GET /admin/holidays?year=2028 — праздники за год (по умолчанию текущий)
+func (h *HolidayHandler) List(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = n
+	}
+
+	list, err := h.Repo.List(r.Context(), year)
+	if err != nil {
+		http.Error(w, "Could not fetch holidays", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*models.Holiday{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// PUT /admin/holidays/{date} — добавить праздник или изменить его название
+func (h *HolidayHandler) Put(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse("2006-01-02", mux.Vars(r)["date"])
+	if err != nil {
+		http.Error(w, "Invalid date: expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	var req PutHolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		http.Error(w, "Invalid request: name is required", http.StatusBadRequest)
+		return
+	}
+
+	holiday := &models.Holiday{Date: date, Name: strings.TrimSpace(req.Name)}
+	if err := h.Repo.Put(r.Context(), holiday); err != nil {
+		http.Error(w, "Could not save holiday", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holiday)
+}
+
+// DELETE /admin/holidays/{date} — убрать праздник
+func (h *HolidayHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse("2006-01-02", mux.Vars(r)["date"])
+	if err != nil {
+		http.Error(w, "Invalid date: expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	deleted, err := h.Repo.Delete(r.Context(), date)
+	if err != nil {
+		http.Error(w, "Could not delete holiday", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Holiday not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
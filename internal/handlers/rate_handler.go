@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gobankapi/internal/models"
+	"gobankapi/internal/repositories"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Период истории курсов по умолчанию и максимальный
+const (
+	defaultRateHistoryDays = 30
+	maxRateHistoryDays     = 366
+)
+
+type RateHandler struct {
+	RateRepo *repositories.ExchangeRateRepository
+}
+
+func NewRateHandler(rateRepo *repositories.ExchangeRateRepository) *RateHandler {
+	return &RateHandler{RateRepo: rateRepo}
+}
+
+// GET /rates?date=YYYY-MM-DD — курсы всех валют на дату (по умолчанию сегодня)
+func (h *RateHandler) GetRates(w http.ResponseWriter, r *http.Request) {
+	date, err := parseDateParam(r, "date", time.Now())
+	if err != nil {
+		http.Error(w, "Invalid date (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	rates, err := h.RateRepo.OnDate(r.Context(), date)
+	if err != nil {
+		http.Error(w, "Could not fetch rates", http.StatusInternalServerError)
+		return
+	}
+	if rates == nil {
+		rates = []*models.ExchangeRate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"date":  date.Format("2006-01-02"),
+		"base":  "RUB",
+		"rates": rates,
+	})
+}
+
+// GET /rates/{currency}/history?from=YYYY-MM-DD&to=YYYY-MM-DD — по умолчанию последние 30 дней
+func (h *RateHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	currency := strings.ToUpper(mux.Vars(r)["currency"])
+	if len(currency) != 3 {
+		http.Error(w, "Invalid currency", http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseDateParam(r, "to", time.Now())
+	if err != nil {
+		http.Error(w, "Invalid to (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	from, err := parseDateParam(r, "from", to.AddDate(0, 0, -defaultRateHistoryDays))
+	if err != nil {
+		http.Error(w, "Invalid from (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	if from.After(to) || to.Sub(from) > maxRateHistoryDays*24*time.Hour {
+		http.Error(w, "Invalid period (up to 366 days)", http.StatusBadRequest)
+		return
+	}
+
+	rates, err := h.RateRepo.History(r.Context(), currency, from, to)
+	if err != nil {
+		http.Error(w, "Could not fetch rate history", http.StatusInternalServerError)
+		return
+	}
+	if rates == nil {
+		rates = []*models.ExchangeRate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"currency": currency,
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"rates":    rates,
+	})
+}
+
+func parseDateParam(r *http.Request, name string, fallback time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Date(fallback.Year(), fallback.Month(), fallback.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
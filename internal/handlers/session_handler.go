@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"gobankapi/internal/middleware"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type SessionHandler struct {
+	SessionRepo *repositories.SessionRepository
+	Tokens      *services.TokenService
+}
+
+func NewSessionHandler(repo *repositories.SessionRepository, tokens *services.TokenService) *SessionHandler {
+	return &SessionHandler{SessionRepo: repo, Tokens: tokens}
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// POST /auth/refresh — обмен refresh-токена на новую пару (старый токен аннулируется)
+func (h *SessionHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.Tokens.Refresh(r.Context(), req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// POST /auth/logout — отзыв текущей сессии и access-токена
+func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+	sessionID := r.Context().Value(middleware.SessionIDKey).(string)
+	jti := r.Context().Value(middleware.TokenIDKey).(string)
+	expiresAt := r.Context().Value(middleware.TokenExpiresAtKey).(time.Time)
+
+	if err := h.Tokens.Logout(r.Context(), userID, sessionID, jti, expiresAt); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Logout failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(`{"status":"ok","action":"logout"}`))
+}
+
+// GET /sessions — активные сессии (устройства) пользователя
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+	currentID := r.Context().Value(middleware.SessionIDKey).(string)
+
+	sessions, err := h.SessionRepo.FindActiveByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Could not fetch sessions", http.StatusInternalServerError)
+		return
+	}
+
+	type SessionResponse struct {
+		ID         string    `json:"id"`
+		UserAgent  string    `json:"user_agent"`
+		IP         string    `json:"ip"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastUsedAt time.Time `json:"last_used_at"`
+		ExpiresAt  time.Time `json:"expires_at"`
+		Current    bool      `json:"current"`
+	}
+
+	resp := []SessionResponse{}
+	for _, s := range sessions {
+		resp = append(resp, SessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == currentID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// DELETE /sessions/{sessionId} — отзыв сессии на конкретном устройстве
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	sessionID := mux.Vars(r)["sessionId"]
+	err := h.SessionRepo.Revoke(r.Context(), sessionID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(`{"status":"ok","action":"revoke"}`))
+}
+
+// IP клиента без порта
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gobankapi/internal/money"
+	"gobankapi/internal/services"
+)
+
+// StepUp — поля подтверждения критичной операции кодом из письма.
+// Первый запрос отправляется без них и получает challenge_id, повторный — с кодом.
+type StepUp struct {
+	ChallengeID string `json:"challenge_id,omitempty"`
+	Code        string `json:"code,omitempty"`
+}
+
+// confirmOperation возвращает true, если операцию можно выполнять.
+// Иначе ответ (запрос кода или ошибка проверки) уже записан в w.
+func confirmOperation(ctx context.Context, w http.ResponseWriter, tf *services.TwoFactorService, userID int, amount money.Money, purpose string, su StepUp) bool {
+	if !tf.RequiresStepUp(ctx, amount) {
+		return true
+	}
+
+	if su.ChallengeID == "" {
+		challenge, err := tf.StartChallengeForUser(ctx, userID, purpose)
+		if err != nil {
+			writeTwoFAError(w, err)
+			return false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":       "2fa_required",
+			"challenge_id": challenge.ID,
+			"expires_at":   challenge.ExpiresAt,
+		})
+		return false
+	}
+
+	user, err := tf.VerifyChallenge(ctx, su.ChallengeID, su.Code, purpose)
+	if err != nil {
+		writeTwoFAError(w, err)
+		return false
+	}
+	if user.ID != userID {
+		writeTwoFAError(w, services.ErrTwoFAChallengeNotFound)
+		return false
+	}
+	return true
+}
+
+func writeTwoFAError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrTwoFALocked):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrTwoFAChallengeNotFound),
+		errors.Is(err, services.ErrTwoFACodeExpired),
+		errors.Is(err, services.ErrTwoFAInvalidCode),
+		errors.Is(err, services.ErrTwoFAPurposeMismatch):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	default:
+		http.Error(w, "Could not process 2FA", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gobankapi/internal/middleware"
+	"gobankapi/internal/services"
+	"net/http"
+	"strconv"
+)
+
+type TwoFAHandler struct {
+	TwoFA *services.TwoFactorService
+}
+
+func NewTwoFAHandler(tf *services.TwoFactorService) *TwoFAHandler {
+	return &TwoFAHandler{TwoFA: tf}
+}
+
+// POST /2fa/totp/enroll — выдаёт секрет и otpauth:// URI для приложения-аутентификатора
+func (h *TwoFAHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	enrollment, err := h.TwoFA.EnrollTOTP(r.Context(), userID)
+	if errors.Is(err, services.ErrTOTPAlreadyEnabled) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not start TOTP enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+// POST /2fa/totp/confirm — включает TOTP по первому коду и возвращает коды восстановления
+func (h *TwoFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.Context().Value(middleware.UserIDKey).(string)
+	userID, _ := strconv.Atoi(userIDStr)
+
+	var req ConfirmTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.TwoFA.ConfirmTOTP(r.Context(), userID, req.Code)
+	switch {
+	case errors.Is(err, services.ErrTOTPAlreadyEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, services.ErrTOTPNotEnrolled):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		writeTwoFAError(w, err)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"status":         "enabled",
+		"recovery_codes": codes,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
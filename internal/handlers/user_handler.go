@@ -11,18 +11,19 @@ import (
 
 	"gobankapi/internal/models"
 	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
 
 	"fmt"
-	"gobankapi/internal/config"
-	"gobankapi/internal/utils"
 )
 
 type UserHandler struct {
 	UserRepo *repositories.UserRepository
+	TwoFA    *services.TwoFactorService
+	Tokens   *services.TokenService
 }
 
-func NewUserHandler(repo *repositories.UserRepository) *UserHandler {
-	return &UserHandler{UserRepo: repo}
+func NewUserHandler(repo *repositories.UserRepository, twoFA *services.TwoFactorService, tokens *services.TokenService) *UserHandler {
+	return &UserHandler{UserRepo: repo, TwoFA: twoFA, Tokens: tokens}
 }
 
 type RegisterRequest struct {
@@ -81,7 +82,7 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		PasswordHash: string(hashedPassword),
 	}
 
-	err = h.UserRepo.Create(user)
+	err = h.UserRepo.Create(r.Context(), user)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -111,7 +112,7 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.UserRepo.FindByEmail(req.Email)
+	user, err := h.UserRepo.FindByEmail(r.Context(), req.Email)
 	if err != nil || user == nil {
 		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 		return
@@ -123,16 +124,55 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := utils.GenerateJWTToken(
-		fmt.Sprintf("%d", user.ID),
-		config.AppConfig.JWTSecret,
-	)
+	// Второй фактор не включён — токен выдаётся сразу, но без claim 2fa-verified,
+	// поэтому чувствительные маршруты для такой сессии закрыты
+	if !h.TwoFA.LoginRequiresChallenge(user) {
+		h.issueTokens(w, r, user.ID, false)
+		return
+	}
+
+	// Первый шаг входа: пароль верный — запрашиваем код из письма или приложения
+	challenge, err := h.TwoFA.StartChallenge(r.Context(), user, services.TwoFAPurposeLogin)
+	if err != nil {
+		writeTwoFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(challenge)
+}
+
+type VerifyLoginRequest struct {
+	ChallengeID string `json:"challenge_id"`
+	Code        string `json:"code"`
+}
+
+// POST /login/verify — второй шаг входа: обмен кода из письма на JWT
+func (h *UserHandler) VerifyLogin(w http.ResponseWriter, r *http.Request) {
+	var req VerifyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.TwoFA.VerifyChallenge(r.Context(), req.ChallengeID, req.Code, services.TwoFAPurposeLogin)
+	if err != nil {
+		writeTwoFAError(w, err)
+		return
+	}
+
+	// Сюда попадают только после пройденной проверки кода из письма или TOTP
+	h.issueTokens(w, r, user.ID, true)
+}
+
+// issueTokens открывает сессию; twoFAVerified — пройден ли при входе второй фактор
+func (h *UserHandler) issueTokens(w http.ResponseWriter, r *http.Request, userID int, twoFAVerified bool) {
+	tokens, err := h.Tokens.Issue(r.Context(), userID, twoFAVerified, r.UserAgent(), clientIP(r))
 	if err != nil {
 		http.Error(w, "Could not generate token", http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]string{"token": token}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(tokens)
 }
@@ -2,17 +2,62 @@ package middleware
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
-	"gobankapi/internal/config"
+	"gobankapi/internal/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey         contextKey = "userID"
+	TwoFAVerifiedKey  contextKey = "twoFAVerified"
+	SessionIDKey      contextKey = "sessionID"
+	TokenIDKey        contextKey = "tokenID"
+	TokenExpiresAtKey contextKey = "tokenExpiresAt"
+)
+
+// SessionValidator проверяет, что сессия токена не отозвана и сам токен (jti) не отозван
+type SessionValidator interface {
+	IsActive(ctx context.Context, sessionID, jti string) (bool, error)
+}
+
+// KeyResolver возвращает ключ проверки подписи по заголовку токена (kid/alg).
+// Набор ключей совпадает с публикуемым в /.well-known/jwks.json.
+type KeyResolver interface {
+	Keyfunc(ctx context.Context, token *jwt.Token) (interface{}, error)
+}
+
+// RoleResolver возвращает роль пользователя по его ID из токена
+type RoleResolver interface {
+	Role(ctx context.Context, userID string) (string, error)
+}
+
+var (
+	sessionValidator SessionValidator
+	keyResolver      KeyResolver
+	roleResolver     RoleResolver
+)
+
+// SetRoleResolver подключает проверку ролей (вызывается при сборке роутера)
+func SetRoleResolver(r RoleResolver) {
+	roleResolver = r
+}
+
+// SetKeyResolver подключает набор ключей проверки JWT (вызывается при сборке роутера)
+func SetKeyResolver(k KeyResolver) {
+	keyResolver = k
+}
+
+// SetSessionValidator подключает серверную проверку сессий (вызывается при сборке роутера)
+func SetSessionValidator(v SessionValidator) {
+	sessionValidator = v
+}
 
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -24,16 +69,76 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		claims := &jwt.RegisteredClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.AppConfig.JWTSecret), nil
+		claims := &utils.Claims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+			return keyResolver.Keyfunc(r.Context(), t)
 		})
-		if err != nil || !token.Valid {
+		if err != nil || !token.Valid || claims.SessionID == "" || claims.ID == "" {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
+		if sessionValidator != nil {
+			active, err := sessionValidator.IsActive(r.Context(), claims.SessionID, claims.ID)
+			if err != nil {
+				log.Println("Ошибка проверки сессии:", err)
+				http.Error(w, "Could not validate session", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				http.Error(w, "Token revoked", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var expiresAt time.Time
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.Subject)
+		ctx = context.WithValue(ctx, TwoFAVerifiedKey, claims.TwoFAVerified)
+		ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
+		ctx = context.WithValue(ctx, TokenIDKey, claims.ID)
+		ctx = context.WithValue(ctx, TokenExpiresAtKey, expiresAt)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireTwoFA пропускает только запросы с токеном, выданным после проверки второго фактора.
+// Используется для чувствительных маршрутов поверх AuthMiddleware.
+func RequireTwoFA(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified, _ := r.Context().Value(TwoFAVerifiedKey).(bool)
+		if !verified {
+			http.Error(w, "Two-factor authentication required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole пропускает только пользователей с ролью role. Роль читается из БД при каждом
+// запросе, поэтому её снятие действует сразу, без перевыпуска токенов.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(UserIDKey).(string)
+			if roleResolver == nil || userID == "" {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			actual, err := roleResolver.Role(r.Context(), userID)
+			if err != nil {
+				log.Println("Ошибка проверки роли:", err)
+				http.Error(w, "Could not check role", http.StatusInternalServerError)
+				return
+			}
+			if actual != role {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,11 +1,18 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
 
 type Account struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Number    string    `json:"number"`
-	Balance   float64   `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int         `json:"id"`
+	UserID    int         `json:"user_id"`
+	Number    string      `json:"number"`
+	Currency  string      `json:"currency"`          // ISO 4217
+	Balance   money.Money `json:"balance"`           // проведённый остаток
+	Held      money.Money `json:"held"`              // холды по одобренным авторизациям карт
+	Available money.Money `json:"available_balance"` // доступно для списаний: balance - held
+	CreatedAt time.Time   `json:"created_at"`
 }
@@ -2,13 +2,71 @@ package models
 
 import "time"
 
+// Статусы карты: active ⇄ frozen; blocked, expired и reissued — конечные
+// (перевыпустить можно любую карту, кроме уже перевыпущенной)
+const (
+	CardActive   = "active"
+	CardFrozen   = "frozen"
+	CardBlocked  = "blocked"
+	CardExpired  = "expired"
+	CardReissued = "reissued"
+)
+
+// Причины блокировки карты
+const (
+	BlockLost        = "lost"
+	BlockStolen      = "stolen"
+	BlockCompromised = "compromised"
+	BlockClosed      = "closed" // по желанию владельца
+	BlockUsed        = "used"   // одноразовая карта после списания; вручную не задаётся
+)
+
+// IsBlockReason проверяет, что причина блокировки поддерживается
+func IsBlockReason(reason string) bool {
+	switch reason {
+	case BlockLost, BlockStolen, BlockCompromised, BlockClosed:
+		return true
+	}
+	return false
+}
+
 type Card struct {
+	ID        int    `json:"-"`
+	Token     string `json:"token"` // непрозрачный идентификатор карты для API
+	UserID    int    `json:"user_id"`
+	AccountID int    `json:"account_id"`
+	First6    string `json:"first6"` // BIN и последние 4 цифры — единственные открытые части номера
+	Last4     string `json:"last4"`
+	Brand     string `json:"brand"`
+
+	// Номер и срок действия зашифрованы ключом данных карты, ключ данных — мастер-ключом
+	// версии KeyVersion (0 — карта выпущена до шифрования и ещё хранит данные открыто)
+	EncryptedData []byte `json:"-"`
+	WrappedKey    []byte `json:"-"`
+	KeyVersion    int    `json:"-"`
+	LegacyNumber  string `json:"-"` // открытые данные старых карт; шифруются командой rotate-card-keys
+	LegacyExpiry  string `json:"-"`
+
+	CVVHash   string     `json:"-"`
+	HMAC      string     `json:"hmac"` // HMAC номера для поиска карты без расшифровки
+	Status    string     `json:"status"`
+	ExpiresOn *time.Time `json:"expires_on,omitempty"` // последний день срока действия
+	CreatedAt time.Time  `json:"created_at"`
+
+	BlockReason       string     `json:"block_reason,omitempty"`
+	ReissuedFromID    *int       `json:"-"`
+	ReissuedFromToken string     `json:"reissued_from,omitempty"` // токен карты, вместо которой выпущена эта
+	StatusChangedAt   *time.Time `json:"status_changed_at,omitempty"`
+}
+
+// CardRevealAudit — запись журнала просмотров полного номера карты
+type CardRevealAudit struct {
 	ID        int       `json:"id"`
+	CardID    int       `json:"card_id"`
 	UserID    int       `json:"user_id"`
-	AccountID int       `json:"account_id"`
-	NumberPGP string    `json:"-"` // не выводим напрямую
-	ExpiryPGP string    `json:"-"`
-	CVVHash   string    `json:"-"`
-	HMAC      string    `json:"hmac"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// Статусы авторизации по карте: approved держит холд на счёте до списания, отмены или истечения
+const (
+	AuthApproved = "approved"
+	AuthDeclined = "declined"
+	AuthCaptured = "captured"
+	AuthReversed = "reversed"
+	AuthExpired  = "expired"
+)
+
+// Причины отказа в авторизации
+const (
+	DeclineInvalidCard       = "invalid_card" // номер, срок действия или CVV не совпали
+	DeclineCardInactive      = "card_inactive"
+	DeclineCardExpired       = "card_expired"
+	DeclineCurrencyMismatch  = "currency_mismatch"
+	DeclineInsufficientFunds = "insufficient_funds"
+	DeclineTransactionLimit  = "transaction_limit_exceeded"
+	DeclineDailyLimit        = "daily_limit_exceeded"
+	DeclineMonthlyLimit      = "monthly_limit_exceeded"
+	DeclineMCCNotAllowed     = "merchant_category_not_allowed"
+	DeclineChannelNotAllowed = "channel_not_allowed" // карта только для интернет-платежей
+	DeclineSingleUseSpent    = "single_use_spent"    // по одноразовой карте уже была операция
+)
+
+type CardAuthorization struct {
+	ID             int         `json:"id"`
+	CardID         int         `json:"-"`
+	AccountID      int         `json:"-"`
+	Amount         money.Money `json:"amount"`
+	CapturedAmount money.Money `json:"captured_amount"`
+	Merchant       string      `json:"merchant,omitempty"`
+	MCC            string      `json:"mcc,omitempty"`
+	Channel        string      `json:"channel,omitempty"`
+	Status         string      `json:"status"`
+	DeclineReason  string      `json:"decline_reason,omitempty"`
+	AuthCode       string      `json:"auth_code,omitempty"`
+	LedgerEntryID  *int        `json:"ledger_entry_id,omitempty"`
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
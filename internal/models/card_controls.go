@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// Каналы операций по карте
+const (
+	ChannelEcommerce = "ecommerce"
+	ChannelPOS       = "pos"
+)
+
+// IsChannel проверяет, что канал операции поддерживается
+func IsChannel(channel string) bool {
+	return channel == ChannelEcommerce || channel == ChannelPOS
+}
+
+// CardControls — ограничения по карте. Лимиты — в валюте счёта карты, nil — без лимита.
+// Пустой AllowedMCCs разрешает любые категории, кроме BlockedMCCs.
+type CardControls struct {
+	CardID           int          `json:"-"`
+	Currency         string       `json:"currency"` // валюта счёта карты и лимитов
+	TransactionLimit *money.Money `json:"transaction_limit"`
+	DailyLimit       *money.Money `json:"daily_limit"`
+	MonthlyLimit     *money.Money `json:"monthly_limit"`
+	AllowedMCCs      []string     `json:"allowed_mccs"`
+	BlockedMCCs      []string     `json:"blocked_mccs"`
+	EcommerceOnly    bool         `json:"ecommerce_only"`
+	SingleUse        bool         `json:"single_use"` // после первого списания карта блокируется
+	UpdatedAt        *time.Time   `json:"updated_at,omitempty"`
+}
+
+// Violation — причина отказа по ограничениям, которые не зависят от прошлых операций
+// (лимит на операцию, MCC, канал); пустая строка — операция разрешена
+func (c *CardControls) Violation(amount money.Money, mcc, channel string) string {
+	if c.EcommerceOnly && channel != ChannelEcommerce {
+		return DeclineChannelNotAllowed
+	}
+	for _, blocked := range c.BlockedMCCs {
+		if blocked == mcc {
+			return DeclineMCCNotAllowed
+		}
+	}
+	if len(c.AllowedMCCs) > 0 {
+		allowed := false
+		for _, a := range c.AllowedMCCs {
+			allowed = allowed || a == mcc
+		}
+		if !allowed {
+			return DeclineMCCNotAllowed
+		}
+	}
+	if c.TransactionLimit != nil && c.TransactionLimit.Currency == amount.Currency && amount.GreaterThan(*c.TransactionLimit) {
+		return DeclineTransactionLimit
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+
+	"gobankapi/internal/money"
+)
+
+func TestCardControlsViolation(t *testing.T) {
+	limit := money.MustParse("1000", "RUB")
+	tests := []struct {
+		name     string
+		controls CardControls
+		amount   string
+		mcc      string
+		channel  string
+		want     string
+	}{
+		{"no controls", CardControls{}, "5000", "5411", ChannelPOS, ""},
+		{"within transaction limit", CardControls{TransactionLimit: &limit}, "1000", "5411", ChannelPOS, ""},
+		{"over transaction limit", CardControls{TransactionLimit: &limit}, "1000.01", "5411", ChannelPOS, DeclineTransactionLimit},
+		{"allowed category", CardControls{AllowedMCCs: []string{"5411", "5812"}}, "10", "5812", ChannelPOS, ""},
+		{"category not in allow list", CardControls{AllowedMCCs: []string{"5411"}}, "10", "7995", ChannelPOS, DeclineMCCNotAllowed},
+		{"blocked category", CardControls{BlockedMCCs: []string{"7995"}}, "10", "7995", ChannelEcommerce, DeclineMCCNotAllowed},
+		{"blocked wins over allowed", CardControls{AllowedMCCs: []string{"7995"}, BlockedMCCs: []string{"7995"}}, "10", "7995", ChannelPOS, DeclineMCCNotAllowed},
+		{"ecommerce only online", CardControls{EcommerceOnly: true}, "10", "5411", ChannelEcommerce, ""},
+		{"ecommerce only in store", CardControls{EcommerceOnly: true}, "10", "5411", ChannelPOS, DeclineChannelNotAllowed},
+	}
+	for _, tt := range tests {
+		if got := tt.controls.Violation(money.MustParse(tt.amount, "RUB"), tt.mcc, tt.channel); got != tt.want {
+			t.Errorf("%s: Violation = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
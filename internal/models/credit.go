@@ -1,24 +1,61 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// Пересчёт графика после частичного досрочного погашения
+const (
+	RepayShortenTerm   = "shorten_term"   // платёж прежний, срок короче
+	RepayReducePayment = "reduce_payment" // срок прежний, платёж меньше
+)
+
+// Виды погашения кредита
+const (
+	RepaymentAnnuity        = "annuity"        // равные ежемесячные платежи
+	RepaymentDifferentiated = "differentiated" // равные доли основного долга, платёж убывает
+)
 
 type Credit struct {
-	ID             int       `json:"id"`
-	UserID         int       `json:"user_id"`
-	AccountID      int       `json:"account_id"`
-	Amount         float64   `json:"amount"`
-	TermMonths     int       `json:"term_months"`
-	AnnualRate     float64   `json:"annual_rate"`
-	MonthlyPayment float64   `json:"monthly_payment"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID             int         `json:"id"`
+	UserID         int         `json:"user_id"`
+	AccountID      int         `json:"account_id"`
+	Amount         money.Money `json:"amount"`
+	TermMonths     int         `json:"term_months"`
+	AnnualRate     float64     `json:"annual_rate"`
+	MonthlyPayment money.Money `json:"monthly_payment"` // для дифференцированного — ближайший платёж
+	ProductID      *int        `json:"product_id,omitempty"`
+	RateType       string      `json:"rate_type"`
+	RepaymentType  string      `json:"repayment_type"`
+	DayCount       string      `json:"day_count"` // конвенция подсчёта дней для процентов
+	Spread         float64     `json:"spread"`    // надбавка к ключевой ставке, п.п.
+	KeyRate        float64     `json:"key_rate"`  // ключевая ставка, по которой рассчитана annual_rate
+	CreatedAt      time.Time   `json:"created_at"`
 }
 
 type PaymentSchedule struct {
-	ID       int        `json:"id"`
-	CreditID int        `json:"credit_id"`
-	DueDate  time.Time  `json:"due_date"`
-	Amount   float64    `json:"amount"`
-	Paid     bool       `json:"paid"`
-	PaidAt   *time.Time `json:"paid_at,omitempty"`
-	Penalty  float64    `json:"penalty"`
+	ID        int         `json:"id"`
+	CreditID  int         `json:"credit_id"`
+	DueDate   time.Time   `json:"due_date"`
+	Amount    money.Money `json:"amount"`    // principal + interest
+	Principal money.Money `json:"principal"` // основной долг в платеже
+	Interest  money.Money `json:"interest"`  // проценты в платеже
+	Remaining money.Money `json:"remaining"` // остаток долга после платежа
+	Paid      bool        `json:"paid"`
+	PaidAt    *time.Time  `json:"paid_at,omitempty"`
+	Penalty   money.Money `json:"penalty"`
+	// Погашено по частям платежа (при частичном списании)
+	AmountPaid    money.Money `json:"amount_paid"`
+	PenaltyPaid   money.Money `json:"penalty_paid"`
+	InterestPaid  money.Money `json:"interest_paid"`
+	PrincipalPaid money.Money `json:"principal_paid"`
 }
+
+// Части платежа в порядке погашения (очерёдность задаётся COLLECTION_WATERFALL)
+const (
+	ComponentPenalty   = "penalty"
+	ComponentInterest  = "interest"
+	ComponentPrincipal = "principal"
+)
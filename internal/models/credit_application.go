@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// Статусы заявки на кредит
+const (
+	ApplicationSubmitted = "submitted"
+	ApplicationScored    = "scored"
+	ApplicationApproved  = "approved"
+	ApplicationRejected  = "rejected"
+	ApplicationDisbursed = "disbursed"
+)
+
+type CreditApplication struct {
+	ID             int         `json:"id"`
+	UserID         int         `json:"user_id"`
+	AccountID      int         `json:"account_id"`
+	ProductID      int         `json:"product_id"`
+	Amount         money.Money `json:"amount"`
+	TermMonths     int         `json:"term_months"`
+	RepaymentType  string      `json:"repayment_type"`
+	Status         string      `json:"status"`
+	AnnualRate     float64     `json:"annual_rate"`
+	KeyRate        float64     `json:"key_rate"`
+	Spread         float64     `json:"spread"`
+	MonthlyPayment money.Money `json:"monthly_payment"`
+	MonthlyIncome  money.Money `json:"monthly_income"` // средний доход за период скоринга
+	ExistingDebt   money.Money `json:"existing_debt"`  // непогашенный основной долг по кредитам
+	DTI            float64     `json:"dti"`            // ежемесячные платежи по кредитам / месячный доход
+	DecisionReason string      `json:"decision_reason,omitempty"`
+	CreditID       *int        `json:"credit_id,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "gobankapi/internal/money"
+
+// Тип ставки кредита
+const (
+	RateTypeFixed    = "fixed"
+	RateTypeFloating = "floating"
+)
+
+type CreditProduct struct {
+	ID              int         `json:"id"`
+	Code            string      `json:"code"`
+	Name            string      `json:"name"`
+	RateType        string      `json:"rate_type"`
+	DayCount        string      `json:"day_count"`
+	Spread          float64     `json:"spread"` // надбавка продукта к ставке банка, п.п.
+	MinAmount       money.Money `json:"min_amount"`
+	MaxAmount       money.Money `json:"max_amount"`
+	MinTermMonths   int         `json:"min_term_months"`
+	MaxTermMonths   int         `json:"max_term_months"`
+	Active          bool        `json:"active"`
+	PenaltyPolicyID *int        `json:"penalty_policy_id,omitempty"`
+}
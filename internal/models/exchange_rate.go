@@ -0,0 +1,27 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Курс валюты к рублю: Nominal единиц Currency стоят Rate рублей
+type ExchangeRate struct {
+	ID        int       `json:"id"`
+	Currency  string    `json:"currency"`
+	Rate      string    `json:"rate"` // десятичная строка без потери точности
+	Nominal   int       `json:"nominal"`
+	RateDate  time.Time `json:"rate_date"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PerUnit — стоимость одной единицы валюты в рублях
+func (r *ExchangeRate) PerUnit() (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok || rate.Sign() <= 0 || r.Nominal <= 0 {
+		return nil, fmt.Errorf("invalid exchange rate %s/%d for %s", r.Rate, r.Nominal, r.Currency)
+	}
+	return rate.Quo(rate, big.NewRat(int64(r.Nominal), 1)), nil
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// Holiday — праздничный нерабочий день производственного календаря
+type Holiday struct {
+	Date time.Time `json:"date"`
+	Name string    `json:"name"`
+}
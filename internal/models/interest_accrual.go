@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// InterestAccrual — проценты, начисленные по кредиту за один день
+type InterestAccrual struct {
+	ID          int         `json:"id"`
+	CreditID    int         `json:"credit_id"`
+	AccrualDate time.Time   `json:"accrual_date"`
+	Principal   money.Money `json:"principal"` // остаток основного долга на конец дня
+	AnnualRate  float64     `json:"annual_rate"`
+	DayCount    string      `json:"day_count"`
+	Amount      money.Money `json:"amount"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type JWTKey struct {
+	KID           string
+	Alg           string
+	PrivateKeyEnc []byte
+	PublicKey     []byte
+	CreatedAt     time.Time
+	NotBefore     time.Time // с этого момента ключ подписывает новые токены (в JWKS — сразу после создания)
+	NotAfter      time.Time // после этого момента ключ не подписывает новые токены
+	RetireAt      time.Time // после этого момента ключ не принимается и не публикуется
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// Направление проводки
+const (
+	DirectionDebit  = "debit"
+	DirectionCredit = "credit"
+)
+
+// Внутренние счета банка (вторая сторона операций клиента)
+const (
+	GLCash           = "cash"            // внешние пополнения и снятия
+	GLLoans          = "loans"           // кредитный портфель
+	GLOpeningBalance = "opening_balance" // входящие остатки до перехода на двойную запись
+	GLFXPosition     = "fx_position"     // валютная позиция: обе стороны конвертации
+	GLInterestIncome = "interest_income" // полученные проценты по кредитам
+	GLPenaltyIncome  = "penalty_income"  // полученная неустойка
+	GLCardSettlement = "card_settlement" // расчёты с платёжной системой по операциям с картами
+)
+
+type LedgerEntry struct {
+	ID          int             `json:"id"`
+	Type        string          `json:"type"`
+	Description string          `json:"description,omitempty"`
+	Postings    []LedgerPosting `json:"postings"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type LedgerPosting struct {
+	ID        int         `json:"id"`
+	EntryID   int         `json:"entry_id"`
+	AccountID *int        `json:"account_id,omitempty"` // счёт клиента
+	GLAccount string      `json:"gl_account,omitempty"` // внутренний счёт банка
+	Direction string      `json:"direction"`
+	Amount    money.Money `json:"amount"`
+}
+
+// Проводка по счёту клиента
+func AccountPosting(accountID int, direction string, amount money.Money) LedgerPosting {
+	return LedgerPosting{AccountID: &accountID, Direction: direction, Amount: amount}
+}
+
+// Проводка по внутреннему счёту банка
+func GLPosting(glAccount string, direction string, amount money.Money) LedgerPosting {
+	return LedgerPosting{GLAccount: glAccount, Direction: direction, Amount: amount}
+}
+
+// Расхождение сохранённого баланса счёта с суммой его проводок
+type BalanceMismatch struct {
+	AccountID     int         `json:"account_id"`
+	Currency      string      `json:"currency"`
+	StoredBalance money.Money `json:"stored_balance"`
+	LedgerBalance money.Money `json:"ledger_balance"`
+	Difference    money.Money `json:"difference"`
+}
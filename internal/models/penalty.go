@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// PenaltyPolicy — правила начисления неустойки по кредитному продукту
+type PenaltyPolicy struct {
+	ID         int     `json:"id"`
+	Code       string  `json:"code"`
+	Name       string  `json:"name"`
+	DailyRate  float64 `json:"daily_rate"`  // % в день от просроченной суммы
+	GraceDays  int     `json:"grace_days"`  // дней после даты платежа без неустойки
+	CapPercent float64 `json:"cap_percent"` // предел суммы неустойки по кредиту, % от суммы кредита
+}
+
+// PenaltyEvent — неустойка, начисленная по платежу за один календарный день
+type PenaltyEvent struct {
+	ID            int         `json:"id"`
+	PaymentID     int         `json:"payment_id"`
+	CreditID      int         `json:"credit_id"`
+	PolicyID      int         `json:"policy_id"`
+	EventDate     time.Time   `json:"event_date"`
+	OverdueAmount money.Money `json:"overdue_amount"`
+	DailyRate     float64     `json:"daily_rate"`
+	Amount        money.Money `json:"amount"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Статусы запуска фоновой задачи
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+type SchedulerRun struct {
+	ID         int        `json:"id"`
+	Job        string     `json:"job"`
+	Instance   string     `json:"instance"`
+	Status     string     `json:"status"`
+	Processed  int        `json:"processed"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
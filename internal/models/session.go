@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID                string     `json:"id"`
+	UserID            int        `json:"user_id"`
+	RefreshTokenHash  string     `json:"-"`
+	PreviousTokenHash string     `json:"-"`
+	TwoFAVerified     bool       `json:"twofa_verified"`
+	UserAgent         string     `json:"user_agent"`
+	IP                string     `json:"ip"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active — сессия не отозвана и не истекла
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
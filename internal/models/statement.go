@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// Statement — выписка по счёту за календарный месяц
+type Statement struct {
+	ID             int         `json:"id"`
+	AccountID      int         `json:"account_id"`
+	PeriodStart    time.Time   `json:"period_start"`
+	PeriodEnd      time.Time   `json:"period_end"`
+	OpeningBalance money.Money `json:"opening_balance"`
+	CreditTurnover money.Money `json:"credit_turnover"` // зачисления за период
+	DebitTurnover  money.Money `json:"debit_turnover"`  // списания за период
+	ClosingBalance money.Money `json:"closing_balance"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
@@ -1,12 +1,21 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gobankapi/internal/money"
+)
 
 type Transaction struct {
-	ID            int       `json:"id"`
-	FromAccountID *int      `json:"from_account_id,omitempty"`
-	ToAccountID   *int      `json:"to_account_id,omitempty"`
-	Amount        float64   `json:"amount"`
-	Type          string    `json:"type"` // "deposit", "withdraw", "transfer"
-	CreatedAt     time.Time `json:"created_at"`
+	ID              int          `json:"id"`
+	FromAccountID   *int         `json:"from_account_id,omitempty"`
+	ToAccountID     *int         `json:"to_account_id,omitempty"`
+	Amount          money.Money  `json:"amount"`
+	Currency        string       `json:"currency"`
+	CounterAmount   *money.Money `json:"counter_amount,omitempty"` // при конвертации: сумма в валюте второго счёта
+	CounterCurrency string       `json:"counter_currency,omitempty"`
+	ExchangeRate    string       `json:"exchange_rate,omitempty"` // курс amount → counter_amount
+	Type            string       `json:"type"`                    // "deposit", "withdraw", "transfer", "credit_payment"
+	LedgerEntryID   *int         `json:"ledger_entry_id,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
 }
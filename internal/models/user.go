@@ -2,10 +2,41 @@ package models
 
 import "time"
 
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // доступ к /api/admin
+)
+
 type User struct {
 	ID           int       `json:"id"`
 	Email        string    `json:"email"`
 	Username     string    `json:"username"`
 	PasswordHash string    `json:"-"` // json:"-" означает, что PasswordHash не попадёт в JSON-ответы.
+	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// Состояние 2FA-подтверждения (наружу не отдаётся)
+	TwoFACode        string     `json:"-"` // HMAC кода из письма, не сам код
+	TwoFAExpires     *time.Time `json:"-"`
+	TwoFAChallengeID string     `json:"-"`
+	TwoFAPurpose     string     `json:"-"` // "login" или отпечаток подтверждаемой операции
+	TwoFAAttempts    int        `json:"-"`
+	TwoFALockedUntil *time.Time `json:"-"`
+
+	// TOTP: секрет хранится только в зашифрованном виде
+	TOTPSecretEnc []byte `json:"-"`
+	TOTPEnabled   bool   `json:"totp_enabled"`
+	TOTPLastStep  int64  `json:"-"`
+}
+
+type RecoveryCode struct {
+	ID       int
+	UserID   int
+	CodeHash string
+}
+
+// TwoFALocked — заблокирован ли ввод кодов после превышения числа попыток
+func (u *User) TwoFALocked(now time.Time) bool {
+	return u.TwoFALockedUntil != nil && u.TwoFALockedUntil.After(now)
 }
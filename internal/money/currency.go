@@ -0,0 +1,31 @@
+package money
+
+// Число знаков после запятой (минорных единиц) для поддерживаемых валют ISO 4217
+var exponents = map[string]int{
+	"RUB": 2,
+	"USD": 2,
+	"EUR": 2,
+	"CNY": 2,
+	"GBP": 2,
+	"CHF": 2,
+	"KZT": 2,
+	"BYN": 2,
+	"AMD": 2,
+	"TRY": 2,
+	"AED": 2,
+	"JPY": 0,
+}
+
+// Exponent — число минорных знаков валюты (для неизвестных — 2)
+func Exponent(currency string) int {
+	if exp, ok := exponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// IsSupported — поддерживается ли валюта
+func IsSupported(currency string) bool {
+	_, ok := exponents[currency]
+	return ok
+}
@@ -0,0 +1,276 @@
+// Package money — денежные суммы с фиксированной точкой: целое число минорных единиц
+// (копеек, центов) плюс код валюты ISO 4217. Арифметика точная, округление выполняется
+// только явно, одним из режимов RoundingMode.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Валюта по умолчанию для сумм, у которых валюта не указана явно
+const DefaultCurrency = "RUB"
+
+// RoundingMode — правило округления до минорной единицы
+type RoundingMode int
+
+const (
+	// HalfUp — половина округляется от нуля (1.005 → 1.01). Суммы платежей, штрафы.
+	HalfUp RoundingMode = iota
+	// HalfEven — банковское округление: половина к чётному (1.005 → 1.00, 1.015 → 1.02).
+	// Начисление процентов: не даёт систематического смещения на длинных графиках.
+	HalfEven
+)
+
+var ErrInvalidAmount = errors.New("invalid money amount")
+
+type Money struct {
+	Amount   int64  // минорные единицы
+	Currency string // ISO 4217
+}
+
+func New(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: currency}
+}
+
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// Parse разбирает десятичную строку ("1234.5", "-0.01") без потери точности.
+// Дробных знаков не может быть больше, чем у валюты (кроме незначащих нулей).
+func Parse(s, currency string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || strings.ContainsAny(s, "/eExXpP_") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	scaled := new(big.Rat).Mul(r, scaleRat(currency))
+	if !scaled.IsInt() {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, s, Exponent(currency), currency)
+	}
+	if !scaled.Num().IsInt64() {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+	return Money{Amount: scaled.Num().Int64(), Currency: currency}, nil
+}
+
+// MustParse — Parse для констант в коде
+func MustParse(s, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// FromFloat — для значений из конфигурации. Берётся кратчайшее десятичное
+// представление числа (0.1 → "0.1"), затем сумма округляется до минорных единиц.
+func FromFloat(f float64, currency string, mode RoundingMode) Money {
+	return FromRat(RatFromFloat(f), currency, mode)
+}
+
+// FromRat округляет точное рациональное значение до минорных единиц
+func FromRat(r *big.Rat, currency string, mode RoundingMode) Money {
+	scaled := new(big.Rat).Mul(r, scaleRat(currency))
+	return Money{Amount: roundRat(scaled, mode), Currency: currency}
+}
+
+// RatFromFloat — точное рациональное значение десятичной записи числа (ставки, проценты)
+func RatFromFloat(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	return r
+}
+
+// Rat — точное значение суммы в основных единицах
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(m.Amount), scaleInt(m.Currency))
+}
+
+func (m Money) String() string {
+	exp := Exponent(m.Currency)
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// Float64 — только для отображения и логов, не для расчётов
+func (m Money) Float64() float64 {
+	f, _ := m.Rat().Float64()
+	return f
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+func (m Money) Abs() Money {
+	if m.Amount < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Смешивание валют в арифметике — ошибка программиста, поэтому паника, а не error
+func (m Money) mustMatch(o Money) {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("money: currency mismatch %s/%s", m.Currency, o.Currency))
+	}
+}
+
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}
+}
+
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}
+}
+
+// Cmp возвращает -1, 0 или 1
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) LessThan(o Money) bool    { return m.Cmp(o) < 0 }
+func (m Money) GreaterThan(o Money) bool { return m.Cmp(o) > 0 }
+
+func Min(a, b Money) Money {
+	if a.LessThan(b) {
+		return a
+	}
+	return b
+}
+
+// MulRat умножает сумму на точный коэффициент с явным округлением
+func (m Money) MulRat(factor *big.Rat, mode RoundingMode) Money {
+	return FromRat(new(big.Rat).Mul(m.Rat(), factor), m.Currency, mode)
+}
+
+// Convert переводит сумму в другую валюту по курсу (единиц currency за единицу m.Currency)
+func (m Money) Convert(rate *big.Rat, currency string, mode RoundingMode) Money {
+	return FromRat(new(big.Rat).Mul(m.Rat(), rate), currency, mode)
+}
+
+// Percent — pct процентов от суммы
+func (m Money) Percent(pct float64, mode RoundingMode) Money {
+	factor := new(big.Rat).Quo(RatFromFloat(pct), big.NewRat(100, 1))
+	return m.MulRat(factor, mode)
+}
+
+// MarshalJSON — точное десятичное число без кавычек: 1234.50
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON принимает число или строку. Валюта — уже заданная у получателя либо DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	parsed, err := Parse(s, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value — для записи в столбцы NUMERIC
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan читает NUMERIC. Валюта берётся уже заданная у получателя либо DefaultCurrency.
+func (m *Money) Scan(src interface{}) error {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*m = Zero(currency)
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		*m = FromFloat(v, currency, HalfUp)
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+
+	// NUMERIC без ограничения масштаба (например, SUM по ставкам) может дать лишние знаки
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	*m = FromRat(r, currency, HalfUp)
+	return nil
+}
+
+// roundRat округляет до целого по правилу mode
+func roundRat(r *big.Rat, mode RoundingMode) int64 {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+	neg := num.Sign() < 0
+	num.Abs(num)
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	twice := new(big.Int).Mul(rem, big.NewInt(2))
+	switch twice.Cmp(den) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		if mode == HalfUp || q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if neg {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
+
+func scaleInt(currency string) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(Exponent(currency))), nil)
+}
+
+func scaleRat(currency string) *big.Rat {
+	return new(big.Rat).SetInt(scaleInt(currency))
+}
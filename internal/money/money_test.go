@@ -0,0 +1,120 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		amount string // сумма в основных единицах, точная дробь
+		mode   RoundingMode
+		want   int64
+	}{
+		{"1.005", HalfUp, 101},
+		{"1.005", HalfEven, 100},
+		{"1.015", HalfUp, 102},
+		{"1.015", HalfEven, 102},
+		{"1.025", HalfEven, 102},
+		{"1.0049", HalfUp, 100},
+		{"1.0051", HalfEven, 101},
+		{"-1.005", HalfUp, -101},
+		{"-1.005", HalfEven, -100},
+		{"-1.015", HalfEven, -102},
+		{"0.005", HalfEven, 0},
+		{"1/3", HalfUp, 33},
+		{"2/3", HalfEven, 67},
+	}
+	for _, tt := range tests {
+		r, ok := new(big.Rat).SetString(tt.amount)
+		if !ok {
+			t.Fatalf("bad test amount %q", tt.amount)
+		}
+		got := FromRat(r, "RUB", tt.mode)
+		if got.Amount != tt.want {
+			t.Errorf("FromRat(%s, %d) = %d, want %d", tt.amount, tt.mode, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestRoundingZeroExponent(t *testing.T) {
+	if got := FromRat(big.NewRat(5, 2), "JPY", HalfEven); got.Amount != 2 {
+		t.Errorf("2.5 JPY HalfEven = %d, want 2", got.Amount)
+	}
+	if got := FromRat(big.NewRat(5, 2), "JPY", HalfUp); got.Amount != 3 {
+		t.Errorf("2.5 JPY HalfUp = %d, want 3", got.Amount)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     int64
+	}{
+		{"1234.5", "RUB", 123450},
+		{"-0.01", "RUB", -1},
+		{"0", "RUB", 0},
+		{" 10 ", "RUB", 1000},
+		{"1.500", "RUB", 150}, // незначащие нули допустимы
+		{".5", "USD", 50},
+		{"100", "JPY", 100},
+		{"92233720368547758.07", "RUB", 9223372036854775807},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.currency)
+		if err != nil {
+			t.Errorf("Parse(%q, %s): %v", tt.in, tt.currency, err)
+			continue
+		}
+		if got.Amount != tt.want || got.Currency != tt.currency {
+			t.Errorf("Parse(%q, %s) = %+v, want %d", tt.in, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+	}{
+		{"", "RUB"},
+		{"abc", "RUB"},
+		{"1.001", "RUB"},
+		{"1.5", "JPY"},
+		{"1e2", "RUB"},
+		{"1E2", "RUB"},
+		{"1/2", "RUB"},
+		{"0x10", "RUB"},
+		{"1_000", "RUB"},
+		{"92233720368547758.08", "RUB"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.in, tt.currency); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Parse(%q, %s) error = %v, want ErrInvalidAmount", tt.in, tt.currency, err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{New(123450, "RUB"), "1234.50"},
+		{New(-1, "RUB"), "-0.01"},
+		{New(5, "RUB"), "0.05"},
+		{New(0, "RUB"), "0.00"},
+		{New(100, "JPY"), "100"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.m, got, tt.want)
+		}
+		back, err := Parse(tt.want, tt.m.Currency)
+		if err != nil || back != tt.m {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", tt.want, back, err, tt.m)
+		}
+	}
+}
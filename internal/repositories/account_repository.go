@@ -1,38 +1,44 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"gobankapi/internal/events"
 	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"math/big"
 )
 
 type AccountRepository struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Events *events.Bus // после зачисления публикуется AccountFunded; nil — без событий
 }
 
 func NewAccountRepository(db *sql.DB) *AccountRepository {
 	return &AccountRepository{DB: db}
 }
 
-func (r *AccountRepository) Create(account *models.Account) error {
+func (r *AccountRepository) Create(ctx context.Context, account *models.Account) error {
 	query := `
-		INSERT INTO accounts (user_id, number, balance)
-		VALUES ($1, $2, $3)
+		INSERT INTO accounts (user_id, number, currency, balance)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at
 	`
-	err := r.DB.QueryRow(query, account.UserID, account.Number, account.Balance).
+	err := r.DB.QueryRowContext(ctx, query, account.UserID, account.Number, account.Currency, account.Balance).
 		Scan(&account.ID, &account.CreatedAt)
 	return err
 }
 
-func (r *AccountRepository) FindByUserID(userID int) ([]*models.Account, error) {
+func (r *AccountRepository) FindByUserID(ctx context.Context, userID int) ([]*models.Account, error) {
 	query := `
-		SELECT id, user_id, number, balance, created_at
+		SELECT id, user_id, number, currency, balance, held, created_at
 		FROM accounts
 		WHERE user_id = $1
 		ORDER BY created_at DESC
 	`
-	rows, err := r.DB.Query(query, userID)
+	rows, err := r.DB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -41,66 +47,301 @@ func (r *AccountRepository) FindByUserID(userID int) ([]*models.Account, error)
 	var accounts []*models.Account
 	for rows.Next() {
 		var acc models.Account
-		err := rows.Scan(&acc.ID, &acc.UserID, &acc.Number, &acc.Balance, &acc.CreatedAt)
+		var balance, held string
+		err := rows.Scan(&acc.ID, &acc.UserID, &acc.Number, &acc.Currency, &balance, &held, &acc.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		if acc.Balance, err = money.Parse(balance, acc.Currency); err != nil {
+			return nil, err
+		}
+		if acc.Held, err = money.Parse(held, acc.Currency); err != nil {
+			return nil, err
+		}
+		acc.Available = acc.Balance.Sub(acc.Held)
 		accounts = append(accounts, &acc)
 	}
 
 	return accounts, nil
 }
 
-func (r *AccountRepository) UpdateBalance(accountID, userID int, amount float64) error {
-	query := `
-		UPDATE accounts
-		SET balance = balance + $1
-		WHERE id = $2 AND user_id = $3
-	`
-	result, err := r.DB.Exec(query, amount, accountID, userID)
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrCurrencyMismatch  = errors.New("amount currency does not match account currency")
+	ErrSameAccount       = errors.New("cannot transfer to the same account")
+)
+
+// RateQuoter — источник курса конвертации from → to для переводов между валютами
+type RateQuoter interface {
+	Quote(ctx context.Context, from, to string) (*big.Rat, error)
+}
+
+// Пополнение счёта: баланс, проводка (дебет кассы, кредит счёта) и запись в истории — одной транзакцией
+func (r *AccountRepository) Deposit(ctx context.Context, accountID, userID int, amount money.Money) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	defer tx.Rollback()
+
+	balance, err := lockAccount(ctx, tx, accountID, userID)
+	if err != nil {
+		return err
+	}
+	if balance.Currency != amount.Currency {
+		return ErrCurrencyMismatch
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET balance = balance + $1 WHERE id = $2`, amount, accountID); err != nil {
+		return err
+	}
+
+	entry := &models.LedgerEntry{
+		Type: "deposit",
+		Postings: []models.LedgerPosting{
+			models.GLPosting(models.GLCash, models.DirectionDebit, amount),
+			models.AccountPosting(accountID, models.DirectionCredit, amount),
+		},
+	}
+	if err := postLedgerEntry(ctx, tx, entry); err != nil {
+		return err
 	}
+
+	err = logTransaction(ctx, tx, &models.Transaction{
+		ToAccountID:   &accountID,
+		Amount:        amount,
+		Type:          "deposit",
+		LedgerEntryID: &entry.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.Events.Publish(ctx, events.AccountFunded{AccountID: accountID, Amount: amount, Source: events.FundingDeposit})
 	return nil
 }
 
-func (r *AccountRepository) Transfer(fromID, toID, userID int, amount float64) error {
-	tx, err := r.DB.Begin()
+// Снятие со счёта: дебет счёта, кредит кассы
+func (r *AccountRepository) Withdraw(ctx context.Context, accountID, userID int, amount money.Money) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	balance, err := lockAccount(ctx, tx, accountID, userID)
+	if err != nil {
+		return err
+	}
+	if balance.Currency != amount.Currency {
+		return ErrCurrencyMismatch
+	}
+	if balance.LessThan(amount) {
+		return ErrInsufficientFunds
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET balance = balance - $1 WHERE id = $2`, amount, accountID); err != nil {
+		return err
+	}
+
+	entry := &models.LedgerEntry{
+		Type: "withdraw",
+		Postings: []models.LedgerPosting{
+			models.AccountPosting(accountID, models.DirectionDebit, amount),
+			models.GLPosting(models.GLCash, models.DirectionCredit, amount),
+		},
+	}
+	if err := postLedgerEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	err = logTransaction(ctx, tx, &models.Transaction{
+		FromAccountID: &accountID,
+		Amount:        amount.Neg(),
+		Type:          "withdraw",
+		LedgerEntryID: &entry.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Перевод между счетами. amount — в валюте счёта отправителя. Если валюта получателя другая,
+// сумма конвертируется по курсу из rates, а проводка проходит через валютную позицию банка:
+// дебет отправителя / кредит позиции в одной валюте, дебет позиции / кредит получателя в другой.
+func (r *AccountRepository) Transfer(ctx context.Context, fromID, toID, userID int, amount money.Money, rates RateQuoter) error {
+	if fromID == toID {
+		return ErrSameAccount
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// Проверка баланса отправителя
-	var balance float64
-	err = tx.QueryRow(`SELECT balance FROM accounts WHERE id = $1 AND user_id = $2`, fromID, userID).Scan(&balance)
+	// Блокируем оба счёта в порядке id, чтобы встречные переводы не взаимоблокировались
+	rows, err := tx.QueryContext(ctx, `SELECT id, user_id, currency, balance - held FROM accounts WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`, fromID, toID)
 	if err != nil {
 		return err
 	}
-	if balance < amount {
-		return errors.New("insufficient funds")
+	var (
+		found      int
+		balance    money.Money
+		owner      int
+		toCurrency string
+	)
+	for rows.Next() {
+		var id, uid int
+		var currency, bal string
+		if err := rows.Scan(&id, &uid, &currency, &bal); err != nil {
+			rows.Close()
+			return err
+		}
+		found++
+		if id == fromID {
+			owner = uid
+			if balance, err = money.Parse(bal, currency); err != nil {
+				rows.Close()
+				return err
+			}
+		} else {
+			toCurrency = currency
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Проверка владельца и доступного остатка отправителя (за вычетом холдов по картам)
+	if found != 2 || owner != userID {
+		return ErrAccountNotFound
+	}
+	if balance.Currency != amount.Currency {
+		return ErrCurrencyMismatch
+	}
+	if balance.LessThan(amount) {
+		return ErrInsufficientFunds
+	}
+
+	// Сумма зачисления в валюте получателя
+	credited := amount
+	var rate *big.Rat
+	if toCurrency != amount.Currency {
+		if rates == nil {
+			return fmt.Errorf("no exchange rate source for %s/%s", amount.Currency, toCurrency)
+		}
+		if rate, err = rates.Quote(ctx, amount.Currency, toCurrency); err != nil {
+			return err
+		}
+		credited = amount.Convert(rate, toCurrency, money.HalfUp)
+		if !credited.IsPositive() {
+			return fmt.Errorf("%w: %s %s is less than minimal unit of %s", money.ErrInvalidAmount, amount, amount.Currency, toCurrency)
+		}
 	}
 
 	// Списание и зачисление
-	_, err = tx.Exec(`UPDATE accounts SET balance = balance - $1 WHERE id = $2`, amount, fromID)
+	_, err = tx.ExecContext(ctx, `UPDATE accounts SET balance = balance - $1 WHERE id = $2`, amount, fromID)
 	if err != nil {
 		return err
 	}
-	_, err = tx.Exec(`UPDATE accounts SET balance = balance + $1 WHERE id = $2`, amount, toID)
+	_, err = tx.ExecContext(ctx, `UPDATE accounts SET balance = balance + $1 WHERE id = $2`, credited, toID)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	entry := &models.LedgerEntry{Type: "transfer"}
+	if rate == nil {
+		entry.Postings = []models.LedgerPosting{
+			models.AccountPosting(fromID, models.DirectionDebit, amount),
+			models.AccountPosting(toID, models.DirectionCredit, credited),
+		}
+	} else {
+		entry.Description = fmt.Sprintf("Конвертация %s %s → %s %s", amount, amount.Currency, credited, credited.Currency)
+		entry.Postings = []models.LedgerPosting{
+			models.AccountPosting(fromID, models.DirectionDebit, amount),
+			models.GLPosting(models.GLFXPosition, models.DirectionCredit, amount),
+			models.GLPosting(models.GLFXPosition, models.DirectionDebit, credited),
+			models.AccountPosting(toID, models.DirectionCredit, credited),
+		}
+	}
+	if err := postLedgerEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	// списание со счёта отправителя
+	debit := &models.Transaction{
+		FromAccountID: &fromID,
+		Amount:        amount.Neg(),
+		Type:          "transfer",
+		LedgerEntryID: &entry.ID,
+	}
+	// пополнение счёта получателя
+	credit := &models.Transaction{
+		ToAccountID:   &toID,
+		Amount:        credited,
+		Type:          "transfer",
+		LedgerEntryID: &entry.ID,
+	}
+	if rate != nil {
+		// counter_amount — та же сумма в валюте второго счёта, с тем же знаком
+		debitCounter, creditCounter := credited.Neg(), amount
+		debit.CounterAmount, debit.ExchangeRate = &debitCounter, rate.FloatString(10)
+		credit.CounterAmount, credit.ExchangeRate = &creditCounter, new(big.Rat).Inv(rate).FloatString(10)
+	}
+
+	if err := logTransaction(ctx, tx, debit); err != nil {
+		return err
+	}
+	if err := logTransaction(ctx, tx, credit); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.Events.Publish(ctx, events.AccountFunded{AccountID: toID, Amount: credited, Source: events.FundingTransfer})
+	return nil
+}
+
+// Блокировка строки счёта владельца до конца транзакции. Возвращает доступный остаток в валюте
+// счёта: баланс за вычетом холдов по картам.
+func lockAccount(ctx context.Context, tx *sql.Tx, accountID, userID int) (money.Money, error) {
+	var currency, balance string
+	err := tx.QueryRowContext(ctx, `SELECT currency, balance - held FROM accounts WHERE id = $1 AND user_id = $2 FOR UPDATE`, accountID, userID).
+		Scan(&currency, &balance)
+	if err == sql.ErrNoRows {
+		return money.Money{}, ErrAccountNotFound
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+	return money.Parse(balance, currency)
+}
+
+func (r *AccountRepository) GetBalance(ctx context.Context, accountID int, userID int) (money.Money, error) {
+	query := `SELECT currency, balance FROM accounts WHERE id = $1 AND user_id = $2`
+	var currency, balance string
+	if err := r.DB.QueryRowContext(ctx, query, accountID, userID).Scan(&currency, &balance); err != nil {
+		return money.Money{}, err
+	}
+	return money.Parse(balance, currency)
 }
 
-func (r *AccountRepository) GetBalance(accountID int, userID int) (float64, error) {
-	query := `SELECT balance FROM accounts WHERE id = $1 AND user_id = $2`
-	var balance float64
-	err := r.DB.QueryRow(query, accountID, userID).Scan(&balance)
-	return balance, err
+// GetCurrency — валюта счёта владельца (ErrAccountNotFound, если счёт чужой или не существует)
+func (r *AccountRepository) GetCurrency(ctx context.Context, accountID int, userID int) (string, error) {
+	var currency string
+	err := r.DB.QueryRowContext(ctx, `SELECT currency FROM accounts WHERE id = $1 AND user_id = $2`, accountID, userID).
+		Scan(&currency)
+	if err == sql.ErrNoRows {
+		return "", ErrAccountNotFound
+	}
+	return currency, err
 }
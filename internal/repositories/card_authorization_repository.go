@@ -0,0 +1,366 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+)
+
+var (
+	ErrAuthorizationNotFound = errors.New("authorization not found")
+	ErrAuthorizationState    = errors.New("authorization is not approved or has expired")
+	ErrCaptureExceedsHold    = errors.New("capture amount exceeds authorized amount")
+	ErrCardInactive          = errors.New("card is not active")
+	ErrCardAccountMismatch   = errors.New("card account belongs to another user")
+)
+
+// ControlViolation — авторизация нарушает ограничения карты; Reason — причина отказа (models.Decline*)
+type ControlViolation struct {
+	Reason string
+}
+
+func (v *ControlViolation) Error() string {
+	return "card controls violated: " + v.Reason
+}
+
+type CardAuthorizationRepository struct {
+	DB *sql.DB
+}
+
+func NewCardAuthorizationRepository(db *sql.DB) *CardAuthorizationRepository {
+	return &CardAuthorizationRepository{DB: db}
+}
+
+const authorizationColumns = `
+	id, card_id, account_id, currency, amount, captured_amount, merchant, COALESCE(mcc, ''), COALESCE(channel, ''),
+	status, COALESCE(decline_reason, ''),
+	COALESCE(auth_code, ''), ledger_entry_id, expires_at, created_at, updated_at
+`
+
+func scanAuthorization(row interface{ Scan(...interface{}) error }) (*models.CardAuthorization, error) {
+	var a models.CardAuthorization
+	var currency, amount, captured string
+	err := row.Scan(&a.ID, &a.CardID, &a.AccountID, &currency, &amount, &captured, &a.Merchant, &a.MCC, &a.Channel, &a.Status,
+		&a.DeclineReason, &a.AuthCode, &a.LedgerEntryID, &a.ExpiresAt, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if a.Amount, err = money.Parse(amount, currency); err != nil {
+		return nil, err
+	}
+	if a.CapturedAmount, err = money.Parse(captured, currency); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *CardAuthorizationRepository) FindByID(ctx context.Context, id int) (*models.CardAuthorization, error) {
+	query := `SELECT ` + authorizationColumns + ` FROM card_authorizations WHERE id = $1`
+	a, err := scanAuthorization(r.DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrAuthorizationNotFound
+	}
+	return a, err
+}
+
+func insertAuthorization(ctx context.Context, q rowQuerier, a *models.CardAuthorization) error {
+	return q.QueryRowContext(ctx, `
+		INSERT INTO card_authorizations (card_id, account_id, amount, currency, merchant, mcc, channel, status,
+			decline_reason, auth_code, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''), NULLIF($10, ''), $11)
+		RETURNING id, created_at, updated_at
+	`, a.CardID, a.AccountID, a.Amount, a.Amount.Currency, a.Merchant, a.MCC, a.Channel, a.Status, a.DeclineReason,
+		a.AuthCode, a.ExpiresAt).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// Decline сохраняет отклонённую авторизацию с причиной a.DeclineReason
+func (r *CardAuthorizationRepository) Decline(ctx context.Context, a *models.CardAuthorization) error {
+	a.Status = models.AuthDeclined
+	a.CapturedAmount = money.Zero(a.Amount.Currency)
+	return insertAuthorization(ctx, r.DB, a)
+}
+
+// Approve ставит холд на a.Amount: если карта активна, ограничения карты соблюдены и доступного
+// остатка счёта хватает, сохраняет авторизацию и увеличивает held счёта. Иначе возвращает
+// ErrCardInactive, ErrCardAccountMismatch, *ControlViolation, ErrInsufficientFunds или
+// ErrCurrencyMismatch без изменений.
+// Статус и ограничения перечитываются под блокировкой строки карты: заморозка, блокировка или
+// смена ограничений, успевшие завершиться до неё, учитываются, а параллельные авторизации
+// не превысят лимиты вместе.
+func (r *CardAuthorizationRepository) Approve(ctx context.Context, a *models.CardAuthorization) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var ownAccount bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT c.status, a.user_id = c.user_id AND a.id = $2
+		FROM cards c
+		JOIN accounts a ON a.id = c.account_id
+		WHERE c.id = $1
+		FOR UPDATE OF c
+	`, a.CardID, a.AccountID).Scan(&status, &ownAccount)
+	if err == sql.ErrNoRows {
+		return ErrCardNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !ownAccount {
+		return ErrCardAccountMismatch
+	}
+	if status != models.CardActive {
+		return ErrCardInactive
+	}
+	controls, err := findControls(ctx, tx, a.CardID)
+	if err != nil {
+		return err
+	}
+	if reason := controls.Violation(a.Amount, a.MCC, a.Channel); reason != "" {
+		return &ControlViolation{Reason: reason}
+	}
+
+	var currency, raw string
+	err = tx.QueryRowContext(ctx, `SELECT currency, balance - held FROM accounts WHERE id = $1 FOR UPDATE`, a.AccountID).
+		Scan(&currency, &raw)
+	if err == sql.ErrNoRows {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if currency != a.Amount.Currency {
+		return ErrCurrencyMismatch
+	}
+	if err := checkSpending(ctx, tx, a, controls); err != nil {
+		return err
+	}
+	available, err := money.Parse(raw, currency)
+	if err != nil {
+		return err
+	}
+	if available.LessThan(a.Amount) {
+		return ErrInsufficientFunds
+	}
+
+	a.Status = models.AuthApproved
+	a.CapturedAmount = money.Zero(currency)
+	if err := insertAuthorization(ctx, tx, a); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET held = held + $1 WHERE id = $2`, a.Amount, a.AccountID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkSpending сверяет авторизацию с лимитами на день и месяц и с одноразовостью карты.
+// В расход входят холды одобренных авторизаций и списанные суммы за текущие сутки и месяц.
+func checkSpending(ctx context.Context, tx *sql.Tx, a *models.CardAuthorization, controls *models.CardControls) error {
+	if controls == nil || (controls.DailyLimit == nil && controls.MonthlyLimit == nil && !controls.SingleUse) {
+		return nil
+	}
+	var dailyRaw, monthlyRaw string
+	var used int
+	err := tx.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN status = $2 THEN amount ELSE captured_amount END)
+				FILTER (WHERE created_at >= date_trunc('day', NOW())), 0),
+			COALESCE(SUM(CASE WHEN status = $2 THEN amount ELSE captured_amount END)
+				FILTER (WHERE created_at >= date_trunc('month', NOW())), 0),
+			COUNT(*)
+		FROM card_authorizations
+		WHERE card_id = $1 AND status IN ($2, $3)
+	`, a.CardID, models.AuthApproved, models.AuthCaptured).Scan(&dailyRaw, &monthlyRaw, &used)
+	if err != nil {
+		return err
+	}
+	if controls.SingleUse && used > 0 {
+		return &ControlViolation{Reason: models.DeclineSingleUseSpent}
+	}
+	spent := []struct {
+		raw    string
+		limit  *money.Money
+		reason string
+	}{
+		{dailyRaw, controls.DailyLimit, models.DeclineDailyLimit},
+		{monthlyRaw, controls.MonthlyLimit, models.DeclineMonthlyLimit},
+	}
+	for _, s := range spent {
+		if s.limit == nil {
+			continue
+		}
+		total, err := money.Parse(s.raw, a.Amount.Currency)
+		if err != nil {
+			return err
+		}
+		if total.Add(a.Amount).GreaterThan(*s.limit) {
+			return &ControlViolation{Reason: s.reason}
+		}
+	}
+	return nil
+}
+
+// lockApproved блокирует авторизацию до конца транзакции; она должна быть одобрена и не истекшей
+func lockApproved(ctx context.Context, tx *sql.Tx, id int, now time.Time) (*models.CardAuthorization, error) {
+	query := `SELECT ` + authorizationColumns + ` FROM card_authorizations WHERE id = $1 FOR UPDATE`
+	a, err := scanAuthorization(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrAuthorizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != models.AuthApproved || (a.ExpiresAt != nil && a.ExpiresAt.Before(now)) {
+		return nil, ErrAuthorizationState
+	}
+	return a, nil
+}
+
+// Capture списывает со счёта amount (nil — всю сумму холда) и снимает холд целиком:
+// остаток частичного списания возвращается в доступный остаток. Списание проводится
+// дебетом счёта и кредитом расчётов с платёжной системой. Одноразовая карта после
+// списания блокируется с причиной used.
+func (r *CardAuthorizationRepository) Capture(ctx context.Context, id int, amount *money.Money, now time.Time) (*models.CardAuthorization, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	a, err := lockApproved(ctx, tx, id, now)
+	if err != nil {
+		return nil, err
+	}
+	captured := a.Amount
+	if amount != nil {
+		if amount.Currency != a.Amount.Currency {
+			return nil, ErrCurrencyMismatch
+		}
+		if !amount.IsPositive() {
+			return nil, money.ErrInvalidAmount
+		}
+		if amount.GreaterThan(a.Amount) {
+			return nil, ErrCaptureExceedsHold
+		}
+		captured = *amount
+	}
+
+	// Порядок блокировок — авторизация, карта, счёт — тот же, что в Approve
+	var singleUse bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(cc.single_use, false)
+		FROM cards c LEFT JOIN card_controls cc ON cc.card_id = c.id
+		WHERE c.id = $1
+		FOR UPDATE OF c
+	`, a.CardID).Scan(&singleUse)
+	if err != nil {
+		return nil, err
+	}
+	if singleUse {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE cards SET status = $1, block_reason = $2, status_changed_at = NOW()
+			WHERE id = $3 AND status IN ($4, $5)
+		`, models.CardBlocked, models.BlockUsed, a.CardID, models.CardActive, models.CardFrozen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Баланс уменьшается на списанное, held — на весь холд; доступный остаток растёт на разницу
+	_, err = tx.ExecContext(ctx, `UPDATE accounts SET balance = balance - $1, held = held - $2 WHERE id = $3`,
+		captured, a.Amount, a.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.LedgerEntry{
+		Type:        "card_payment",
+		Description: a.Merchant,
+		Postings: []models.LedgerPosting{
+			models.AccountPosting(a.AccountID, models.DirectionDebit, captured),
+			models.GLPosting(models.GLCardSettlement, models.DirectionCredit, captured),
+		},
+	}
+	if err := postLedgerEntry(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+	err = logTransaction(ctx, tx, &models.Transaction{
+		FromAccountID: &a.AccountID,
+		Amount:        captured.Neg(),
+		Type:          "card_payment",
+		LedgerEntryID: &entry.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE card_authorizations
+		SET status = $1, captured_amount = $2, ledger_entry_id = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING updated_at
+	`, models.AuthCaptured, captured, entry.ID, a.ID).Scan(&a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	a.Status, a.CapturedAmount, a.LedgerEntryID = models.AuthCaptured, captured, &entry.ID
+	return a, nil
+}
+
+// Reverse отменяет одобренную авторизацию и снимает её холд без списания
+func (r *CardAuthorizationRepository) Reverse(ctx context.Context, id int, now time.Time) (*models.CardAuthorization, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	a, err := lockApproved(ctx, tx, id, now)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET held = held - $1 WHERE id = $2`, a.Amount, a.AccountID); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE card_authorizations SET status = $1, updated_at = NOW() WHERE id = $2 RETURNING updated_at
+	`, models.AuthReversed, a.ID).Scan(&a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	a.Status = models.AuthReversed
+	return a, nil
+}
+
+// ExpireBefore переводит в expired одобренные авторизации со сроком холда до now и снимает
+// их холды со счетов. Возвращает число истёкших авторизаций.
+func (r *CardAuthorizationRepository) ExpireBefore(ctx context.Context, now time.Time) (int, error) {
+	var expired int
+	err := r.DB.QueryRowContext(ctx, `
+		WITH expired AS (
+			UPDATE card_authorizations SET status = $1, updated_at = NOW()
+			WHERE status = $2 AND expires_at < $3
+			RETURNING account_id, amount
+		), released AS (
+			UPDATE accounts a SET held = a.held - e.total
+			FROM (SELECT account_id, SUM(amount) AS total FROM expired GROUP BY account_id) e
+			WHERE a.id = e.account_id
+		)
+		SELECT COUNT(*) FROM expired
+	`, models.AuthExpired, models.AuthApproved, now).Scan(&expired)
+	return expired, err
+}
@@ -0,0 +1,273 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func testAuthorization(amount string) *models.CardAuthorization {
+	expires := time.Now().Add(time.Hour)
+	return &models.CardAuthorization{
+		CardID:    3,
+		AccountID: 42,
+		Amount:    money.MustParse(amount, "RUB"),
+		Merchant:  "shop",
+		AuthCode:  "123456",
+		ExpiresAt: &expires,
+	}
+}
+
+// expectCardLock — блокировка карты: статус и то, что счёт карты принадлежит её владельцу
+func expectCardLock(mock sqlmock.Sqlmock, status string, ownAccount bool) {
+	mock.ExpectQuery(sqlLike("FOR UPDATE OF c")).WithArgs(3, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "own"}).AddRow(status, ownAccount))
+}
+
+// expectNoControls — у карты нет ограничений
+func expectNoControls(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(sqlLike("LEFT JOIN card_controls")).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "tl", "dl", "ml", "allowed", "blocked", "ecom", "single", "updated"}).
+			AddRow("RUB", nil, nil, nil, "{}", "{}", false, false, nil))
+}
+
+func TestApproveHoldsFunds(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectCardLock(mock, models.CardActive, true)
+	expectNoControls(mock)
+	mock.ExpectQuery(sqlLike("SELECT currency, balance - held FROM accounts WHERE id = $1 FOR UPDATE")).WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "available"}).AddRow("RUB", "100.00"))
+	mock.ExpectQuery(sqlLike("INSERT INTO card_authorizations")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(10, time.Now(), time.Now()))
+	mock.ExpectExec(sqlLike("UPDATE accounts SET held = held + $1 WHERE id = $2")).
+		WithArgs(money.MustParse("100", "RUB"), 42).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	a := testAuthorization("100")
+	if err := NewCardAuthorizationRepository(db).Approve(context.Background(), a); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if a.ID != 10 || a.Status != models.AuthApproved {
+		t.Errorf("authorization = %d/%s, want 10/approved", a.ID, a.Status)
+	}
+}
+
+func TestApproveDeclinesBeyondAvailableBalance(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectCardLock(mock, models.CardActive, true)
+	expectNoControls(mock)
+	// Остаток 150, из них 100 уже в холдах — доступно 50
+	mock.ExpectQuery(sqlLike("balance - held")).WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "available"}).AddRow("RUB", "50.00"))
+	mock.ExpectRollback()
+
+	err := NewCardAuthorizationRepository(db).Approve(context.Background(), testAuthorization("50.01"))
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("Approve beyond available: %v, want ErrInsufficientFunds", err)
+	}
+}
+
+func TestApproveRejectsForeignAccount(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectCardLock(mock, models.CardActive, false)
+	mock.ExpectRollback()
+
+	err := NewCardAuthorizationRepository(db).Approve(context.Background(), testAuthorization("10"))
+	if !errors.Is(err, ErrCardAccountMismatch) {
+		t.Fatalf("Approve on foreign account: %v, want ErrCardAccountMismatch", err)
+	}
+}
+
+func TestApproveRejectsCardFrozenConcurrently(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	// Заморозка успела завершиться до блокировки строки карты
+	expectCardLock(mock, models.CardFrozen, true)
+	mock.ExpectRollback()
+
+	err := NewCardAuthorizationRepository(db).Approve(context.Background(), testAuthorization("10"))
+	if !errors.Is(err, ErrCardInactive) {
+		t.Fatalf("Approve on frozen card: %v, want ErrCardInactive", err)
+	}
+}
+
+// expectLockedAuthorization — авторизация 10 на 100 ₽ по карте 3 и счёту 42 в статусе status
+func expectLockedAuthorization(mock sqlmock.Sqlmock, status string, expiresAt time.Time) {
+	mock.ExpectQuery(sqlLike("FROM card_authorizations WHERE id = $1 FOR UPDATE")).WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "card_id", "account_id", "currency", "amount", "captured_amount",
+			"merchant", "mcc", "channel", "status", "decline_reason", "auth_code", "ledger_entry_id", "expires_at",
+			"created_at", "updated_at"}).
+			AddRow(10, 3, 42, "RUB", "100.00", "0.00", "shop", "5411", "pos", status, "", "123456", nil, expiresAt,
+				time.Now(), time.Now()))
+}
+
+func TestPartialCaptureReleasesRestOfHold(t *testing.T) {
+	db, mock := newMockDB(t)
+	now := time.Now()
+	captured := money.MustParse("60", "RUB")
+	mock.ExpectBegin()
+	expectLockedAuthorization(mock, models.AuthApproved, now.Add(time.Hour))
+	mock.ExpectQuery(sqlLike("SELECT COALESCE(cc.single_use, false)")).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"single_use"}).AddRow(false))
+	mock.ExpectExec(sqlLike("SET balance = balance - $1, held = held - $2")).
+		WithArgs(captured, money.MustParse("100", "RUB"), 42).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(sqlLike("INSERT INTO ledger_entries")).WithArgs("card_payment", "shop").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(20, now))
+	mock.ExpectQuery(sqlLike("INSERT INTO ledger_postings")).WithArgs(20, 42, nil, models.DirectionDebit, captured, "RUB").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(sqlLike("INSERT INTO ledger_postings")).WithArgs(20, nil, models.GLCardSettlement, models.DirectionCredit, captured, "RUB").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(sqlLike("INSERT INTO transactions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(30, now))
+	mock.ExpectQuery(sqlLike("SET status = $1, captured_amount = $2")).WithArgs(models.AuthCaptured, captured, 20, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+	mock.ExpectCommit()
+
+	a, err := NewCardAuthorizationRepository(db).Capture(context.Background(), 10, &captured, now)
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if a.Status != models.AuthCaptured || a.CapturedAmount != captured {
+		t.Errorf("authorization = %s/%s, want captured/60", a.Status, a.CapturedAmount)
+	}
+}
+
+func TestCaptureRejected(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		status    string
+		expiresAt time.Time
+		amount    string
+		err       error
+	}{
+		{"more than held", models.AuthApproved, now.Add(time.Hour), "100.01", ErrCaptureExceedsHold},
+		{"expired hold", models.AuthApproved, now.Add(-time.Minute), "50", ErrAuthorizationState},
+		{"already reversed", models.AuthReversed, now.Add(time.Hour), "50", ErrAuthorizationState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectBegin()
+			expectLockedAuthorization(mock, tt.status, tt.expiresAt)
+			mock.ExpectRollback()
+
+			amount := money.MustParse(tt.amount, "RUB")
+			if _, err := NewCardAuthorizationRepository(db).Capture(context.Background(), 10, &amount, now); !errors.Is(err, tt.err) {
+				t.Errorf("Capture: err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReverseReleasesHold(t *testing.T) {
+	db, mock := newMockDB(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	expectLockedAuthorization(mock, models.AuthApproved, now.Add(time.Hour))
+	mock.ExpectExec(sqlLike("UPDATE accounts SET held = held - $1 WHERE id = $2")).
+		WithArgs(money.MustParse("100", "RUB"), 42).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(sqlLike("UPDATE card_authorizations SET status = $1")).WithArgs(models.AuthReversed, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+	mock.ExpectCommit()
+
+	a, err := NewCardAuthorizationRepository(db).Reverse(context.Background(), 10, now)
+	if err != nil || a.Status != models.AuthReversed {
+		t.Fatalf("Reverse = %v, %v; want reversed", a, err)
+	}
+}
+
+func TestReverseCapturedAuthorizationRejected(t *testing.T) {
+	db, mock := newMockDB(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	expectLockedAuthorization(mock, models.AuthCaptured, now.Add(time.Hour))
+	mock.ExpectRollback()
+
+	if _, err := NewCardAuthorizationRepository(db).Reverse(context.Background(), 10, now); !errors.Is(err, ErrAuthorizationState) {
+		t.Fatalf("Reverse captured: err = %v, want %v", err, ErrAuthorizationState)
+	}
+}
+
+// expectControls — ограничения карты: дневной лимит и список запрещённых категорий
+func expectControls(mock sqlmock.Sqlmock, dailyLimit interface{}, blocked string, singleUse bool) {
+	mock.ExpectQuery(sqlLike("LEFT JOIN card_controls")).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "tl", "dl", "ml", "allowed", "blocked", "ecom", "single", "updated"}).
+			AddRow("RUB", nil, dailyLimit, nil, "{}", blocked, false, singleUse, time.Now()))
+}
+
+// expectSpending — потрачено по карте за сутки и месяц и число операций
+func expectSpending(mock sqlmock.Sqlmock, daily, monthly string, used int) {
+	mock.ExpectQuery(sqlLike("FROM card_authorizations\n\t\tWHERE card_id = $1 AND status IN ($2, $3)")).
+		WithArgs(3, models.AuthApproved, models.AuthCaptured).
+		WillReturnRows(sqlmock.NewRows([]string{"daily", "monthly", "used"}).AddRow(daily, monthly, used))
+}
+
+func TestApproveWithinDailyLimit(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectCardLock(mock, models.CardActive, true)
+	expectControls(mock, "1000.00", "{7995}", false)
+	mock.ExpectQuery(sqlLike("balance - held")).WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "available"}).AddRow("RUB", "5000.00"))
+	expectSpending(mock, "900.00", "900.00", 2)
+	mock.ExpectQuery(sqlLike("INSERT INTO card_authorizations")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(10, time.Now(), time.Now()))
+	mock.ExpectExec(sqlLike("UPDATE accounts SET held = held + $1")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	a := testAuthorization("100")
+	a.MCC = "5411"
+	if err := NewCardAuthorizationRepository(db).Approve(context.Background(), a); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+}
+
+func TestApproveDeclinedByControls(t *testing.T) {
+	tests := []struct {
+		name   string
+		mcc    string
+		single bool
+		expect func(mock sqlmock.Sqlmock)
+		reason string
+	}{
+		{"blocked category", "7995", false, func(sqlmock.Sqlmock) {}, models.DeclineMCCNotAllowed},
+		{"daily limit", "5411", false, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(sqlLike("balance - held")).
+				WillReturnRows(sqlmock.NewRows([]string{"currency", "available"}).AddRow("RUB", "5000.00"))
+			expectSpending(mock, "900.01", "900.01", 2)
+		}, models.DeclineDailyLimit},
+		{"single-use card spent", "5411", true, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(sqlLike("balance - held")).
+				WillReturnRows(sqlmock.NewRows([]string{"currency", "available"}).AddRow("RUB", "5000.00"))
+			expectSpending(mock, "0.00", "50.00", 1)
+		}, models.DeclineSingleUseSpent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectBegin()
+			expectCardLock(mock, models.CardActive, true)
+			expectControls(mock, "1000.00", "{7995}", tt.single)
+			tt.expect(mock)
+			mock.ExpectRollback()
+
+			a := testAuthorization("100")
+			a.MCC = tt.mcc
+			err := NewCardAuthorizationRepository(db).Approve(context.Background(), a)
+			var violation *ControlViolation
+			if !errors.As(err, &violation) || violation.Reason != tt.reason {
+				t.Fatalf("Approve: err = %v, want control violation %s", err, tt.reason)
+			}
+		})
+	}
+}
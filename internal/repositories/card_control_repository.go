@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+
+	"github.com/lib/pq"
+)
+
+type CardControlRepository struct {
+	DB *sql.DB
+}
+
+func NewCardControlRepository(db *sql.DB) *CardControlRepository {
+	return &CardControlRepository{DB: db}
+}
+
+// Find — ограничения карты; если они не заданы, возвращаются пустые (без ограничений).
+// Лимиты читаются в валюте счёта карты.
+func (r *CardControlRepository) Find(ctx context.Context, cardID int) (*models.CardControls, error) {
+	return findControls(ctx, r.DB, cardID)
+}
+
+// findControls читает ограничения карты через q — соединение или транзакцию вызывающего
+func findControls(ctx context.Context, q rowQuerier, cardID int) (*models.CardControls, error) {
+	c := &models.CardControls{CardID: cardID, AllowedMCCs: []string{}, BlockedMCCs: []string{}}
+	var limits [3]sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT a.currency, cc.transaction_limit, cc.daily_limit, cc.monthly_limit,
+			COALESCE(cc.allowed_mccs, '{}'), COALESCE(cc.blocked_mccs, '{}'),
+			COALESCE(cc.ecommerce_only, false), COALESCE(cc.single_use, false), cc.updated_at
+		FROM cards c
+		JOIN accounts a ON a.id = c.account_id
+		LEFT JOIN card_controls cc ON cc.card_id = c.id
+		WHERE c.id = $1
+	`, cardID).Scan(&c.Currency, &limits[0], &limits[1], &limits[2], pq.Array(&c.AllowedMCCs), pq.Array(&c.BlockedMCCs),
+		&c.EcommerceOnly, &c.SingleUse, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCardNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	targets := []**money.Money{&c.TransactionLimit, &c.DailyLimit, &c.MonthlyLimit}
+	for i, limit := range limits {
+		if !limit.Valid {
+			continue
+		}
+		parsed, err := money.Parse(limit.String, c.Currency)
+		if err != nil {
+			return nil, err
+		}
+		*targets[i] = &parsed
+	}
+	return c, nil
+}
+
+// Save заменяет ограничения карты целиком
+func (r *CardControlRepository) Save(ctx context.Context, c *models.CardControls) error {
+	return r.DB.QueryRowContext(ctx, `
+		INSERT INTO card_controls (card_id, transaction_limit, daily_limit, monthly_limit, allowed_mccs, blocked_mccs,
+			ecommerce_only, single_use)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (card_id) DO UPDATE SET
+			transaction_limit = EXCLUDED.transaction_limit,
+			daily_limit = EXCLUDED.daily_limit,
+			monthly_limit = EXCLUDED.monthly_limit,
+			allowed_mccs = EXCLUDED.allowed_mccs,
+			blocked_mccs = EXCLUDED.blocked_mccs,
+			ecommerce_only = EXCLUDED.ecommerce_only,
+			single_use = EXCLUDED.single_use,
+			updated_at = NOW()
+		RETURNING updated_at
+	`, c.CardID, c.TransactionLimit, c.DailyLimit, c.MonthlyLimit, pq.Array(c.AllowedMCCs), pq.Array(c.BlockedMCCs),
+		c.EcommerceOnly, c.SingleUse).Scan(&c.UpdatedAt)
+}
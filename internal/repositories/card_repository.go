@@ -1,8 +1,16 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"gobankapi/internal/models"
+	"time"
+)
+
+var (
+	ErrCardNotFound      = errors.New("card not found")
+	ErrCardStatusChanged = errors.New("card status has changed concurrently")
 )
 
 type CardRepository struct {
@@ -13,30 +21,105 @@ func NewCardRepository(db *sql.DB) *CardRepository {
 	return &CardRepository{DB: db}
 }
 
-func (r *CardRepository) Create(card *models.Card) error {
+const cardColumns = `
+	id, token, user_id, account_id, COALESCE(first6, ''), COALESCE(last4, ''), brand, encrypted_data, wrapped_key, COALESCE(key_version, 0),
+	COALESCE(number_pgp, ''), COALESCE(expiry_pgp, ''), cvv_hash, hmac, status, expires_on, created_at,
+	COALESCE(block_reason, ''), reissued_from,
+	COALESCE((SELECT o.token FROM cards o WHERE o.id = cards.reissued_from), ''), status_changed_at
+`
+
+func scanCard(row interface{ Scan(...interface{}) error }) (*models.Card, error) {
+	var card models.Card
+	err := row.Scan(&card.ID, &card.Token, &card.UserID, &card.AccountID, &card.First6, &card.Last4, &card.Brand, &card.EncryptedData, &card.WrappedKey,
+		&card.KeyVersion, &card.LegacyNumber, &card.LegacyExpiry, &card.CVVHash, &card.HMAC, &card.Status, &card.ExpiresOn, &card.CreatedAt,
+		&card.BlockReason, &card.ReissuedFromID, &card.ReissuedFromToken, &card.StatusChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// Create сохраняет карту к счёту card.AccountID, только если счёт принадлежит card.UserID;
+// иначе возвращает ErrAccountNotFound
+func (r *CardRepository) Create(ctx context.Context, card *models.Card) error {
+	return insertCard(ctx, r.DB, card)
+}
+
+// insertCard вставляет карту, если её счёт принадлежит её владельцу: по карте списываются
+// деньги со счёта, поэтому карта к чужому счёту не создаётся (ErrAccountNotFound)
+func insertCard(ctx context.Context, q rowQuerier, card *models.Card) error {
 	query := `
-		INSERT INTO cards (user_id, account_id, number_pgp, expiry_pgp, cvv_hash, hmac)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at
+		INSERT INTO cards (token, user_id, account_id, first6, last4, brand, encrypted_data, wrapped_key, key_version,
+			cvv_hash, hmac, expires_on, reissued_from)
+		SELECT $1, a.user_id, a.id, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM accounts a
+		WHERE a.user_id = $2 AND a.id = $3
+		RETURNING id, status, created_at
 	`
-	return r.DB.QueryRow(query,
+	err := q.QueryRowContext(ctx, query,
+		card.Token,
 		card.UserID,
 		card.AccountID,
-		card.NumberPGP,
-		card.ExpiryPGP,
+		card.First6,
+		card.Last4,
+		card.Brand,
+		card.EncryptedData,
+		card.WrappedKey,
+		card.KeyVersion,
 		card.CVVHash,
 		card.HMAC,
-	).Scan(&card.ID, &card.CreatedAt)
+		card.ExpiresOn,
+		card.ReissuedFromID,
+	).Scan(&card.ID, &card.Status, &card.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrAccountNotFound
+	}
+	return err
 }
 
-func (r *CardRepository) FindByUserID(userID int) ([]*models.Card, error) {
-	query := `
-		SELECT id, user_id, account_id, number_pgp, expiry_pgp, hmac, created_at
-		FROM cards
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
-	rows, err := r.DB.Query(query, userID)
+func (r *CardRepository) FindByUserID(ctx context.Context, userID int) ([]*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []*models.Card
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// FindByToken — карта пользователя по токену вместе с зашифрованными данными
+func (r *CardRepository) FindByToken(ctx context.Context, token string, userID int) (*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE token = $1 AND user_id = $2`
+	card, err := scanCard(r.DB.QueryRowContext(ctx, query, token, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrCardNotFound
+	}
+	return card, err
+}
+
+// FindByHMAC — карта по HMAC номера (для авторизации платежа, без привязки к пользователю)
+func (r *CardRepository) FindByHMAC(ctx context.Context, hmac string) (*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE hmac = $1 ORDER BY id DESC LIMIT 1`
+	card, err := scanCard(r.DB.QueryRowContext(ctx, query, hmac))
+	if err == sql.ErrNoRows {
+		return nil, ErrCardNotFound
+	}
+	return card, err
+}
+
+// FindNotUnderKey — карты, зашифрованные не мастер-ключом version, и карты, хранящие данные открыто
+func (r *CardRepository) FindNotUnderKey(ctx context.Context, version int) ([]*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE key_version IS DISTINCT FROM $1 ORDER BY id`
+	rows, err := r.DB.QueryContext(ctx, query, version)
 	if err != nil {
 		return nil, err
 	}
@@ -44,12 +127,101 @@ func (r *CardRepository) FindByUserID(userID int) ([]*models.Card, error) {
 
 	var cards []*models.Card
 	for rows.Next() {
-		var card models.Card
-		err := rows.Scan(&card.ID, &card.UserID, &card.AccountID, &card.NumberPGP, &card.ExpiryPGP, &card.HMAC, &card.CreatedAt)
+		card, err := scanCard(rows)
 		if err != nil {
 			return nil, err
 		}
-		cards = append(cards, &card)
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// Reencrypt сохраняет новые зашифрованные данные карты и очищает открытые. Запись меняется,
+// только если карта всё ещё под ключом fromVersion (0 — открытые данные), иначе её уже
+// перешифровал другой процесс и возвращается false.
+func (r *CardRepository) Reencrypt(ctx context.Context, card *models.Card, fromVersion int) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE cards
+		SET encrypted_data = $1, wrapped_key = $2, key_version = $3, hmac = $4, first6 = $5, last4 = $6, brand = $7,
+			number_pgp = NULL, expiry_pgp = NULL
+		WHERE id = $8 AND COALESCE(key_version, 0) = $9
+	`, card.EncryptedData, card.WrappedKey, card.KeyVersion, card.HMAC, card.First6, card.Last4, card.Brand,
+		card.ID, fromVersion)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RecordReveal записывает попытку просмотра полного номера карты в журнал
+func (r *CardRepository) RecordReveal(ctx context.Context, audit *models.CardRevealAudit) error {
+	return r.DB.QueryRowContext(ctx, `
+		INSERT INTO card_reveal_audit (card_id, user_id, success, reason, ip, user_agent)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+		RETURNING id, created_at
+	`, audit.CardID, audit.UserID, audit.Success, audit.Reason, audit.IP, audit.UserAgent).
+		Scan(&audit.ID, &audit.CreatedAt)
+}
+
+// SetStatus переводит карту из статуса card.Status в status (с причиной блокировки для blocked).
+// Если статус карты уже изменился, возвращает ErrCardStatusChanged.
+func (r *CardRepository) SetStatus(ctx context.Context, card *models.Card, status, blockReason string) error {
+	err := r.DB.QueryRowContext(ctx, `
+		UPDATE cards
+		SET status = $1, block_reason = COALESCE(NULLIF($2, ''), block_reason), status_changed_at = NOW()
+		WHERE id = $3 AND status = $4
+		RETURNING status, COALESCE(block_reason, ''), status_changed_at
+	`, status, blockReason, card.ID, card.Status).Scan(&card.Status, &card.BlockReason, &card.StatusChangedAt)
+	if err == sql.ErrNoRows {
+		return ErrCardStatusChanged
+	}
+	return err
+}
+
+// Reissue в одной транзакции переводит карту old в reissued и сохраняет новую карту card,
+// связанную с ней. Если статус old уже изменился, возвращает ErrCardStatusChanged.
+func (r *CardRepository) Reissue(ctx context.Context, old, card *models.Card) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE cards SET status = $1, status_changed_at = NOW()
+		WHERE id = $2 AND status = $3
+	`, models.CardReissued, old.ID, old.Status)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrCardStatusChanged
+	}
+
+	card.ReissuedFromID = &old.ID
+	card.ReissuedFromToken = old.Token
+	if err := insertCard(ctx, tx, card); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	old.Status = models.CardReissued
+	return nil
+}
+
+// ExpireBefore переводит в expired активные и замороженные карты, срок действия которых
+// закончился до day. Возвращает число таких карт.
+func (r *CardRepository) ExpireBefore(ctx context.Context, day time.Time) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE cards SET status = $1, status_changed_at = NOW()
+		WHERE status IN ($2, $3) AND expires_on < $4
+	`, models.CardExpired, models.CardActive, models.CardFrozen, day)
+	if err != nil {
+		return 0, err
 	}
-	return cards, nil
+	return res.RowsAffected()
 }
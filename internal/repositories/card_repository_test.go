@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func testCard(userID, accountID int) *models.Card {
+	expires := time.Date(2030, time.January, 31, 0, 0, 0, 0, time.UTC)
+	return &models.Card{
+		Token:     "tok_test",
+		UserID:    userID,
+		AccountID: accountID,
+		First6:    "400000",
+		Last4:     "0002",
+		Brand:     "visa",
+		CVVHash:   "hash",
+		ExpiresOn: &expires,
+	}
+}
+
+func TestCreateCardOnOwnAccount(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(sqlLike("FROM accounts a\n\t\tWHERE a.user_id = $2 AND a.id = $3")).
+		WithArgs("tok_test", 7, 42, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(1, models.CardActive, time.Now()))
+
+	card := testCard(7, 42)
+	if err := NewCardRepository(db).Create(context.Background(), card); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if card.ID != 1 || card.Status != models.CardActive {
+		t.Errorf("card = %d/%s, want 1/active", card.ID, card.Status)
+	}
+}
+
+func TestCreateCardOnForeignAccountRejected(t *testing.T) {
+	db, mock := newMockDB(t)
+	// Счёт 42 принадлежит другому пользователю — INSERT ... SELECT не вставляет ни строки
+	mock.ExpectQuery(sqlLike("INSERT INTO cards")).
+		WithArgs("tok_test", 8, 42, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}))
+
+	err := NewCardRepository(db).Create(context.Background(), testCard(8, 42))
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("Create on foreign account: %v, want ErrAccountNotFound", err)
+	}
+}
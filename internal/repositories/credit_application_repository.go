@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gobankapi/internal/models"
+)
+
+var ErrApplicationState = errors.New("credit application is not in the required state")
+
+type CreditApplicationRepository struct {
+	DB *sql.DB
+}
+
+func NewCreditApplicationRepository(db *sql.DB) *CreditApplicationRepository {
+	return &CreditApplicationRepository{DB: db}
+}
+
+const creditApplicationColumns = `id, user_id, account_id, product_id, amount, term_months, repayment_type, status,
+	COALESCE(annual_rate, 0), COALESCE(key_rate, 0), COALESCE(spread, 0), COALESCE(monthly_payment, 0),
+	COALESCE(monthly_income, 0), COALESCE(existing_debt, 0), COALESCE(dti, 0), COALESCE(decision_reason, ''),
+	credit_id, created_at, updated_at`
+
+func scanCreditApplication(row interface{ Scan(...interface{}) error }) (*models.CreditApplication, error) {
+	var a models.CreditApplication
+	err := row.Scan(&a.ID, &a.UserID, &a.AccountID, &a.ProductID, &a.Amount, &a.TermMonths, &a.RepaymentType, &a.Status,
+		&a.AnnualRate, &a.KeyRate, &a.Spread, &a.MonthlyPayment,
+		&a.MonthlyIncome, &a.ExistingDebt, &a.DTI, &a.DecisionReason,
+		&a.CreditID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *CreditApplicationRepository) Create(ctx context.Context, app *models.CreditApplication) error {
+	query := `
+		INSERT INTO credit_applications (user_id, account_id, product_id, amount, term_months, repayment_type, status,
+			annual_rate, key_rate, spread, monthly_payment)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at
+	`
+	app.Status = models.ApplicationSubmitted
+	return r.DB.QueryRowContext(ctx, query, app.UserID, app.AccountID, app.ProductID, app.Amount, app.TermMonths, app.RepaymentType, app.Status,
+		app.AnnualRate, app.KeyRate, app.Spread, app.MonthlyPayment).
+		Scan(&app.ID, &app.CreatedAt, &app.UpdatedAt)
+}
+
+// SaveScoring сохраняет результат скоринга: submitted → scored
+func (r *CreditApplicationRepository) SaveScoring(ctx context.Context, app *models.CreditApplication) error {
+	query := `
+		UPDATE credit_applications
+		SET status = $1, monthly_income = $2, existing_debt = $3, dti = $4, updated_at = NOW()
+		WHERE id = $5 AND status = $6
+		RETURNING updated_at
+	`
+	err := r.DB.QueryRowContext(ctx, query, models.ApplicationScored, app.MonthlyIncome, app.ExistingDebt, app.DTI,
+		app.ID, models.ApplicationSubmitted).Scan(&app.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrApplicationState
+	}
+	if err != nil {
+		return err
+	}
+	app.Status = models.ApplicationScored
+	return nil
+}
+
+// Decide фиксирует решение по заявке: scored → approved / rejected
+func (r *CreditApplicationRepository) Decide(ctx context.Context, app *models.CreditApplication, status, reason string) error {
+	query := `
+		UPDATE credit_applications
+		SET status = $1, decision_reason = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $3 AND status = $4
+		RETURNING updated_at
+	`
+	err := r.DB.QueryRowContext(ctx, query, status, reason, app.ID, models.ApplicationScored).Scan(&app.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrApplicationState
+	}
+	if err != nil {
+		return err
+	}
+	app.Status, app.DecisionReason = status, reason
+	return nil
+}
+
+// Disburse выдаёт кредит по одобренной заявке одной транзакцией: создаёт кредит, зачисляет сумму
+// на счёт (дебет кредитного портфеля, кредит счёта), пишет операцию в историю, создаёт график
+// и переводит заявку в disbursed. Повторная выдача по той же заявке невозможна.
+func (r *CreditApplicationRepository) Disburse(ctx context.Context, app *models.CreditApplication, credit *models.Credit, schedule []*models.PaymentSchedule) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM credit_applications WHERE id = $1 FOR UPDATE`, app.ID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != models.ApplicationApproved {
+		return ErrApplicationState
+	}
+
+	balance, err := lockAccount(ctx, tx, app.AccountID, app.UserID)
+	if err != nil {
+		return err
+	}
+	if balance.Currency != app.Amount.Currency {
+		return ErrCurrencyMismatch
+	}
+
+	if err := insertCredit(ctx, tx, credit); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET balance = balance + $1 WHERE id = $2`, credit.Amount, credit.AccountID); err != nil {
+		return err
+	}
+	entry := &models.LedgerEntry{
+		Type: "credit_disbursement",
+		Postings: []models.LedgerPosting{
+			models.GLPosting(models.GLLoans, models.DirectionDebit, credit.Amount),
+			models.AccountPosting(credit.AccountID, models.DirectionCredit, credit.Amount),
+		},
+	}
+	if err := postLedgerEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+	err = logTransaction(ctx, tx, &models.Transaction{
+		ToAccountID:   &credit.AccountID,
+		Amount:        credit.Amount,
+		Type:          "credit_disbursement",
+		LedgerEntryID: &entry.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range schedule {
+		payment.CreditID = credit.ID
+		if err := insertSchedule(ctx, tx, payment); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE credit_applications
+		SET status = $1, credit_id = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING updated_at
+	`, models.ApplicationDisbursed, credit.ID, app.ID).Scan(&app.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	app.Status, app.CreditID = models.ApplicationDisbursed, &credit.ID
+	return nil
+}
+
+// FindByID — заявка пользователя (nil, если не найдена)
+func (r *CreditApplicationRepository) FindByID(ctx context.Context, id, userID int) (*models.CreditApplication, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+creditApplicationColumns+` FROM credit_applications WHERE id = $1 AND user_id = $2`, id, userID)
+	app, err := scanCreditApplication(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return app, err
+}
+
+func (r *CreditApplicationRepository) FindByUserID(ctx context.Context, userID int) ([]*models.CreditApplication, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+creditApplicationColumns+` FROM credit_applications WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apps []*models.CreditApplication
+	for rows.Next() {
+		app, err := scanCreditApplication(rows)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+	return apps, rows.Err()
+}
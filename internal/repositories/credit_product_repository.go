@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+)
+
+type CreditProductRepository struct {
+	DB *sql.DB
+}
+
+func NewCreditProductRepository(db *sql.DB) *CreditProductRepository {
+	return &CreditProductRepository{DB: db}
+}
+
+const creditProductColumns = `id, code, name, rate_type, day_count, spread, min_amount, max_amount, min_term_months, max_term_months, active, penalty_policy_id`
+
+func scanCreditProduct(row interface{ Scan(...interface{}) error }) (*models.CreditProduct, error) {
+	var p models.CreditProduct
+	err := row.Scan(&p.ID, &p.Code, &p.Name, &p.RateType, &p.DayCount, &p.Spread, &p.MinAmount, &p.MaxAmount,
+		&p.MinTermMonths, &p.MaxTermMonths, &p.Active, &p.PenaltyPolicyID)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *CreditProductRepository) FindActive(ctx context.Context) ([]*models.CreditProduct, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+creditProductColumns+` FROM credit_products WHERE active ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.CreditProduct
+	for rows.Next() {
+		p, err := scanCreditProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// FindByCode — активный продукт по коду (nil, если не найден)
+func (r *CreditProductRepository) FindByCode(ctx context.Context, code string) (*models.CreditProduct, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+creditProductColumns+` FROM credit_products WHERE code = $1 AND active`, code)
+	p, err := scanCreditProduct(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
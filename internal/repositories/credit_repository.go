@@ -1,8 +1,13 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/utils"
+	"time"
 )
 
 type CreditRepository struct {
@@ -13,34 +18,450 @@ func NewCreditRepository(db *sql.DB) *CreditRepository {
 	return &CreditRepository{DB: db}
 }
 
-func (r *CreditRepository) Create(credit *models.Credit) error {
+func (r *CreditRepository) Create(ctx context.Context, credit *models.Credit) error {
+	return insertCredit(ctx, r.DB, credit)
+}
+
+// Общий для *sql.DB и *sql.Tx метод: вставка может идти как отдельно, так и внутри транзакции выдачи
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func insertCredit(ctx context.Context, q rowQuerier, credit *models.Credit) error {
 	query := `
-		INSERT INTO credits (user_id, account_id, amount, term_months, annual_rate, monthly_payment)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO credits (user_id, account_id, amount, term_months, annual_rate, monthly_payment,
+			product_id, rate_type, repayment_type, day_count, spread, key_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at
 	`
-	return r.DB.QueryRow(query,
+	return q.QueryRowContext(ctx, query,
 		credit.UserID,
 		credit.AccountID,
 		credit.Amount,
 		credit.TermMonths,
 		credit.AnnualRate,
 		credit.MonthlyPayment,
+		credit.ProductID,
+		credit.RateType,
+		credit.RepaymentType,
+		credit.DayCount,
+		credit.Spread,
+		credit.KeyRate,
 	).Scan(&credit.ID, &credit.CreatedAt)
 }
 
-func (r *CreditRepository) GetActiveCreditLoad(userID int) (float64, error) {
+// GetActiveCreditLoad — непогашенный основной долг пользователя: неоплаченная часть
+// основного долга в ещё не закрытых платежах графиков
+func (r *CreditRepository) GetActiveCreditLoad(ctx context.Context, userID int) (money.Money, error) {
+	query := `
+		SELECT COALESCE(SUM(ps.principal - ps.principal_paid), 0)
+		FROM payment_schedules ps
+		JOIN credits c ON c.id = ps.credit_id
+		WHERE c.user_id = $1 AND ps.paid = false
+	`
+
+	var total money.Money
+	err := r.DB.QueryRowContext(ctx, query, userID).Scan(&total)
+	return total, err
+}
+
+// GetActiveMonthlyPayments — сумма ближайших неоплаченных платежей по всем активным кредитам
+// пользователя: его текущая ежемесячная нагрузка
+func (r *CreditRepository) GetActiveMonthlyPayments(ctx context.Context, userID int) (money.Money, error) {
+	query := `
+		SELECT COALESCE(SUM(next.amount), 0)
+		FROM (
+			SELECT DISTINCT ON (ps.credit_id) ps.amount
+			FROM payment_schedules ps
+			JOIN credits c ON c.id = ps.credit_id
+			WHERE c.user_id = $1 AND ps.paid = false
+			ORDER BY ps.credit_id, ps.due_date
+		) next
+	`
+
+	var total money.Money
+	err := r.DB.QueryRowContext(ctx, query, userID).Scan(&total)
+	return total, err
+}
+
+// Кредиты с плавающей ставкой, рассчитанные по другой ключевой ставке и ещё не погашенные
+func (r *CreditRepository) FindFloatingForRepricing(ctx context.Context, keyRate float64) ([]*models.Credit, error) {
 	query := `
-		SELECT COALESCE(SUM(c.amount), 0)
+		SELECT c.id, c.user_id, c.account_id, c.amount, c.term_months, c.annual_rate, c.monthly_payment,
+			c.product_id, c.rate_type, c.repayment_type, c.day_count, COALESCE(c.spread, 0), COALESCE(c.key_rate, 0), c.created_at
 		FROM credits c
-		WHERE c.user_id = $1
+		WHERE c.rate_type = 'floating'
+		  AND c.key_rate IS DISTINCT FROM $1
 		  AND EXISTS (
 			  SELECT 1 FROM payment_schedules ps
 			  WHERE ps.credit_id = c.id AND ps.paid = false
 		  )
+		ORDER BY c.id
 	`
+	rows, err := r.DB.QueryContext(ctx, query, keyRate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	var total float64
-	err := r.DB.QueryRow(query, userID).Scan(&total)
-	return total, err
+	var credits []*models.Credit
+	for rows.Next() {
+		var c models.Credit
+		err := rows.Scan(&c.ID, &c.UserID, &c.AccountID, &c.Amount, &c.TermMonths, &c.AnnualRate, &c.MonthlyPayment,
+			&c.ProductID, &c.RateType, &c.RepaymentType, &c.DayCount, &c.Spread, &c.KeyRate, &c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &c)
+	}
+	return credits, rows.Err()
+}
+
+// Reprice пересчитывает будущие неоплаченные платежи кредита по новой ставке: остаток основного
+// долга распределяется на то же число платежей по виду погашения кредита. Просроченные платежи не меняются.
+// Обновляет credit (ставка, платёж) и возвращает false, если пересчитывать нечего.
+func (r *CreditRepository) Reprice(ctx context.Context, credit *models.Credit, keyRate, annualRate float64) (bool, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Блокировка кредита: параллельный пересчёт и списание платежа ждут друг друга
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM credits WHERE id = $1 FOR UPDATE`, credit.ID); err != nil {
+		return false, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, due_date, principal
+		FROM payment_schedules
+		WHERE credit_id = $1 AND paid = false AND due_date >= CURRENT_DATE
+		ORDER BY due_date
+		FOR UPDATE
+	`, credit.ID)
+	if err != nil {
+		return false, err
+	}
+	var ids []int
+	var dueDates []time.Time
+	outstanding := money.Zero(credit.Amount.Currency)
+	for rows.Next() {
+		var id int
+		var due time.Time
+		principal := money.Zero(credit.Amount.Currency)
+		if err := rows.Scan(&id, &due, &principal); err != nil {
+			rows.Close()
+			return false, err
+		}
+		ids = append(ids, id)
+		dueDates = append(dueDates, due)
+		outstanding = outstanding.Add(principal)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if len(ids) == 0 || !outstanding.IsPositive() {
+		return false, nil
+	}
+
+	start, err := periodStart(ctx, tx, credit.ID, dueDates[0])
+	if err != nil {
+		return false, err
+	}
+	installments := utils.DatedSchedule(credit.RepaymentType, outstanding, annualRate, credit.DayCount, start, dueDates)
+	if err := rewriteSchedule(ctx, tx, ids, installments); err != nil {
+		return false, err
+	}
+
+	credit.AnnualRate = annualRate
+	credit.KeyRate = keyRate
+	credit.MonthlyPayment = installments[0].Payment
+	_, err = tx.ExecContext(ctx, `UPDATE credits SET annual_rate = $1, key_rate = $2, monthly_payment = $3 WHERE id = $4`,
+		credit.AnnualRate, credit.KeyRate, credit.MonthlyPayment, credit.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+var (
+	ErrCreditNotFound         = errors.New("credit not found")
+	ErrCreditClosed           = errors.New("credit has no outstanding debt")
+	ErrCreditOverdue          = errors.New("credit has overdue payments")
+	ErrRepaymentExceedsDebt   = errors.New("repayment exceeds outstanding debt")
+	ErrRepaymentBelowInterest = errors.New("repayment does not cover accrued interest")
+)
+
+// Outstanding — сумма полного досрочного погашения кредита пользователя на сегодня: остаток
+// основного долга по будущим неоплаченным платежам и проценты, начисленные на него с начала
+// текущего процентного периода
+func (r *CreditRepository) Outstanding(ctx context.Context, creditID, userID int) (money.Money, error) {
+	var (
+		currency   string
+		annualRate float64
+		dayCount   string
+		nextDue    sql.NullTime
+		total      string
+	)
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT a.currency, c.annual_rate, c.day_count,
+			(SELECT MIN(ps.due_date) FROM payment_schedules ps WHERE ps.credit_id = c.id AND ps.paid = false),
+			COALESCE((
+				SELECT SUM(ps.principal) FROM payment_schedules ps
+				WHERE ps.credit_id = c.id AND ps.paid = false
+			), 0)
+		FROM credits c
+		JOIN accounts a ON a.id = c.account_id
+		WHERE c.id = $1 AND c.user_id = $2
+	`, creditID, userID).Scan(&currency, &annualRate, &dayCount, &nextDue, &total)
+	if err == sql.ErrNoRows {
+		return money.Money{}, ErrCreditNotFound
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+	principal, err := money.Parse(total, currency)
+	if err != nil || !nextDue.Valid || !principal.IsPositive() {
+		return principal, err
+	}
+	start, err := periodStart(ctx, r.DB, creditID, nextDue.Time)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return principal.Add(utils.AccruedInterest(principal, dayCount, annualRate, start, utils.Date(time.Now()))), nil
+}
+
+// prepayment — разнесение досрочного погашения: сначала проценты за текущий период, затем основной долг
+type prepayment struct {
+	Interest     money.Money
+	Principal    money.Money
+	Remaining    money.Money
+	Installments []utils.Installment // новый график будущих платежей; пуст, если кредит закрыт
+}
+
+// planPrepayment разносит amount на проценты, начисленные на outstanding с start по today, и основной
+// долг и пересчитывает будущие платежи с датами dueDates от today. payment и share — прежний первый
+// платёж и его доля основного долга: по ним RepayShortenTerm подбирает новый срок.
+func planPrepayment(credit *models.Credit, outstanding, amount, payment, share money.Money, dueDates []time.Time, mode string, start, today time.Time) (*prepayment, error) {
+	p := &prepayment{Interest: utils.AccruedInterest(outstanding, credit.DayCount, credit.AnnualRate, start, today)}
+	if amount.GreaterThan(outstanding.Add(p.Interest)) {
+		return nil, ErrRepaymentExceedsDebt
+	}
+	if !amount.GreaterThan(p.Interest) {
+		return nil, ErrRepaymentBelowInterest
+	}
+	p.Principal = amount.Sub(p.Interest)
+	p.Remaining = outstanding.Sub(p.Principal)
+	if !p.Remaining.IsPositive() {
+		return p, nil
+	}
+
+	months := len(dueDates)
+	if mode == models.RepayShortenTerm {
+		// Наименьшее число платежей, при котором первый платёж не больше прежнего
+		// (для дифференцированного — доля основного долга не больше прежней)
+		for months > 1 {
+			next := utils.RepaymentSchedule(credit.RepaymentType, p.Remaining, credit.AnnualRate, months-1)[0]
+			if credit.RepaymentType == models.RepaymentDifferentiated && next.Principal.GreaterThan(share) ||
+				credit.RepaymentType != models.RepaymentDifferentiated && next.Payment.GreaterThan(payment) {
+				break
+			}
+			months--
+		}
+	}
+	// Проценты за период до погашения уплачены им, дальше они начисляются на новый остаток
+	p.Installments = utils.DatedSchedule(credit.RepaymentType, p.Remaining, credit.AnnualRate, credit.DayCount, today, dueDates[:months])
+	return p, nil
+}
+
+// Repay — досрочное погашение со счёта кредита. Сумма идёт сначала на проценты, начисленные с начала
+// текущего процентного периода по сегодня, остаток — на основной долг. amount, равный сумме из
+// Outstanding, закрывает кредит; меньшая сумма уменьшает остаток, и будущие платежи пересчитываются
+// по mode: RepayShortenTerm сохраняет ежемесячный платёж и сокращает срок, RepayReducePayment сохраняет
+// число платежей и уменьшает платёж. Погашение записывается в график оплаченной строкой на сегодня,
+// поэтому сумма основного долга по графику по-прежнему равна сумме кредита.
+func (r *CreditRepository) Repay(ctx context.Context, creditID, userID int, amount money.Money, mode string) (*models.Credit, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Блокировка кредита: пересчёт ставки и повторное погашение ждут завершения
+	var credit models.Credit
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, user_id, account_id, term_months, annual_rate, rate_type, repayment_type, day_count
+		FROM credits WHERE id = $1 AND user_id = $2 FOR UPDATE
+	`, creditID, userID).Scan(&credit.ID, &credit.UserID, &credit.AccountID, &credit.TermMonths, &credit.AnnualRate,
+		&credit.RateType, &credit.RepaymentType, &credit.DayCount)
+	if err == sql.ErrNoRows {
+		return nil, ErrCreditNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := lockAccount(ctx, tx, credit.AccountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Currency != amount.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+
+	// Сначала должны быть погашены просроченные платежи — их списывает шедулер вместе со штрафами
+	var overdue bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM payment_schedules WHERE credit_id = $1 AND paid = false AND due_date < CURRENT_DATE)
+	`, credit.ID).Scan(&overdue)
+	if err != nil {
+		return nil, err
+	}
+	if overdue {
+		return nil, ErrCreditOverdue
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, due_date, amount, principal
+		FROM payment_schedules
+		WHERE credit_id = $1 AND paid = false
+		ORDER BY due_date
+		FOR UPDATE
+	`, credit.ID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	var dueDates []time.Time
+	var payment, share money.Money
+	outstanding := money.Zero(amount.Currency)
+	for rows.Next() {
+		var id int
+		var due time.Time
+		amt, principal := money.Zero(amount.Currency), money.Zero(amount.Currency)
+		if err := rows.Scan(&id, &due, &amt, &principal); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if len(ids) == 0 {
+			payment, share = amt, principal
+		}
+		ids = append(ids, id)
+		dueDates = append(dueDates, due)
+		outstanding = outstanding.Add(principal)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 || !outstanding.IsPositive() {
+		return nil, ErrCreditClosed
+	}
+
+	start, err := periodStart(ctx, tx, credit.ID, dueDates[0])
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	plan, err := planPrepayment(&credit, outstanding, amount, payment, share, dueDates, mode, start, utils.Date(now))
+	if err != nil {
+		return nil, err
+	}
+	if balance.LessThan(amount) {
+		return nil, ErrInsufficientFunds
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET balance = balance - $1 WHERE id = $2`, amount, credit.AccountID); err != nil {
+		return nil, err
+	}
+	entry := &models.LedgerEntry{
+		Type: "credit_prepayment",
+		Postings: []models.LedgerPosting{
+			models.AccountPosting(credit.AccountID, models.DirectionDebit, amount),
+			models.GLPosting(models.GLLoans, models.DirectionCredit, plan.Principal),
+		},
+	}
+	if plan.Interest.IsPositive() {
+		entry.Postings = append(entry.Postings, models.GLPosting(models.GLInterestIncome, models.DirectionCredit, plan.Interest))
+	}
+	if err := postLedgerEntry(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+	err = logTransaction(ctx, tx, &models.Transaction{
+		FromAccountID: &credit.AccountID,
+		Amount:        amount.Neg(),
+		Type:          "credit_prepayment",
+		LedgerEntryID: &entry.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertSchedule(ctx, tx, &models.PaymentSchedule{
+		CreditID:  credit.ID,
+		DueDate:   now,
+		Amount:    amount,
+		Principal: plan.Principal,
+		Interest:  plan.Interest,
+		Remaining: plan.Remaining,
+		Paid:      true,
+		PaidAt:    &now,
+		Penalty:   money.Zero(amount.Currency),
+
+		AmountPaid:    amount,
+		InterestPaid:  plan.Interest,
+		PrincipalPaid: plan.Principal,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rewriteSchedule(ctx, tx, ids, plan.Installments); err != nil {
+		return nil, err
+	}
+
+	credit.MonthlyPayment = money.Zero(amount.Currency)
+	if len(plan.Installments) > 0 {
+		credit.MonthlyPayment = plan.Installments[0].Payment
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE credits SET monthly_payment = $1 WHERE id = $2`, credit.MonthlyPayment, credit.ID); err != nil {
+		return nil, err
+	}
+
+	return &credit, tx.Commit()
+}
+
+// rewriteSchedule записывает новый расчёт в будущие платежи по порядку; платежи сверх
+// нового графика удаляются — долг закрывается раньше
+func rewriteSchedule(ctx context.Context, tx *sql.Tx, ids []int, installments []utils.Installment) error {
+	for i, id := range ids {
+		if i >= len(installments) {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM payment_schedules WHERE id = $1`, id); err != nil {
+				return err
+			}
+			continue
+		}
+		inst := installments[i]
+		_, err := tx.ExecContext(ctx, `UPDATE payment_schedules SET amount = $1, principal = $2, interest = $3, remaining = $4 WHERE id = $5`,
+			inst.Payment, inst.Principal, inst.Interest, inst.Remaining, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// periodStart — начало процентного периода платежа с датой due: дата предыдущей строки графика
+// (платежа или досрочного погашения), для первого платежа — дата выдачи кредита
+func periodStart(ctx context.Context, q rowQuerier, creditID int, due time.Time) (time.Time, error) {
+	var start time.Time
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(
+			(SELECT MAX(due_date) FROM payment_schedules WHERE credit_id = $1 AND due_date < $2),
+			(SELECT created_at::date FROM credits WHERE id = $1)
+		)
+	`, creditID, due).Scan(&start)
+	return start, err
 }
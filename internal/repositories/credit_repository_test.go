@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// Кредит на 100 000 ₽ под 12% ACT/365, погашение 16 марта — в середине периода 1 марта → 1 апреля:
+// за 15 дней начислено 100 000 × 0,12 × 15 / 365 = 493,15 ₽
+var (
+	prepayStart = time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	prepayToday = time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC)
+)
+
+func prepayCredit() (*models.Credit, money.Money, []time.Time) {
+	credit := &models.Credit{AnnualRate: 12, RepaymentType: models.RepaymentAnnuity, DayCount: utils.DayCountACT365}
+	var dueDates []time.Time
+	for i := 1; i <= 6; i++ {
+		dueDates = append(dueDates, utils.AddMonths(prepayStart, i))
+	}
+	return credit, money.MustParse("100000", "RUB"), dueDates
+}
+
+func TestPlanFullPrepaymentMidPeriod(t *testing.T) {
+	credit, outstanding, dueDates := prepayCredit()
+	first := utils.DatedSchedule(credit.RepaymentType, outstanding, credit.AnnualRate, credit.DayCount, prepayStart, dueDates)[0]
+
+	plan, err := planPrepayment(credit, outstanding, money.MustParse("100493.15", "RUB"), first.Payment, first.Principal,
+		dueDates, models.RepayShortenTerm, prepayStart, prepayToday)
+	if err != nil {
+		t.Fatalf("planPrepayment: %v", err)
+	}
+	if plan.Interest != money.MustParse("493.15", "RUB") || plan.Principal != outstanding {
+		t.Errorf("interest/principal = %s/%s, want 493.15/100000.00", plan.Interest, plan.Principal)
+	}
+	if !plan.Remaining.IsZero() || len(plan.Installments) != 0 {
+		t.Errorf("remaining %s, %d installments; want closed credit", plan.Remaining, len(plan.Installments))
+	}
+}
+
+func TestPlanPartialPrepaymentMidPeriod(t *testing.T) {
+	credit, outstanding, dueDates := prepayCredit()
+	first := utils.DatedSchedule(credit.RepaymentType, outstanding, credit.AnnualRate, credit.DayCount, prepayStart, dueDates)[0]
+	amount := money.MustParse("20000", "RUB")
+
+	for _, mode := range []string{models.RepayReducePayment, models.RepayShortenTerm} {
+		t.Run(mode, func(t *testing.T) {
+			plan, err := planPrepayment(credit, outstanding, amount, first.Payment, first.Principal, dueDates, mode, prepayStart, prepayToday)
+			if err != nil {
+				t.Fatalf("planPrepayment: %v", err)
+			}
+			// Проценты за 1–16 марта уплачиваются первыми, в основной долг идёт остаток суммы
+			if plan.Interest != money.MustParse("493.15", "RUB") || plan.Principal != money.MustParse("19506.85", "RUB") {
+				t.Errorf("interest/principal = %s/%s, want 493.15/19506.85", plan.Interest, plan.Principal)
+			}
+			if plan.Remaining != money.MustParse("80493.15", "RUB") {
+				t.Errorf("remaining = %s, want 80493.15", plan.Remaining)
+			}
+
+			principal := money.Zero("RUB")
+			for _, inst := range plan.Installments {
+				principal = principal.Add(inst.Principal)
+			}
+			if principal != plan.Remaining {
+				t.Errorf("schedule principal = %s, want %s", principal, plan.Remaining)
+			}
+			// Первый новый платёж начисляет проценты только с даты погашения
+			want := utils.AccruedInterest(plan.Remaining, credit.DayCount, credit.AnnualRate, prepayToday, dueDates[0])
+			if plan.Installments[0].Interest != want {
+				t.Errorf("first interest = %s, want %s", plan.Installments[0].Interest, want)
+			}
+
+			switch mode {
+			case models.RepayReducePayment:
+				if len(plan.Installments) != len(dueDates) || !plan.Installments[0].Payment.LessThan(first.Payment) {
+					t.Errorf("%d installments, payment %s; want %d with payment below %s",
+						len(plan.Installments), plan.Installments[0].Payment, len(dueDates), first.Payment)
+				}
+			case models.RepayShortenTerm:
+				if len(plan.Installments) >= len(dueDates) || plan.Installments[0].Payment.GreaterThan(first.Payment) {
+					t.Errorf("%d installments, payment %s; want fewer than %d with payment up to %s",
+						len(plan.Installments), plan.Installments[0].Payment, len(dueDates), first.Payment)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanPrepaymentRejected(t *testing.T) {
+	credit, outstanding, dueDates := prepayCredit()
+	tests := []struct {
+		amount string
+		err    error
+	}{
+		{"100493.16", ErrRepaymentExceedsDebt},
+		{"493.15", ErrRepaymentBelowInterest},
+		{"100", ErrRepaymentBelowInterest},
+	}
+	for _, tt := range tests {
+		_, err := planPrepayment(credit, outstanding, money.MustParse(tt.amount, "RUB"), outstanding, outstanding,
+			dueDates, models.RepayShortenTerm, prepayStart, prepayToday)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("amount %s: err = %v, want %v", tt.amount, err, tt.err)
+		}
+	}
+}
+
+func TestOutstandingIncludesAccruedInterest(t *testing.T) {
+	db, mock := newMockDB(t)
+	today := utils.Date(time.Now())
+	mock.ExpectQuery(sqlLike("SELECT a.currency, c.annual_rate, c.day_count")).WithArgs(5, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "annual_rate", "day_count", "next_due", "principal"}).
+			AddRow("RUB", 12.0, utils.DayCountACT365, today.AddDate(0, 0, 16), "100000.00"))
+	mock.ExpectQuery(sqlLike("SELECT MAX(due_date) FROM payment_schedules")).
+		WillReturnRows(sqlmock.NewRows([]string{"start"}).AddRow(today.AddDate(0, 0, -15)))
+
+	total, err := NewCreditRepository(db).Outstanding(context.Background(), 5, 7)
+	if err != nil {
+		t.Fatalf("Outstanding: %v", err)
+	}
+	if want := money.MustParse("100493.15", "RUB"); total != want {
+		t.Errorf("payoff = %s, want %s", total, want)
+	}
+}
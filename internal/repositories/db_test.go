@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newMockDB — БД-заглушка: ожидаемые запросы задаются фрагментами SQL (см. sqlLike),
+// по окончании теста проверяется, что все ожидания выполнены
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return db, mock
+}
+
+// sqlLike — регулярное выражение для запроса, содержащего fragment буквально
+func sqlLike(fragment string) string {
+	return regexp.QuoteMeta(fragment)
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+	"time"
+)
+
+type ExchangeRateRepository struct {
+	DB *sql.DB
+}
+
+func NewExchangeRateRepository(db *sql.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{DB: db}
+}
+
+// Save записывает курс; повторная загрузка того же источника за ту же дату обновляет значение
+func (r *ExchangeRateRepository) Save(ctx context.Context, rate *models.ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (currency, rate, nominal, rate_date, source)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (currency, rate_date, source)
+		DO UPDATE SET rate = EXCLUDED.rate, nominal = EXCLUDED.nominal, created_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at
+	`
+	return r.DB.QueryRowContext(ctx, query, rate.Currency, rate.Rate, rate.Nominal, rate.RateDate, rate.Source).
+		Scan(&rate.ID, &rate.CreatedAt)
+}
+
+// Latest — последний действующий на сегодня курс валюты (nil, если курса нет)
+func (r *ExchangeRateRepository) Latest(ctx context.Context, currency string) (*models.ExchangeRate, error) {
+	query := `
+		SELECT id, currency, rate, nominal, rate_date, source, created_at
+		FROM exchange_rates
+		WHERE currency = $1 AND rate_date <= CURRENT_DATE
+		ORDER BY rate_date DESC, created_at DESC
+		LIMIT 1
+	`
+	var rate models.ExchangeRate
+	err := r.DB.QueryRowContext(ctx, query, currency).Scan(
+		&rate.ID, &rate.Currency, &rate.Rate, &rate.Nominal, &rate.RateDate, &rate.Source, &rate.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// OnDate — курсы всех валют, действовавшие на дату (для каждой валюты — последний не позже даты)
+func (r *ExchangeRateRepository) OnDate(ctx context.Context, date time.Time) ([]*models.ExchangeRate, error) {
+	query := `
+		SELECT DISTINCT ON (currency) id, currency, rate, nominal, rate_date, source, created_at
+		FROM exchange_rates
+		WHERE rate_date <= $1
+		ORDER BY currency, rate_date DESC, created_at DESC
+	`
+	return r.list(ctx, query, date)
+}
+
+// History — курсы валюты за период, по возрастанию даты
+func (r *ExchangeRateRepository) History(ctx context.Context, currency string, from, to time.Time) ([]*models.ExchangeRate, error) {
+	query := `
+		SELECT DISTINCT ON (rate_date) id, currency, rate, nominal, rate_date, source, created_at
+		FROM exchange_rates
+		WHERE currency = $1 AND rate_date BETWEEN $2 AND $3
+		ORDER BY rate_date ASC, created_at DESC
+	`
+	return r.list(ctx, query, currency, from, to)
+}
+
+func (r *ExchangeRateRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.ExchangeRate, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*models.ExchangeRate
+	for rows.Next() {
+		var rate models.ExchangeRate
+		err := rows.Scan(&rate.ID, &rate.Currency, &rate.Rate, &rate.Nominal, &rate.RateDate, &rate.Source, &rate.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, &rate)
+	}
+	return rates, rows.Err()
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+	"gobankapi/internal/utils"
+	"log"
+	"time"
+)
+
+type HolidayRepository struct {
+	DB *sql.DB
+}
+
+func NewHolidayRepository(db *sql.DB) *HolidayRepository {
+	return &HolidayRepository{DB: db}
+}
+
+// Between — праздничные дни в интервале [from, to]
+func (r *HolidayRepository) Between(ctx context.Context, from, to time.Time) ([]time.Time, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT date FROM holidays WHERE date BETWEEN $1 AND $2 ORDER BY date`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dates []time.Time
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		dates = append(dates, d)
+	}
+	return dates, rows.Err()
+}
+
+// LastYear — последний год, на который заведены праздники (0, если таблица пуста)
+func (r *HolidayRepository) LastYear(ctx context.Context) (int, error) {
+	var year int
+	err := r.DB.QueryRowContext(ctx, `SELECT COALESCE(EXTRACT(YEAR FROM MAX(date))::int, 0) FROM holidays`).Scan(&year)
+	return year, err
+}
+
+// Calendar — производственный календарь на интервал [from, to]. Если праздники на конец интервала
+// ещё не заведены, календарь строится по одним выходным и в лог пишется предупреждение.
+func (r *HolidayRepository) Calendar(ctx context.Context, from, to time.Time) (*utils.Calendar, error) {
+	dates, err := r.Between(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	last, err := r.LastYear(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if to.Year() > last {
+		log.Printf("Праздничные дни заведены только по %d год: даты до %s переносятся только с выходных — обновите календарь (PUT /api/admin/holidays/{date})",
+			last, to.Format("2006-01-02"))
+	}
+	return utils.NewCalendar(dates), nil
+}
+
+// List — праздники за год по возрастанию даты
+func (r *HolidayRepository) List(ctx context.Context, year int) ([]*models.Holiday, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT date, name FROM holidays WHERE EXTRACT(YEAR FROM date) = $1 ORDER BY date`, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.Holiday
+	for rows.Next() {
+		var h models.Holiday
+		if err := rows.Scan(&h.Date, &h.Name); err != nil {
+			return nil, err
+		}
+		list = append(list, &h)
+	}
+	return list, rows.Err()
+}
+
+// Put добавляет праздник или переименовывает уже заведённый
+func (r *HolidayRepository) Put(ctx context.Context, h *models.Holiday) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO holidays (date, name) VALUES ($1, $2)
+		ON CONFLICT (date) DO UPDATE SET name = EXCLUDED.name
+	`, h.Date, h.Name)
+	return err
+}
+
+// Delete убирает праздник (например, перенесённый выходной); false — такого дня не было
+func (r *HolidayRepository) Delete(ctx context.Context, date time.Time) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM holidays WHERE date = $1`, date)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
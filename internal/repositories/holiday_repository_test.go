@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// captureLog перенаправляет стандартный лог в буфер до конца теста
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	saved := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(saved) })
+	return &buf
+}
+
+func expectHolidays(mock sqlmock.Sqlmock, lastYear int, dates ...time.Time) {
+	rows := sqlmock.NewRows([]string{"date"})
+	for _, d := range dates {
+		rows.AddRow(d)
+	}
+	mock.ExpectQuery(sqlLike("SELECT date FROM holidays WHERE date BETWEEN")).WillReturnRows(rows)
+	mock.ExpectQuery(sqlLike("MAX(date)")).WillReturnRows(sqlmock.NewRows([]string{"year"}).AddRow(lastYear))
+}
+
+func TestCalendarWithinSeededYears(t *testing.T) {
+	db, mock := newMockDB(t)
+	buf := captureLog(t)
+	may9 := time.Date(2026, time.May, 11, 0, 0, 0, 0, time.UTC) // понедельник, перенесённый выходной
+	expectHolidays(mock, 2027, may9)
+
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	calendar, err := NewHolidayRepository(db).Calendar(context.Background(), from, from.AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("Calendar: %v", err)
+	}
+	if calendar.IsBusinessDay(may9) {
+		t.Error("holiday from the table is a business day")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected warning: %s", buf)
+	}
+}
+
+func TestCalendarPastSeededYearsWarns(t *testing.T) {
+	db, mock := newMockDB(t)
+	buf := captureLog(t)
+	expectHolidays(mock, 2027)
+
+	from := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := NewHolidayRepository(db).Calendar(context.Background(), from, from.AddDate(3, 0, 0)); err != nil {
+		t.Fatalf("Calendar: %v", err)
+	}
+	if !strings.Contains(buf.String(), "по 2027 год") {
+		t.Errorf("want warning about holidays seeded up to 2027, got %q", buf)
+	}
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"time"
+)
+
+type InterestAccrualRepository struct {
+	DB *sql.DB
+}
+
+func NewInterestAccrualRepository(db *sql.DB) *InterestAccrualRepository {
+	return &InterestAccrualRepository{DB: db}
+}
+
+// AccrualTarget — кредит с непогашенным долгом и первый день, за который ещё нет начисления
+type AccrualTarget struct {
+	Credit *models.Credit
+	From   time.Time
+}
+
+// Targets — кредиты с неоплаченными платежами. Начисление продолжается с дня после последнего
+// начисленного, для нового кредита — с дня выдачи.
+func (r *InterestAccrualRepository) Targets(ctx context.Context) ([]AccrualTarget, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT c.id, c.amount, c.annual_rate, c.day_count,
+			COALESCE((SELECT MAX(ia.accrual_date) + 1 FROM interest_accruals ia WHERE ia.credit_id = c.id), c.created_at::date)
+		FROM credits c
+		WHERE EXISTS (
+			SELECT 1 FROM payment_schedules ps
+			WHERE ps.credit_id = c.id AND ps.paid = false
+		)
+		ORDER BY c.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []AccrualTarget
+	for rows.Next() {
+		var c models.Credit
+		var from time.Time
+		if err := rows.Scan(&c.ID, &c.Amount, &c.AnnualRate, &c.DayCount, &from); err != nil {
+			return nil, err
+		}
+		targets = append(targets, AccrualTarget{Credit: &c, From: from})
+	}
+	return targets, rows.Err()
+}
+
+// OutstandingOn — остаток основного долга на конец дня date: сумма кредита минус погашенный
+// основной долг. Оплаченные полностью платежи учитываются по дате оплаты, частично погашенные
+// просроченные — сразу (дата частичного списания не хранится).
+func (r *InterestAccrualRepository) OutstandingOn(ctx context.Context, credit *models.Credit, date time.Time) (money.Money, error) {
+	paid := money.Zero(credit.Amount.Currency)
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(principal_paid), 0)
+		FROM payment_schedules
+		WHERE credit_id = $1 AND (paid_at IS NULL OR paid_at < $2::date + 1)
+	`, credit.ID, date).Scan(&paid)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return credit.Amount.Sub(paid), nil
+}
+
+// Save записывает начисление за день; повторное начисление за тот же день игнорируется.
+// Возвращает false, если запись уже была.
+func (r *InterestAccrualRepository) Save(ctx context.Context, a *models.InterestAccrual) (bool, error) {
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO interest_accruals (credit_id, accrual_date, principal, annual_rate, day_count, amount)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (credit_id, accrual_date) DO NOTHING
+		RETURNING id, created_at
+	`, a.CreditID, a.AccrualDate, a.Principal, a.AnnualRate, a.DayCount, a.Amount).Scan(&a.ID, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FindByCreditID — начисления по кредиту пользователя, новые сверху
+func (r *InterestAccrualRepository) FindByCreditID(ctx context.Context, creditID, userID int) ([]*models.InterestAccrual, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT ia.id, ia.credit_id, ia.accrual_date, ia.principal, ia.annual_rate, ia.day_count, ia.amount, ia.created_at
+		FROM interest_accruals ia
+		JOIN credits c ON c.id = ia.credit_id
+		WHERE ia.credit_id = $1 AND c.user_id = $2
+		ORDER BY ia.accrual_date DESC
+	`, creditID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.InterestAccrual
+	for rows.Next() {
+		var a models.InterestAccrual
+		err := rows.Scan(&a.ID, &a.CreditID, &a.AccrualDate, &a.Principal, &a.AnnualRate, &a.DayCount, &a.Amount, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, &a)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+)
+
+type JWTKeyRepository struct {
+	DB *sql.DB
+}
+
+func NewJWTKeyRepository(db *sql.DB) *JWTKeyRepository {
+	return &JWTKeyRepository{DB: db}
+}
+
+func (r *JWTKeyRepository) Create(ctx context.Context, key *models.JWTKey) error {
+	query := `
+		INSERT INTO jwt_keys (kid, alg, private_key_enc, public_key, not_before, not_after, retire_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+	return r.DB.QueryRowContext(ctx, query, key.KID, key.Alg, key.PrivateKeyEnc, key.PublicKey, key.NotBefore, key.NotAfter,
+		key.RetireAt).
+		Scan(&key.CreatedAt)
+}
+
+// Ключи, которые ещё принимаются при проверке, включая ещё не подписывающие (новые — первыми)
+func (r *JWTKeyRepository) FindActive(ctx context.Context, alg string) ([]*models.JWTKey, error) {
+	query := `
+		SELECT kid, alg, private_key_enc, public_key, created_at, not_before, not_after, retire_at
+		FROM jwt_keys
+		WHERE alg = $1 AND retire_at > NOW()
+		ORDER BY not_before DESC
+	`
+	rows, err := r.DB.QueryContext(ctx, query, alg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.JWTKey
+	for rows.Next() {
+		var k models.JWTKey
+		err := rows.Scan(&k.KID, &k.Alg, &k.PrivateKeyEnc, &k.PublicKey, &k.CreatedAt, &k.NotBefore, &k.NotAfter, &k.RetireAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+	return keys, nil
+}
+
+// Удаление ключей, вышедших из окна перекрытия
+func (r *JWTKeyRepository) DeleteRetired(ctx context.Context) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM jwt_keys WHERE retire_at <= NOW()`)
+	return err
+}
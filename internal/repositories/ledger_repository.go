@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+)
+
+var ErrUnbalancedEntry = errors.New("ledger entry is not balanced")
+
+type LedgerRepository struct {
+	DB *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{DB: db}
+}
+
+// postLedgerEntry записывает проводку в рамках транзакции БД, в которой меняется баланс, —
+// единственный путь записи в журнал для всех операций со счетами
+func postLedgerEntry(ctx context.Context, tx *sql.Tx, entry *models.LedgerEntry) error {
+	if err := validateEntry(entry); err != nil {
+		return err
+	}
+
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO ledger_entries (type, description) VALUES ($1, $2) RETURNING id, created_at`,
+		entry.Type, entry.Description,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range entry.Postings {
+		p := &entry.Postings[i]
+		p.EntryID = entry.ID
+		var glAccount *string
+		if p.GLAccount != "" {
+			glAccount = &p.GLAccount
+		}
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO ledger_postings (entry_id, account_id, gl_account, direction, amount, currency)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, p.EntryID, p.AccountID, glAccount, p.Direction, p.Amount, p.Amount.Currency).Scan(&p.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// В каждой валюте проводки сумма дебетов должна точно совпадать с суммой кредитов
+func validateEntry(entry *models.LedgerEntry) error {
+	if len(entry.Postings) < 2 {
+		return fmt.Errorf("%w: at least two postings required", ErrUnbalancedEntry)
+	}
+	// Сальдо по валютам: дебеты со знаком плюс, кредиты — минус
+	saldo := make(map[string]money.Money)
+	for _, p := range entry.Postings {
+		if !p.Amount.IsPositive() {
+			return fmt.Errorf("%w: posting amount must be positive", ErrUnbalancedEntry)
+		}
+		current, ok := saldo[p.Amount.Currency]
+		if !ok {
+			current = money.Zero(p.Amount.Currency)
+		}
+		switch p.Direction {
+		case models.DirectionDebit:
+			saldo[p.Amount.Currency] = current.Add(p.Amount)
+		case models.DirectionCredit:
+			saldo[p.Amount.Currency] = current.Sub(p.Amount)
+		default:
+			return fmt.Errorf("%w: unknown direction %q", ErrUnbalancedEntry, p.Direction)
+		}
+	}
+	for currency, diff := range saldo {
+		if !diff.IsZero() {
+			return fmt.Errorf("%w: debit and credit differ by %s %s", ErrUnbalancedEntry, diff, currency)
+		}
+	}
+	return nil
+}
+
+// Баланс счёта клиента по журналу проводок (в валюте счёта): кредиты минус дебеты
+func (r *LedgerRepository) AccountBalance(ctx context.Context, accountID int) (money.Money, error) {
+	query := `
+		SELECT a.currency, COALESCE(SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END), 0)
+		FROM accounts a
+		LEFT JOIN ledger_postings p ON p.account_id = a.id
+		WHERE a.id = $1
+		GROUP BY a.currency
+	`
+	var currency, balance string
+	if err := r.DB.QueryRowContext(ctx, query, accountID).Scan(&currency, &balance); err != nil {
+		return money.Money{}, err
+	}
+	return money.Parse(balance, currency)
+}
+
+// Reconcile — счета, у которых сохранённый баланс расходится с суммой проводок
+func (r *LedgerRepository) Reconcile(ctx context.Context) ([]*models.BalanceMismatch, error) {
+	query := `
+		SELECT a.id, a.currency, a.balance, COALESCE(l.balance, 0)
+		FROM accounts a
+		LEFT JOIN (
+			SELECT account_id, SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END) AS balance
+			FROM ledger_postings
+			WHERE account_id IS NOT NULL
+			GROUP BY account_id
+		) l ON l.account_id = a.id
+		WHERE a.balance <> COALESCE(l.balance, 0)
+		ORDER BY a.id
+	`
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mismatches []*models.BalanceMismatch
+	for rows.Next() {
+		var m models.BalanceMismatch
+		var stored, ledger string
+		if err := rows.Scan(&m.AccountID, &m.Currency, &stored, &ledger); err != nil {
+			return nil, err
+		}
+		if m.StoredBalance, err = money.Parse(stored, m.Currency); err != nil {
+			return nil, err
+		}
+		if m.LedgerBalance, err = money.Parse(ledger, m.Currency); err != nil {
+			return nil, err
+		}
+		m.Difference = m.StoredBalance.Sub(m.LedgerBalance)
+		mismatches = append(mismatches, &m)
+	}
+	return mismatches, rows.Err()
+}
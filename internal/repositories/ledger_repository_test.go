@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestValidateEntry(t *testing.T) {
+	rub := func(s string) money.Money { return money.MustParse(s, "RUB") }
+	usd := func(s string) money.Money { return money.MustParse(s, "USD") }
+	tests := []struct {
+		name     string
+		postings []models.LedgerPosting
+		ok       bool
+	}{
+		{"balanced", []models.LedgerPosting{
+			models.GLPosting(models.GLCash, models.DirectionDebit, rub("100")),
+			models.AccountPosting(1, models.DirectionCredit, rub("100")),
+		}, true},
+		{"split credit", []models.LedgerPosting{
+			models.AccountPosting(1, models.DirectionDebit, rub("100")),
+			models.GLPosting(models.GLLoans, models.DirectionCredit, rub("90")),
+			models.GLPosting(models.GLInterestIncome, models.DirectionCredit, rub("10")),
+		}, true},
+		{"balanced in each currency", []models.LedgerPosting{
+			models.AccountPosting(1, models.DirectionDebit, rub("9000")),
+			models.GLPosting(models.GLFXPosition, models.DirectionCredit, rub("9000")),
+			models.GLPosting(models.GLFXPosition, models.DirectionDebit, usd("100")),
+			models.AccountPosting(2, models.DirectionCredit, usd("100")),
+		}, true},
+		{"unbalanced", []models.LedgerPosting{
+			models.GLPosting(models.GLCash, models.DirectionDebit, rub("100")),
+			models.AccountPosting(1, models.DirectionCredit, rub("99.99")),
+		}, false},
+		{"balanced only across currencies", []models.LedgerPosting{
+			models.AccountPosting(1, models.DirectionDebit, rub("100")),
+			models.AccountPosting(2, models.DirectionCredit, usd("100")),
+		}, false},
+		{"single posting", []models.LedgerPosting{
+			models.GLPosting(models.GLCash, models.DirectionDebit, rub("100")),
+		}, false},
+		{"zero amount", []models.LedgerPosting{
+			models.GLPosting(models.GLCash, models.DirectionDebit, rub("0")),
+			models.AccountPosting(1, models.DirectionCredit, rub("0")),
+		}, false},
+	}
+	for _, tt := range tests {
+		err := validateEntry(&models.LedgerEntry{Type: "test", Postings: tt.postings})
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrUnbalancedEntry) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, ErrUnbalancedEntry)
+		}
+	}
+}
+
+func expectAccountLock(mock sqlmock.Sqlmock, currency, available string) {
+	mock.ExpectQuery(sqlLike("SELECT currency, balance - held FROM accounts WHERE id = $1 AND user_id = $2 FOR UPDATE")).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "available"}).AddRow(currency, available))
+}
+
+func TestWithdrawPostsLedgerEntry(t *testing.T) {
+	db, mock := newMockDB(t)
+	amount := money.MustParse("300", "RUB")
+	mock.ExpectBegin()
+	expectAccountLock(mock, "RUB", "1000.00")
+	mock.ExpectExec(sqlLike("UPDATE accounts SET balance = balance - $1")).WithArgs(amount, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(sqlLike("INSERT INTO ledger_entries")).WithArgs("withdraw", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(20, time.Now()))
+	mock.ExpectQuery(sqlLike("INSERT INTO ledger_postings")).WithArgs(20, 3, nil, models.DirectionDebit, amount, "RUB").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(sqlLike("INSERT INTO ledger_postings")).WithArgs(20, nil, models.GLCash, models.DirectionCredit, amount, "RUB").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(sqlLike("INSERT INTO transactions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(30, time.Now()))
+	mock.ExpectCommit()
+
+	if err := NewAccountRepository(db).Withdraw(context.Background(), 3, 7, amount); err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+}
+
+func TestWithdrawBeyondBalanceWritesNothing(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectAccountLock(mock, "RUB", "100.00")
+	mock.ExpectRollback()
+
+	err := NewAccountRepository(db).Withdraw(context.Background(), 3, 7, money.MustParse("300", "RUB"))
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("Withdraw: err = %v, want %v", err, ErrInsufficientFunds)
+	}
+}
@@ -1,8 +1,10 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"gobankapi/internal/models"
+	"gobankapi/internal/money"
 	"time"
 )
 
@@ -14,29 +16,41 @@ func NewPaymentScheduleRepository(db *sql.DB) *PaymentScheduleRepository {
 	return &PaymentScheduleRepository{DB: db}
 }
 
-func (r *PaymentScheduleRepository) Create(schedule *models.PaymentSchedule) error {
+func (r *PaymentScheduleRepository) Create(ctx context.Context, schedule *models.PaymentSchedule) error {
+	return insertSchedule(ctx, r.DB, schedule)
+}
+
+func insertSchedule(ctx context.Context, q rowQuerier, schedule *models.PaymentSchedule) error {
 	query := `
-		INSERT INTO payment_schedules (credit_id, due_date, amount, paid, penalty)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO payment_schedules (credit_id, due_date, amount, principal, interest, remaining, paid, paid_at, penalty,
+			amount_paid, principal_paid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id
 	`
-	return r.DB.QueryRow(query,
+	return q.QueryRowContext(ctx, query,
 		schedule.CreditID,
 		schedule.DueDate,
 		schedule.Amount,
+		schedule.Principal,
+		schedule.Interest,
+		schedule.Remaining,
 		schedule.Paid,
+		schedule.PaidAt,
 		schedule.Penalty,
+		schedule.AmountPaid,
+		schedule.PrincipalPaid,
 	).Scan(&schedule.ID)
 }
 
-func (r *PaymentScheduleRepository) FindByCreditID(creditID int) ([]*models.PaymentSchedule, error) {
+func (r *PaymentScheduleRepository) FindByCreditID(ctx context.Context, creditID int) ([]*models.PaymentSchedule, error) {
 	query := `
-		SELECT id, credit_id, due_date, amount, paid, paid_at, penalty
+		SELECT id, credit_id, due_date, amount, principal, interest, remaining, paid, paid_at, penalty,
+			amount_paid, penalty_paid, interest_paid, principal_paid
 		FROM payment_schedules
 		WHERE credit_id = $1
 		ORDER BY due_date ASC
 	`
-	rows, err := r.DB.Query(query, creditID)
+	rows, err := r.DB.QueryContext(ctx, query, creditID)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +59,8 @@ func (r *PaymentScheduleRepository) FindByCreditID(creditID int) ([]*models.Paym
 	var list []*models.PaymentSchedule
 	for rows.Next() {
 		var p models.PaymentSchedule
-		err := rows.Scan(&p.ID, &p.CreditID, &p.DueDate, &p.Amount, &p.Paid, &p.PaidAt, &p.Penalty)
+		err := rows.Scan(&p.ID, &p.CreditID, &p.DueDate, &p.Amount, &p.Principal, &p.Interest, &p.Remaining, &p.Paid, &p.PaidAt, &p.Penalty,
+			&p.AmountPaid, &p.PenaltyPaid, &p.InterestPaid, &p.PrincipalPaid)
 		if err != nil {
 			return nil, err
 		}
@@ -54,17 +69,197 @@ func (r *PaymentScheduleRepository) FindByCreditID(creditID int) ([]*models.Paym
 	return list, nil
 }
 
-func (r *PaymentScheduleRepository) GetScheduledPayments(accountID int, until time.Time) (float64, error) {
+// GetScheduledPayments — сумма неоплаченных платежей (с неустойкой) по кредитам счёта со сроком
+// до until в валюте счёта
+func (r *PaymentScheduleRepository) GetScheduledPayments(ctx context.Context, accountID int, until time.Time) (money.Money, error) {
 	query := `
-		SELECT COALESCE(SUM(ps.amount + ps.penalty), 0)
-		FROM payment_schedules ps
-		JOIN credits c ON ps.credit_id = c.id
-		WHERE c.account_id = $1
-		  AND ps.paid = false
-		  AND ps.due_date <= $2
+		SELECT a.currency, COALESCE((
+			SELECT SUM(ps.amount + ps.penalty - ps.amount_paid)
+			FROM payment_schedules ps
+			JOIN credits c ON ps.credit_id = c.id
+			WHERE c.account_id = a.id
+			  AND ps.paid = false
+			  AND ps.due_date <= $2
+		), 0)
+		FROM accounts a
+		WHERE a.id = $1
 	`
 
-	var total float64
-	err := r.DB.QueryRow(query, accountID, until).Scan(&total)
-	return total, err
+	var currency, total string
+	if err := r.DB.QueryRowContext(ctx, query, accountID, until).Scan(&currency, &total); err != nil {
+		return money.Money{}, err
+	}
+	return money.Parse(total, currency)
+}
+
+// Collection — результат списания по платежу
+type Collection struct {
+	PaymentID int
+	Collected money.Money            // списано со счёта за этот раз
+	Parts     map[string]money.Money // списано по частям платежа
+	Paid      bool                   // платёж погашен полностью
+	Skipped   bool                   // платёж сейчас списывает другой процесс
+}
+
+// Collect списывает со счёта кредита сколько есть в счёт неоплаченных частей платежа в порядке
+// waterfall (например, неустойка → проценты → основной долг) одной транзакцией: баланс, проводка
+// (дебет счёта; кредит доходов по неустойке и процентам и кредитного портфеля), запись в истории
+// и суммы погашения в строке графика. Платёж отмечается оплаченным, когда погашены все части.
+func (r *PaymentScheduleRepository) Collect(ctx context.Context, paymentID int, waterfall []string) (*Collection, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Строка графика захватывается с SKIP LOCKED: если её уже списывает другой процесс
+	// (шедулер другой реплики или обработчик пополнения), платёж пропускается, а не ждёт.
+	// Затем блокируется счёт: другие операции со строками просроченных платежей не работают,
+	// поэтому порядок «строка → счёт» не приводит к взаимоблокировкам.
+	var (
+		accountID int
+		currency  string
+		paid      bool
+		raw       [6]string
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT c.account_id, a.currency, ps.paid,
+			ps.penalty, ps.penalty_paid, ps.interest, ps.interest_paid, ps.principal, ps.principal_paid
+		FROM payment_schedules ps
+		JOIN credits c ON c.id = ps.credit_id
+		JOIN accounts a ON a.id = c.account_id
+		WHERE ps.id = $1
+		FOR UPDATE OF ps SKIP LOCKED
+	`, paymentID).Scan(&accountID, &currency, &paid, &raw[0], &raw[1], &raw[2], &raw[3], &raw[4], &raw[5])
+	if err == sql.ErrNoRows {
+		return &Collection{PaymentID: paymentID, Collected: money.Zero(money.DefaultCurrency), Skipped: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &Collection{PaymentID: paymentID, Collected: money.Zero(currency), Parts: map[string]money.Money{}, Paid: paid}
+	if paid {
+		return result, nil
+	}
+	var parts [6]money.Money
+	for i := range raw {
+		if parts[i], err = money.Parse(raw[i], currency); err != nil {
+			return nil, err
+		}
+	}
+
+	var balanceRaw string
+	// Холды по картам не списываются: доступен только остаток за их вычетом
+	if err := tx.QueryRowContext(ctx, `SELECT balance - held FROM accounts WHERE id = $1 FOR UPDATE`, accountID).Scan(&balanceRaw); err != nil {
+		return nil, err
+	}
+	balance, err := money.Parse(balanceRaw, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Остаток к погашению по каждой части платежа
+	due := map[string]money.Money{
+		models.ComponentPenalty:   parts[0].Sub(parts[1]),
+		models.ComponentInterest:  parts[2].Sub(parts[3]),
+		models.ComponentPrincipal: parts[4].Sub(parts[5]),
+	}
+
+	available := balance
+	for _, c := range waterfall {
+		if !available.IsPositive() {
+			break
+		}
+		part := money.Min(due[c], available)
+		if !part.IsPositive() {
+			continue
+		}
+		result.Parts[c] = part
+		result.Collected = result.Collected.Add(part)
+		available = available.Sub(part)
+		due[c] = due[c].Sub(part)
+	}
+	if !result.Collected.IsPositive() {
+		return result, nil
+	}
+	result.Paid = !due[models.ComponentPenalty].IsPositive() &&
+		!due[models.ComponentInterest].IsPositive() &&
+		!due[models.ComponentPrincipal].IsPositive()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET balance = balance - $1 WHERE id = $2`, result.Collected, accountID); err != nil {
+		return nil, err
+	}
+
+	entry := &models.LedgerEntry{
+		Type:     "credit_payment",
+		Postings: []models.LedgerPosting{models.AccountPosting(accountID, models.DirectionDebit, result.Collected)},
+	}
+	glAccounts := map[string]string{
+		models.ComponentPenalty:   models.GLPenaltyIncome,
+		models.ComponentInterest:  models.GLInterestIncome,
+		models.ComponentPrincipal: models.GLLoans,
+	}
+	for _, c := range waterfall {
+		if part, ok := result.Parts[c]; ok {
+			entry.Postings = append(entry.Postings, models.GLPosting(glAccounts[c], models.DirectionCredit, part))
+		}
+	}
+	if err := postLedgerEntry(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+	err = logTransaction(ctx, tx, &models.Transaction{
+		FromAccountID: &accountID,
+		Amount:        result.Collected.Neg(),
+		Type:          "credit_payment",
+		LedgerEntryID: &entry.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	zero := money.Zero(currency)
+	part := func(c string) money.Money {
+		if p, ok := result.Parts[c]; ok {
+			return p
+		}
+		return zero
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE payment_schedules
+		SET penalty_paid = penalty_paid + $1, interest_paid = interest_paid + $2, principal_paid = principal_paid + $3,
+			amount_paid = amount_paid + $4, paid = $5, paid_at = CASE WHEN $5 THEN NOW() ELSE paid_at END
+		WHERE id = $6
+	`, part(models.ComponentPenalty), part(models.ComponentInterest), part(models.ComponentPrincipal),
+		result.Collected, result.Paid, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, tx.Commit()
+}
+
+// FindOverdue — неоплаченные платежи с прошедшей датой, старые первыми. accountID = 0 — по всем счетам.
+func (r *PaymentScheduleRepository) FindOverdue(ctx context.Context, accountID int) ([]int, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT ps.id
+		FROM payment_schedules ps
+		JOIN credits c ON c.id = ps.credit_id
+		WHERE ps.paid = false AND ps.due_date < CURRENT_DATE
+		  AND ($1 = 0 OR c.account_id = $1)
+		ORDER BY ps.due_date, ps.id
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
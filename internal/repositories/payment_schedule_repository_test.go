@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestScheduledPaymentsInAccountCurrency(t *testing.T) {
+	db, mock := newMockDB(t)
+	until := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(sqlLike("SELECT a.currency, COALESCE((")).WithArgs(3, until).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("USD", "250.50"))
+
+	scheduled, err := NewPaymentScheduleRepository(db).GetScheduledPayments(context.Background(), 3, until)
+	if err != nil {
+		t.Fatalf("GetScheduledPayments: %v", err)
+	}
+	if want := money.MustParse("250.50", "USD"); scheduled != want {
+		t.Errorf("scheduled = %s %s, want %s USD", scheduled, scheduled.Currency, want)
+	}
+	// Прогноз баланса вычитает платежи из баланса счёта той же валюты
+	if expected := money.MustParse("1000", "USD").Sub(scheduled); expected != money.MustParse("749.50", "USD") {
+		t.Errorf("expected balance = %s", expected)
+	}
+}
+
+func TestScheduledPaymentsUnknownAccount(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(sqlLike("SELECT a.currency, COALESCE((")).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}))
+
+	if _, err := NewPaymentScheduleRepository(db).GetScheduledPayments(context.Background(), 3, time.Now()); err == nil {
+		t.Error("GetScheduledPayments on missing account: want error")
+	}
+}
+
+// expectCollectPayment — строка платежа 11 по счёту 3 (неустойка 50, проценты 200, основной долг 1000,
+// ничего не погашено) и доступный остаток счёта
+func expectCollectPayment(mock sqlmock.Sqlmock, available string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(sqlLike("FOR UPDATE OF ps SKIP LOCKED")).WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "currency", "paid", "penalty", "penalty_paid",
+			"interest", "interest_paid", "principal", "principal_paid"}).
+			AddRow(3, "RUB", false, "50.00", "0.00", "200.00", "0.00", "1000.00", "0.00"))
+	mock.ExpectQuery(sqlLike("SELECT balance - held FROM accounts WHERE id = $1 FOR UPDATE")).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow(available))
+}
+
+// expectCollectWrites — списание collected: баланс, проводка (счёт и GL-счета частей), история, строка графика
+func expectCollectWrites(mock sqlmock.Sqlmock, collected string, gl []string, penalty, interest, principal string, paid bool) {
+	rub := func(s string) money.Money { return money.MustParse(s, "RUB") }
+	mock.ExpectExec(sqlLike("UPDATE accounts SET balance = balance - $1")).WithArgs(rub(collected), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(sqlLike("INSERT INTO ledger_entries")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(20, time.Now()))
+	mock.ExpectQuery(sqlLike("INSERT INTO ledger_postings")).WithArgs(20, 3, nil, models.DirectionDebit, rub(collected), "RUB").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	for i, account := range gl {
+		mock.ExpectQuery(sqlLike("INSERT INTO ledger_postings")).
+			WithArgs(20, nil, account, models.DirectionCredit, sqlmock.AnyArg(), "RUB").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(i + 2))
+	}
+	mock.ExpectQuery(sqlLike("INSERT INTO transactions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(30, time.Now()))
+	mock.ExpectExec(sqlLike("SET penalty_paid = penalty_paid + $1")).
+		WithArgs(rub(penalty), rub(interest), rub(principal), rub(collected), paid, 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestCollectFollowsWaterfall(t *testing.T) {
+	rub := func(s string) money.Money { return money.MustParse(s, "RUB") }
+	tests := []struct {
+		name      string
+		waterfall []string
+		gl        []string
+		parts     [3]string // неустойка, проценты, основной долг
+	}{
+		{
+			"penalty first",
+			[]string{models.ComponentPenalty, models.ComponentInterest, models.ComponentPrincipal},
+			[]string{models.GLPenaltyIncome, models.GLInterestIncome, models.GLLoans},
+			[3]string{"50", "200", "50"},
+		},
+		{
+			"principal first",
+			[]string{models.ComponentPrincipal, models.ComponentInterest, models.ComponentPenalty},
+			[]string{models.GLLoans},
+			[3]string{"0", "0", "300"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			expectCollectPayment(mock, "300.00")
+			expectCollectWrites(mock, "300", tt.gl, tt.parts[0], tt.parts[1], tt.parts[2], false)
+
+			c, err := NewPaymentScheduleRepository(db).Collect(context.Background(), 11, tt.waterfall)
+			if err != nil {
+				t.Fatalf("Collect: %v", err)
+			}
+			if c.Collected != rub("300") || c.Paid {
+				t.Errorf("collected %s, paid %v; want 300, partial", c.Collected, c.Paid)
+			}
+			for i, component := range []string{models.ComponentPenalty, models.ComponentInterest, models.ComponentPrincipal} {
+				if got := c.Parts[component]; rub(tt.parts[i]).IsPositive() && got != rub(tt.parts[i]) {
+					t.Errorf("%s = %s, want %s", component, got, tt.parts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCollectPaysPaymentInFull(t *testing.T) {
+	db, mock := newMockDB(t)
+	waterfall := []string{models.ComponentPenalty, models.ComponentInterest, models.ComponentPrincipal}
+	expectCollectPayment(mock, "5000.00")
+	expectCollectWrites(mock, "1250", []string{models.GLPenaltyIncome, models.GLInterestIncome, models.GLLoans}, "50", "200", "1000", true)
+
+	c, err := NewPaymentScheduleRepository(db).Collect(context.Background(), 11, waterfall)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if !c.Paid || c.Collected != money.MustParse("1250", "RUB") {
+		t.Errorf("collected %s, paid %v; want 1250, paid", c.Collected, c.Paid)
+	}
+}
+
+func TestCollectFromEmptyAccountWritesNothing(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectCollectPayment(mock, "0.00")
+	mock.ExpectRollback()
+
+	c, err := NewPaymentScheduleRepository(db).Collect(context.Background(), 11, []string{models.ComponentPenalty})
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if c.Collected.IsPositive() || c.Paid {
+		t.Errorf("collected %s, paid %v; want nothing", c.Collected, c.Paid)
+	}
+}
+
+func TestCollectSkipsPaymentLockedElsewhere(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(sqlLike("FOR UPDATE OF ps SKIP LOCKED")).WillReturnRows(sqlmock.NewRows([]string{"account_id"}))
+	mock.ExpectRollback()
+
+	c, err := NewPaymentScheduleRepository(db).Collect(context.Background(), 11, []string{models.ComponentPenalty})
+	if err != nil || !c.Skipped {
+		t.Fatalf("Collect = %+v, %v; want skipped", c, err)
+	}
+}
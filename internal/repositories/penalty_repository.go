@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"time"
+)
+
+type PenaltyRepository struct {
+	DB *sql.DB
+}
+
+func NewPenaltyRepository(db *sql.DB) *PenaltyRepository {
+	return &PenaltyRepository{DB: db}
+}
+
+// Политика для кредитов без продукта или продуктов без своей политики
+const defaultPenaltyPolicy = "standard"
+
+// PolicyForCredit — политика неустойки продукта кредита (стандартная, если у продукта её нет)
+func (r *PenaltyRepository) PolicyForCredit(ctx context.Context, creditID int) (*models.PenaltyPolicy, error) {
+	var p models.PenaltyPolicy
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT pp.id, pp.code, pp.name, pp.daily_rate, pp.grace_days, pp.cap_percent
+		FROM penalty_policies pp
+		WHERE pp.id = COALESCE(
+			(SELECT cp.penalty_policy_id FROM credits c
+			 JOIN credit_products cp ON cp.id = c.product_id
+			 WHERE c.id = $1),
+			(SELECT id FROM penalty_policies WHERE code = $2)
+		)
+	`, creditID, defaultPenaltyPolicy).Scan(&p.ID, &p.Code, &p.Name, &p.DailyRate, &p.GraceDays, &p.CapPercent)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LastEventDate — дата последнего начисления неустойки по платежу (nil, если не было)
+func (r *PenaltyRepository) LastEventDate(ctx context.Context, paymentID int) (*time.Time, error) {
+	var last *time.Time
+	err := r.DB.QueryRowContext(ctx, `SELECT MAX(event_date) FROM penalty_events WHERE payment_id = $1`, paymentID).Scan(&last)
+	return last, err
+}
+
+// CreditTotal — сумма всей начисленной по кредиту неустойки
+func (r *PenaltyRepository) CreditTotal(ctx context.Context, creditID int, currency string) (money.Money, error) {
+	total := money.Zero(currency)
+	err := r.DB.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount), 0) FROM penalty_events WHERE credit_id = $1`, creditID).Scan(&total)
+	return total, err
+}
+
+// Record сохраняет событие и добавляет его сумму к неустойке платежа одной транзакцией.
+// Повторное начисление за тот же день и начисление по уже оплаченному платежу ничего не меняют
+// и возвращают false.
+func (r *PenaltyRepository) Record(ctx context.Context, e *models.PenaltyEvent) (bool, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO penalty_events (payment_id, credit_id, policy_id, event_date, overdue_amount, daily_rate, amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (payment_id, event_date) DO NOTHING
+		RETURNING id, created_at
+	`, e.PaymentID, e.CreditID, e.PolicyID, e.EventDate, e.OverdueAmount, e.DailyRate, e.Amount).Scan(&e.ID, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Платёж мог быть погашен после расчёта неустойки: тогда событие откатывается
+	res, err := tx.ExecContext(ctx, `UPDATE payment_schedules SET penalty = penalty + $1 WHERE id = $2 AND paid = false`, e.Amount, e.PaymentID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// FindByCreditID — события неустойки по кредиту пользователя, новые сверху
+func (r *PenaltyRepository) FindByCreditID(ctx context.Context, creditID, userID int) ([]*models.PenaltyEvent, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT pe.id, pe.payment_id, pe.credit_id, COALESCE(pe.policy_id, 0), pe.event_date,
+			pe.overdue_amount, pe.daily_rate, pe.amount, pe.created_at
+		FROM penalty_events pe
+		JOIN credits c ON c.id = pe.credit_id
+		WHERE pe.credit_id = $1 AND c.user_id = $2
+		ORDER BY pe.event_date DESC, pe.id DESC
+	`, creditID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.PenaltyEvent
+	for rows.Next() {
+		var e models.PenaltyEvent
+		err := rows.Scan(&e.ID, &e.PaymentID, &e.CreditID, &e.PolicyID, &e.EventDate,
+			&e.OverdueAmount, &e.DailyRate, &e.Amount, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, &e)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func testPenaltyEvent() *models.PenaltyEvent {
+	return &models.PenaltyEvent{
+		PaymentID:     11,
+		CreditID:      5,
+		PolicyID:      1,
+		EventDate:     time.Date(2026, time.March, 20, 0, 0, 0, 0, time.UTC),
+		OverdueAmount: money.MustParse("10000", "RUB"),
+		DailyRate:     0.1,
+		Amount:        money.MustParse("10", "RUB"),
+	}
+}
+
+func expectPenaltyInsert(mock sqlmock.Sqlmock, inserted bool) {
+	rows := sqlmock.NewRows([]string{"id", "created_at"})
+	if inserted {
+		rows.AddRow(1, time.Now())
+	}
+	mock.ExpectQuery(sqlLike("INSERT INTO penalty_events")).WillReturnRows(rows)
+}
+
+func TestRecordPenaltyOnUnpaidPayment(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectPenaltyInsert(mock, true)
+	mock.ExpectExec(sqlLike("SET penalty = penalty + $1 WHERE id = $2 AND paid = false")).
+		WithArgs(money.MustParse("10", "RUB"), 11).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	saved, err := NewPenaltyRepository(db).Record(context.Background(), testPenaltyEvent())
+	if err != nil || !saved {
+		t.Fatalf("Record = %v, %v; want saved", saved, err)
+	}
+}
+
+func TestRecordPenaltyOnPaidPaymentRolledBack(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectPenaltyInsert(mock, true)
+	mock.ExpectExec(sqlLike("AND paid = false")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	saved, err := NewPenaltyRepository(db).Record(context.Background(), testPenaltyEvent())
+	if err != nil || saved {
+		t.Fatalf("Record = %v, %v; want not saved", saved, err)
+	}
+}
+
+func TestRecordPenaltyTwiceForSameDay(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectPenaltyInsert(mock, false)
+	mock.ExpectRollback()
+
+	saved, err := NewPenaltyRepository(db).Record(context.Background(), testPenaltyEvent())
+	if err != nil || saved {
+		t.Fatalf("Record = %v, %v; want not saved", saved, err)
+	}
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+)
+
+type RecoveryCodeRepository struct {
+	DB *sql.DB
+}
+
+func NewRecoveryCodeRepository(db *sql.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{DB: db}
+}
+
+// Замена всех кодов восстановления пользователя новым набором
+func (r *RecoveryCodeRepository) Replace(ctx context.Context, userID int, hashes []string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes — замена кодов восстановления внутри транзакции вызывающего
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		_, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RecoveryCodeRepository) FindUnused(ctx context.Context, userID int) ([]*models.RecoveryCode, error) {
+	query := `
+		SELECT id, user_id, code_hash
+		FROM recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []*models.RecoveryCode
+	for rows.Next() {
+		var c models.RecoveryCode
+		if err := rows.Scan(&c.ID, &c.UserID, &c.CodeHash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, &c)
+	}
+	return codes, nil
+}
+
+// Погашение кода. Возвращает false, если код уже был использован параллельным запросом.
+func (r *RecoveryCodeRepository) MarkUsed(ctx context.Context, id int) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, `UPDATE recovery_codes SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
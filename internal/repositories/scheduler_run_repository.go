@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+)
+
+type SchedulerRunRepository struct {
+	DB *sql.DB
+}
+
+func NewSchedulerRunRepository(db *sql.DB) *SchedulerRunRepository {
+	return &SchedulerRunRepository{DB: db}
+}
+
+// TryLock берёт advisory lock задачи на отдельном соединении. Возвращает nil, если задача уже
+// выполняется в другом процессе. Блокировка снимается вызовом release (или при обрыве соединения).
+func (r *SchedulerRunRepository) TryLock(ctx context.Context, job string) (release func(), err error) {
+	conn, err := r.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext('gobank:' || $1))`, job).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, nil
+	}
+	return func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('gobank:' || $1))`, job)
+		conn.Close()
+	}, nil
+}
+
+func (r *SchedulerRunRepository) Start(ctx context.Context, run *models.SchedulerRun) error {
+	run.Status = models.RunRunning
+	return r.DB.QueryRowContext(ctx, `
+		INSERT INTO scheduler_runs (job, instance, status)
+		VALUES ($1, $2, $3)
+		RETURNING id, started_at
+	`, run.Job, run.Instance, run.Status).Scan(&run.ID, &run.StartedAt)
+}
+
+func (r *SchedulerRunRepository) Finish(ctx context.Context, run *models.SchedulerRun) error {
+	return r.DB.QueryRowContext(ctx, `
+		UPDATE scheduler_runs
+		SET status = $1, processed = $2, failed = $3, error = NULLIF($4, ''), finished_at = NOW()
+		WHERE id = $5
+		RETURNING finished_at
+	`, run.Status, run.Processed, run.Failed, run.Error, run.ID).Scan(&run.FinishedAt)
+}
+
+// Recent — последние запуски задачи (все задачи, если job пустой)
+func (r *SchedulerRunRepository) Recent(ctx context.Context, job string, limit int) ([]*models.SchedulerRun, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, job, instance, status, processed, failed, COALESCE(error, ''), started_at, finished_at
+		FROM scheduler_runs
+		WHERE $1 = '' OR job = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`, job, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*models.SchedulerRun
+	for rows.Next() {
+		var run models.SchedulerRun
+		err := rows.Scan(&run.ID, &run.Job, &run.Instance, &run.Status, &run.Processed, &run.Failed,
+			&run.Error, &run.StartedAt, &run.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, &run)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+	"time"
+)
+
+type SessionRepository struct {
+	DB *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{DB: db}
+}
+
+const sessionColumns = `
+	id, user_id, refresh_token_hash, COALESCE(previous_token_hash, ''), twofa_verified,
+	COALESCE(user_agent, ''), COALESCE(ip, ''), created_at, last_used_at, expires_at, revoked_at
+`
+
+func scanSession(scanner interface{ Scan(...interface{}) error }) (*models.Session, error) {
+	var s models.Session
+	err := scanner.Scan(
+		&s.ID, &s.UserID, &s.RefreshTokenHash, &s.PreviousTokenHash, &s.TwoFAVerified,
+		&s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SessionRepository) Create(ctx context.Context, s *models.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, refresh_token_hash, twofa_verified, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, last_used_at
+	`
+	return r.DB.QueryRowContext(ctx, query, s.ID, s.UserID, s.RefreshTokenHash, s.TwoFAVerified, s.UserAgent, s.IP, s.ExpiresAt).
+		Scan(&s.CreatedAt, &s.LastUsedAt)
+}
+
+// Поиск сессии по хешу refresh-токена — текущему или предыдущему.
+// Второй результат true, если совпал уже ротированный (предыдущий) токен.
+func (r *SessionRepository) FindByTokenHash(ctx context.Context, hash string) (*models.Session, bool, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE refresh_token_hash = $1 OR previous_token_hash = $1`
+	s, err := scanSession(r.DB.QueryRowContext(ctx, query, hash))
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return s, s.RefreshTokenHash != hash, nil
+}
+
+// Ротация refresh-токена. Обновление условное: из двух параллельных запросов
+// с одним и тем же токеном успешен только первый.
+func (r *SessionRepository) Rotate(ctx context.Context, id, oldHash, newHash string, expires time.Time) (bool, error) {
+	query := `
+		UPDATE sessions
+		SET previous_token_hash = refresh_token_hash, refresh_token_hash = $1,
+		    expires_at = $2, last_used_at = NOW()
+		WHERE id = $3 AND refresh_token_hash = $4 AND revoked_at IS NULL
+	`
+	result, err := r.DB.ExecContext(ctx, query, newHash, expires, id, oldHash)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+func (r *SessionRepository) FindActiveByUserID(ctx context.Context, userID int) ([]*models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// Отзыв сессии пользователя
+func (r *SessionRepository) Revoke(ctx context.Context, id string, userID int) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	result, err := r.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Отзыв конкретного access-токена до окончания его срока действия
+func (r *SessionRepository) RevokeToken(ctx context.Context, jti string, expires time.Time) error {
+	query := `INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`
+	_, err := r.DB.ExecContext(ctx, query, jti, expires)
+	return err
+}
+
+// Удаление отозванных токенов, срок действия которых истёк до now
+func (r *SessionRepository) DeleteExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// IsActive — действительна ли сессия и не отозван ли токен с данным jti
+func (r *SessionRepository) IsActive(ctx context.Context, sessionID, jti string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		) AND NOT EXISTS (
+			SELECT 1 FROM revoked_tokens WHERE jti = $2
+		)
+	`
+	var active bool
+	err := r.DB.QueryRowContext(ctx, query, sessionID, jti).Scan(&active)
+	return active, err
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"time"
+)
+
+type StatementRepository struct {
+	DB *sql.DB
+}
+
+func NewStatementRepository(db *sql.DB) *StatementRepository {
+	return &StatementRepository{DB: db}
+}
+
+// Generate формирует выписки за период [from, to) по всем счетам, открытым до его конца:
+// остатки и обороты считаются по журналу проводок. Уже сформированные выписки не меняются.
+// Возвращает число новых выписок.
+func (r *StatementRepository) Generate(ctx context.Context, from, to time.Time) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		WITH movements AS (
+			SELECT p.account_id, p.direction, p.amount, e.created_at
+			FROM ledger_postings p
+			JOIN ledger_entries e ON e.id = p.entry_id
+			WHERE p.account_id IS NOT NULL AND e.created_at < $2
+		)
+		INSERT INTO account_statements (account_id, period_start, period_end, currency,
+			opening_balance, credit_turnover, debit_turnover, closing_balance)
+		SELECT a.id, $1::date, ($2::date - 1), a.currency,
+			COALESCE(SUM(CASE WHEN m.direction = 'credit' THEN m.amount ELSE -m.amount END) FILTER (WHERE m.created_at < $1), 0),
+			COALESCE(SUM(m.amount) FILTER (WHERE m.direction = 'credit' AND m.created_at >= $1), 0),
+			COALESCE(SUM(m.amount) FILTER (WHERE m.direction = 'debit' AND m.created_at >= $1), 0),
+			COALESCE(SUM(CASE WHEN m.direction = 'credit' THEN m.amount ELSE -m.amount END), 0)
+		FROM accounts a
+		LEFT JOIN movements m ON m.account_id = a.id
+		WHERE a.created_at < $2
+		GROUP BY a.id, a.currency
+		ON CONFLICT (account_id, period_start) DO NOTHING
+	`, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FindByAccountID — выписки по счёту пользователя, новые первыми
+func (r *StatementRepository) FindByAccountID(ctx context.Context, accountID, userID int) ([]*models.Statement, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT s.id, s.account_id, s.period_start, s.period_end, s.currency,
+			s.opening_balance, s.credit_turnover, s.debit_turnover, s.closing_balance, s.created_at
+		FROM account_statements s
+		JOIN accounts a ON a.id = s.account_id
+		WHERE s.account_id = $1 AND a.user_id = $2
+		ORDER BY s.period_start DESC
+	`, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []*models.Statement
+	for rows.Next() {
+		var s models.Statement
+		var currency string
+		var amounts [4]string
+		err := rows.Scan(&s.ID, &s.AccountID, &s.PeriodStart, &s.PeriodEnd, &currency,
+			&amounts[0], &amounts[1], &amounts[2], &amounts[3], &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		for i, dst := range []*money.Money{&s.OpeningBalance, &s.CreditTurnover, &s.DebitTurnover, &s.ClosingBalance} {
+			if *dst, err = money.Parse(amounts[i], currency); err != nil {
+				return nil, err
+			}
+		}
+		statements = append(statements, &s)
+	}
+	return statements, rows.Err()
+}
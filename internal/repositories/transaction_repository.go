@@ -1,8 +1,11 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"time"
 )
 
 type TransactionRepository struct {
@@ -13,17 +16,27 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{DB: db}
 }
 
-func (r *TransactionRepository) Log(tx *models.Transaction) error {
+// logTransaction записывает операцию в историю в той же транзакции БД, что и изменение баланса
+func logTransaction(ctx context.Context, tx *sql.Tx, t *models.Transaction) error {
+	t.Currency = t.Amount.Currency
+	var counterCurrency, exchangeRate *string
+	if t.CounterAmount != nil {
+		t.CounterCurrency = t.CounterAmount.Currency
+		counterCurrency, exchangeRate = &t.CounterCurrency, &t.ExchangeRate
+	}
 	query := `
-		INSERT INTO transactions (from_account_id, to_account_id, amount, type)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO transactions (from_account_id, to_account_id, amount, currency,
+			counter_amount, counter_currency, exchange_rate, type, ledger_entry_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at
 	`
-	return r.DB.QueryRow(query, tx.FromAccountID, tx.ToAccountID, tx.Amount, tx.Type).
-		Scan(&tx.ID, &tx.CreatedAt)
+	return tx.QueryRowContext(ctx, query, t.FromAccountID, t.ToAccountID, t.Amount, t.Currency,
+		t.CounterAmount, counterCurrency, exchangeRate, t.Type, t.LedgerEntryID).
+		Scan(&t.ID, &t.CreatedAt)
 }
 
-func (r *TransactionRepository) GetMonthlySummary(userID int) (float64, float64, error) {
+// Доходы и расходы пользователя за текущий месяц по счетам в указанной валюте
+func (r *TransactionRepository) GetMonthlySummary(ctx context.Context, userID int, currency string) (money.Money, money.Money, error) {
 	query := `
 		SELECT 
 			COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0) AS income,
@@ -36,11 +49,55 @@ func (r *TransactionRepository) GetMonthlySummary(userID int) (float64, float64,
 			JOIN accounts a ON 
 				t.from_account_id = a.id OR t.to_account_id = a.id
 			WHERE DATE_TRUNC('month', t.created_at) = DATE_TRUNC('month', CURRENT_DATE)
+			  AND t.currency = $2
 		) AS subquery
 		WHERE user_id = $1
 	`
 
-	var income, expenses float64
-	err := r.DB.QueryRow(query, userID).Scan(&income, &expenses)
+	income, expenses := money.Zero(currency), money.Zero(currency)
+	err := r.DB.QueryRowContext(ctx, query, userID, currency).Scan(&income, &expenses)
 	return income, expenses, err
 }
+
+// IncomeSince — поступления на счета пользователя с момента since по валютам: только переводы
+// со счетов других клиентов. Собственные пополнения наличными, переводы между своими счетами
+// и выдачи кредитов доходом не считаются — иначе доход можно нарастить, внося свои же деньги.
+func (r *TransactionRepository) IncomeSince(ctx context.Context, userID int, since time.Time) ([]money.Money, error) {
+	query := `
+		SELECT t.currency, SUM(t.amount)
+		FROM transactions t
+		JOIN accounts a ON a.id = t.to_account_id
+		WHERE a.user_id = $1
+		  AND t.amount > 0
+		  AND t.created_at >= $2
+		  AND t.type = 'transfer'
+		  AND EXISTS (
+			  SELECT 1
+			  FROM transactions src
+			  JOIN accounts sa ON sa.id = src.from_account_id
+			  WHERE src.ledger_entry_id = t.ledger_entry_id
+			    AND src.id <> t.id
+			    AND sa.user_id <> $1
+		  )
+		GROUP BY t.currency
+	`
+	rows, err := r.DB.QueryContext(ctx, query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var income []money.Money
+	for rows.Next() {
+		var currency, total string
+		if err := rows.Scan(&currency, &total); err != nil {
+			return nil, err
+		}
+		amount, err := money.Parse(total, currency)
+		if err != nil {
+			return nil, err
+		}
+		income = append(income, amount)
+	}
+	return income, rows.Err()
+}
@@ -1,10 +1,12 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"gobankapi/internal/models"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -17,13 +19,48 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
+const userColumns = `
+	id, email, username, password_hash, created_at,
+	COALESCE(twofacode, ''), twofaexpires, COALESCE(twofa_challenge_id, ''),
+	COALESCE(twofa_purpose, ''), twofa_attempts, twofa_locked_until,
+	totp_secret_enc, totp_enabled, totp_last_step, role
+`
+
+func scanUser(row *sql.Row) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.TwoFACode,
+		&user.TwoFAExpires,
+		&user.TwoFAChallengeID,
+		&user.TwoFAPurpose,
+		&user.TwoFAAttempts,
+		&user.TwoFALockedUntil,
+		&user.TOTPSecretEnc,
+		&user.TOTPEnabled,
+		&user.TOTPLastStep,
+		&user.Role,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil // пользователь не найден — это не ошибка
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 // Создание пользователя
-func (r *UserRepository) Create(user *models.User) error {
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `INSERT INTO users (email, username, password_hash)
 			  VALUES ($1, $2, $3)
 			  RETURNING id, created_at`
 
-	err := r.DB.QueryRow(query, user.Email, user.Username, user.PasswordHash).
+	err := r.DB.QueryRowContext(ctx, query, user.Email, user.Username, user.PasswordHash).
 		Scan(&user.ID, &user.CreatedAt)
 
 	if err != nil {
@@ -39,25 +76,128 @@ func (r *UserRepository) Create(user *models.User) error {
 }
 
 // Поиск по email
-func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	return scanUser(r.DB.QueryRowContext(ctx, query, email))
+}
+
+// Поиск по ID
+func (r *UserRepository) FindByID(ctx context.Context, id int) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return scanUser(r.DB.QueryRowContext(ctx, query, id))
+}
+
+// Role — роль пользователя по ID из токена (models.RoleUser, если пользователь не найден)
+func (r *UserRepository) Role(ctx context.Context, userID string) (string, error) {
+	role := models.RoleUser
+	err := r.DB.QueryRowContext(ctx, `SELECT role FROM users WHERE id::text = $1`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return models.RoleUser, nil
+	}
+	return role, err
+}
+
+// Поиск по идентификатору активного 2FA-запроса
+func (r *UserRepository) FindByTwoFAChallenge(ctx context.Context, challengeID string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE twofa_challenge_id = $1`
+	return scanUser(r.DB.QueryRowContext(ctx, query, challengeID))
+}
+
+// Сохранение нового 2FA-запроса (для кода из письма — его HMAC). Счётчик неудачных попыток
+// не сбрасывается, чтобы повторный запрос кода не давал новых попыток подбора.
+func (r *UserRepository) SetTwoFAChallenge(ctx context.Context, userID int, challengeID, codeHash, purpose string, expires time.Time) error {
 	query := `
-		SELECT id, email, username, password_hash, created_at
-		FROM users
-		WHERE email = $1
+		UPDATE users
+		SET twofa_challenge_id = $1, twofacode = $2, twofa_purpose = $3, twofaexpires = $4
+		WHERE id = $5
 	`
-	user := &models.User{}
-	err := r.DB.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Username,
-		&user.PasswordHash,
-		&user.CreatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, nil // пользователь не найден — это не ошибка
+	_, err := r.DB.ExecContext(ctx, query, challengeID, codeHash, purpose, expires, userID)
+	return err
+}
+
+// Сброс 2FA-запроса challengeID после успешной проверки или истечения срока.
+// Возвращает false, если запрос уже погашен или заменён другим (параллельная проверка).
+func (r *UserRepository) ClearTwoFAChallenge(ctx context.Context, userID int, challengeID string, resetAttempts bool) (bool, error) {
+	query := `
+		UPDATE users
+		SET twofa_challenge_id = NULL, twofacode = NULL, twofa_purpose = NULL, twofaexpires = NULL,
+		    twofa_attempts = CASE WHEN $3 THEN 0 ELSE twofa_attempts END
+		WHERE id = $1 AND twofa_challenge_id = $2
+	`
+	result, err := r.DB.ExecContext(ctx, query, userID, challengeID, resetAttempts)
+	if err != nil {
+		return false, err
 	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// Учёт неудачной попытки ввода кода. При достижении maxAttempts активный запрос
+// аннулируется, а ввод кодов блокируется до lockUntil. Возвращает время окончания блокировки.
+func (r *UserRepository) RegisterFailedTwoFAAttempt(ctx context.Context, userID, maxAttempts int, lockUntil time.Time) (*time.Time, error) {
+	query := `
+		UPDATE users
+		SET twofa_locked_until = CASE WHEN twofa_attempts + 1 >= $2 THEN $3 ELSE twofa_locked_until END,
+		    twofa_challenge_id = CASE WHEN twofa_attempts + 1 >= $2 THEN NULL ELSE twofa_challenge_id END,
+		    twofacode          = CASE WHEN twofa_attempts + 1 >= $2 THEN NULL ELSE twofacode END,
+		    twofa_attempts     = CASE WHEN twofa_attempts + 1 >= $2 THEN 0 ELSE twofa_attempts + 1 END
+		WHERE id = $1
+		RETURNING twofa_locked_until
+	`
+	var lockedUntil *time.Time
+	err := r.DB.QueryRowContext(ctx, query, userID, maxAttempts, lockUntil).Scan(&lockedUntil)
+	return lockedUntil, err
+}
+
+// Сохранение секрета TOTP, ожидающего подтверждения (до подтверждения TOTP не включён)
+func (r *UserRepository) SetPendingTOTPSecret(ctx context.Context, userID int, secretEnc []byte) error {
+	query := `
+		UPDATE users
+		SET totp_secret_enc = $1, totp_enabled = false, totp_last_step = 0
+		WHERE id = $2 AND totp_enabled = false
+	`
+	result, err := r.DB.ExecContext(ctx, query, secretEnc, userID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return user, nil
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Включение TOTP вместе с новым набором кодов восстановления в одной транзакции.
+// Возвращает false, если TOTP уже включён (параллельное подтверждение) — тогда ничего не меняется.
+func (r *UserRepository) EnableTOTP(ctx context.Context, userID int, step int64, recoveryHashes []string) (bool, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET totp_enabled = true, totp_last_step = $1 WHERE id = $2 AND NOT totp_enabled`
+	result, err := tx.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return false, nil
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Фиксация использованного шага TOTP. Возвращает false, если код этого
+// или более позднего шага уже применялся (повторное использование).
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`
+	result, err := r.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
 }
@@ -1,32 +1,79 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"gobankapi/internal/config"
+	"gobankapi/internal/events"
 	"gobankapi/internal/handlers"
 	"gobankapi/internal/middleware"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
 	"gobankapi/internal/repositories"
+	"gobankapi/internal/scheduler"
 	"gobankapi/internal/services"
+	"log"
 	"net/http"
 	"path/filepath"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
-func SetupRouter() *mux.Router {
+// SetupRouter собирает маршруты. ctx — время жизни приложения: с его отменой
+// останавливается фоновая ротация ключей JWT. bus — шина доменных событий приложения,
+// centralBank — общий с планировщиком клиент ЦБ (один кэш и одни повторы на приложение).
+func SetupRouter(ctx context.Context, jobs *scheduler.Registry, bus *events.Bus, centralBank services.CentralBank) *mux.Router {
 	r := mux.NewRouter()
 	mailer := services.NewMailer()
 
 	userRepo := repositories.NewUserRepository(config.DB)
-	userHandler := handlers.NewUserHandler(userRepo)
+	recoveryRepo := repositories.NewRecoveryCodeRepository(config.DB)
+	fx := services.NewExchangeService(repositories.NewExchangeRateRepository(config.DB))
+	twoFA, err := services.NewTwoFactorService(userRepo, recoveryRepo, mailer, fx)
+	if err != nil {
+		log.Fatalf("Ошибка настройки 2FA: %v", err)
+	}
+	sessionRepo := repositories.NewSessionRepository(config.DB)
+	keyManager, err := services.NewKeyManager(repositories.NewJWTKeyRepository(config.DB))
+	if err != nil {
+		log.Fatalf("Ошибка настройки подписи JWT: %v", err)
+	}
+	if err := keyManager.Load(ctx); err != nil {
+		log.Fatalf("Ошибка загрузки ключей JWT: %v", err)
+	}
+	go keyManager.StartRotation(ctx, time.Duration(config.AppConfig.JWTKeyRefreshMinutes)*time.Minute)
+	middleware.SetKeyResolver(keyManager)
+
+	tokens := services.NewTokenService(sessionRepo, keyManager)
+	userHandler := handlers.NewUserHandler(userRepo, twoFA, tokens)
+	sessionHandler := handlers.NewSessionHandler(sessionRepo, tokens)
+	middleware.SetSessionValidator(sessionRepo)
+	middleware.SetRoleResolver(userRepo)
 
 	// --- Публичные маршруты ---
 	r.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("pong"))
 	}).Methods("GET")
 
+	// Открытые ключи проверки JWT для других сервисов
+	r.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		set, err := keyManager.JWKS()
+		if err != nil {
+			http.Error(w, "Could not build JWKS", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(services.JWKSMaxAge.Seconds())))
+		json.NewEncoder(w).Encode(set)
+	}).Methods("GET")
+
 	r.HandleFunc("/register", userHandler.Register).Methods("POST")
 	r.HandleFunc("/login", userHandler.Login).Methods("POST")
+	r.HandleFunc("/login/verify", userHandler.VerifyLogin).Methods("POST")
+	r.HandleFunc("/auth/refresh", sessionHandler.Refresh).Methods("POST")
+	r.Handle("/auth/logout", middleware.AuthMiddleware(http.HandlerFunc(sessionHandler.Logout))).Methods("POST")
 
 	r.HandleFunc("/register-form", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "register.html"))
@@ -36,6 +83,11 @@ func SetupRouter() *mux.Router {
 	authRouter := r.PathPrefix("/api").Subrouter()
 	authRouter.Use(middleware.AuthMiddleware)
 
+	// Чувствительные маршруты доступны только с токеном, выданным после 2FA
+	sensitive := func(h http.HandlerFunc) http.Handler {
+		return middleware.RequireTwoFA(h)
+	}
+
 	authRouter.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
 		userID := r.Context().Value(middleware.UserIDKey)
 		w.Header().Set("Content-Type", "application/json")
@@ -44,6 +96,17 @@ func SetupRouter() *mux.Router {
 		})
 	}).Methods("GET")
 
+	// --- Сессии пользователя (устройства) ---
+	authRouter.HandleFunc("/sessions", sessionHandler.ListSessions).Methods("GET")
+	authRouter.HandleFunc("/sessions/{sessionId}", sessionHandler.RevokeSession).Methods("DELETE")
+
+	// --- Подключение TOTP (приложение-аутентификатор) ---
+	// Доступно и без 2fa-verified: подключить TOTP можно, только пока он выключен, а без
+	// кода из письма при входе это единственный способ включить второй фактор
+	twoFAHandler := handlers.NewTwoFAHandler(twoFA)
+	authRouter.HandleFunc("/2fa/totp/enroll", twoFAHandler.EnrollTOTP).Methods("POST")
+	authRouter.HandleFunc("/2fa/totp/confirm", twoFAHandler.ConfirmTOTP).Methods("POST")
+
 	// --- Маршрут для логин-формы ---
 	r.HandleFunc("/login-form", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "login.html"))
@@ -58,8 +121,14 @@ func SetupRouter() *mux.Router {
 	accountRepo := repositories.NewAccountRepository(config.DB)
 	transactionRepo := repositories.NewTransactionRepository(config.DB)
 	scheduleRepo := repositories.NewPaymentScheduleRepository(config.DB)
+	ledgerRepo := repositories.NewLedgerRepository(config.DB)
+
+	// Зачисления на счёт публикуют AccountFunded — по нему сразу списываются просроченные платежи
+	accountRepo.Events = bus
+	services.NewCollectionService(scheduleRepo).Subscribe(bus)
 
-	accountHandler := handlers.NewAccountHandler(accountRepo, transactionRepo, scheduleRepo)
+	accountHandler := handlers.NewAccountHandler(accountRepo, transactionRepo, scheduleRepo, ledgerRepo,
+		repositories.NewStatementRepository(config.DB), twoFA, fx)
 
 	authRouter.HandleFunc("/accounts", accountHandler.CreateAccount).Methods("POST")
 	authRouter.HandleFunc("/accounts", accountHandler.GetUserAccounts).Methods("GET")
@@ -70,14 +139,14 @@ func SetupRouter() *mux.Router {
 
 	// --- Маршрут для пополнения и списания + страница проверки ---
 	authRouter.HandleFunc("/accounts/deposit", accountHandler.Deposit).Methods("POST")
-	authRouter.HandleFunc("/accounts/withdraw", accountHandler.Withdraw).Methods("POST")
+	authRouter.Handle("/accounts/withdraw", sensitive(accountHandler.Withdraw)).Methods("POST")
 
 	r.HandleFunc("/accounts-balance", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "accounts-balance.html"))
 	}).Methods("GET")
 
 	// --- Маршрут для перевода между счетами + страница проверки ---
-	authRouter.HandleFunc("/transfer", accountHandler.Transfer).Methods("POST")
+	authRouter.Handle("/transfer", sensitive(accountHandler.Transfer)).Methods("POST")
 
 	r.HandleFunc("/transfer-form", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "transfer.html"))
@@ -85,9 +154,34 @@ func SetupRouter() *mux.Router {
 
 	// --- Маршрут для создания карт + страница проверки ---
 	cardRepo := repositories.NewCardRepository(config.DB)
-	cardHandler := handlers.NewCardHandler(cardRepo)
-
-	authRouter.HandleFunc("/cards", cardHandler.CreateCard).Methods("POST")
+	cardService, err := services.NewCardService(cardRepo, userRepo, repositories.NewCardControlRepository(config.DB))
+	if err != nil {
+		log.Fatalf("Ошибка настройки шифрования карт: %v", err)
+	}
+	cardHandler := handlers.NewCardHandler(cardRepo, cardService)
+
+	authRouter.Handle("/cards", sensitive(cardHandler.CreateCard)).Methods("POST")
+	authRouter.Handle("/cards/{cardToken}/reveal", sensitive(cardHandler.RevealCard)).Methods("POST")
+
+	// Жизненный цикл карты: заморозить и заблокировать можно сразу, разморозка и перевыпуск — после 2FA
+	authRouter.HandleFunc("/cards/{cardToken}/freeze", cardHandler.FreezeCard).Methods("POST")
+	authRouter.Handle("/cards/{cardToken}/unfreeze", sensitive(cardHandler.UnfreezeCard)).Methods("POST")
+	authRouter.HandleFunc("/cards/{cardToken}/block", cardHandler.BlockCard).Methods("POST")
+	authRouter.Handle("/cards/{cardToken}/reissue", sensitive(cardHandler.ReissueCard)).Methods("POST")
+
+	// Ограничения по карте: изменение — после 2FA, чтобы украденный токен не снял лимиты
+	authRouter.HandleFunc("/cards/{cardToken}/controls", cardHandler.GetControls).Methods("GET")
+	authRouter.Handle("/cards/{cardToken}/controls", sensitive(cardHandler.UpdateControls)).Methods("PUT")
+
+	// --- Авторизации по картам: маршруты платёжной системы по ключу X-API-Key ---
+	processing := services.NewCardProcessingService(cardService, repositories.NewCardAuthorizationRepository(config.DB))
+	networkHandler := handlers.NewCardNetworkHandler(processing)
+	networkRouter := r.PathPrefix("/card-network").Subrouter()
+	networkRouter.Use(middleware.RequireAPIKey(config.AppConfig.CardNetworkAPIKey))
+	networkRouter.HandleFunc("/authorizations", networkHandler.Authorize).Methods("POST")
+	networkRouter.HandleFunc("/charges", networkHandler.Charge).Methods("POST")
+	networkRouter.HandleFunc("/authorizations/{authId}/capture", networkHandler.Capture).Methods("POST")
+	networkRouter.HandleFunc("/authorizations/{authId}/reverse", networkHandler.Reverse).Methods("POST")
 
 	r.HandleFunc("/cards-form", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "cards.html"))
@@ -102,9 +196,24 @@ func SetupRouter() *mux.Router {
 
 	// --- Блок и маршрут по кредитам + страница проверки ---
 	creditRepo := repositories.NewCreditRepository(config.DB)
-	creditHandler := handlers.NewCreditHandler(creditRepo, scheduleRepo)
-
-	authRouter.HandleFunc("/credits", creditHandler.CreateCredit).Methods("POST")
+	pricing := services.NewCreditPricingService(
+		repositories.NewCreditProductRepository(config.DB), creditRepo, userRepo, centralBank, mailer,
+	)
+	applications, err := services.NewCreditApplicationService(
+		repositories.NewCreditApplicationRepository(config.DB), accountRepo, creditRepo, transactionRepo,
+		repositories.NewHolidayRepository(config.DB), pricing, fx,
+	)
+	if err != nil {
+		log.Fatalf("Ошибка настройки скоринга кредитов: %v", err)
+	}
+	creditHandler := handlers.NewCreditHandler(creditRepo, scheduleRepo,
+		repositories.NewInterestAccrualRepository(config.DB), repositories.NewPenaltyRepository(config.DB), accountRepo, pricing, applications, twoFA)
+
+	authRouter.HandleFunc("/credit-products", creditHandler.ListProducts).Methods("GET")
+
+	authRouter.Handle("/credits", sensitive(creditHandler.CreateCredit)).Methods("POST")
+	authRouter.HandleFunc("/credit-applications", creditHandler.ListApplications).Methods("GET")
+	authRouter.HandleFunc("/credit-applications/{applicationId}", creditHandler.GetApplication).Methods("GET")
 
 	r.HandleFunc("/credits-form", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "credits.html"))
@@ -112,6 +221,9 @@ func SetupRouter() *mux.Router {
 
 	// --- Маршрут для графика платежей + страница проверки ---
 	authRouter.HandleFunc("/credits/{creditId}/schedule", creditHandler.GetSchedule).Methods("GET")
+	authRouter.HandleFunc("/credits/{creditId}/accruals", creditHandler.GetAccruals).Methods("GET")
+	authRouter.HandleFunc("/credits/{creditId}/penalties", creditHandler.GetPenalties).Methods("GET")
+	authRouter.Handle("/credits/{creditId}/repay", sensitive(creditHandler.RepayCredit)).Methods("POST")
 
 	r.HandleFunc("/schedule-form", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "schedule-view.html"))
@@ -132,13 +244,19 @@ func SetupRouter() *mux.Router {
 	// --- Маршрут для прогноза баланса ---
 	authRouter.HandleFunc("/accounts/{accountId}/predict", accountHandler.PredictBalance).Methods("GET")
 
+	// --- Сверка баланса счёта с журналом двойной записи ---
+	authRouter.HandleFunc("/accounts/{accountId}/ledger", accountHandler.LedgerBalance).Methods("GET")
+
+	// --- Ежемесячные выписки по счёту ---
+	authRouter.HandleFunc("/accounts/{accountId}/statements", accountHandler.GetStatements).Methods("GET")
+
 	r.HandleFunc("/predict-form", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "predict-balance.html"))
 	}).Methods("GET")
 
 	// --- Проверка SMTP ---
 	authRouter.HandleFunc("/test-email", func(w http.ResponseWriter, r *http.Request) {
-		err := mailer.SendPaymentConfirmation("your@email.com", 149.90)
+		err := mailer.SendPaymentConfirmation(r.Context(), "your@email.com", money.MustParse("149.90", money.DefaultCurrency))
 		if err != nil {
 			http.Error(w, "Ошибка отправки письма: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -152,7 +270,7 @@ func SetupRouter() *mux.Router {
 
 	// --- Получение ключевой ставки ---
 	authRouter.HandleFunc("/test-rate", func(w http.ResponseWriter, r *http.Request) {
-		rate, err := services.GetCentralBankRate()
+		rate, err := centralBank.LendingRate(r.Context())
 		if err != nil {
 			http.Error(w, "Ошибка получения ставки: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -167,5 +285,24 @@ func SetupRouter() *mux.Router {
 		http.ServeFile(w, r, filepath.Join("static", "test-rate.html"))
 	}).Methods("GET")
 
+	// --- Официальные курсы валют ЦБ ---
+	rateHandler := handlers.NewRateHandler(repositories.NewExchangeRateRepository(config.DB))
+	authRouter.HandleFunc("/rates", rateHandler.GetRates).Methods("GET")
+	authRouter.HandleFunc("/rates/{currency}/history", rateHandler.GetHistory).Methods("GET")
+
+	// --- Администрирование фоновых задач (только роль admin) ---
+	adminHandler := handlers.NewAdminHandler(jobs, repositories.NewSchedulerRunRepository(config.DB))
+	adminRouter := authRouter.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.RequireRole(models.RoleAdmin))
+	adminRouter.HandleFunc("/jobs", adminHandler.ListJobs).Methods("GET")
+	adminRouter.HandleFunc("/jobs/runs", adminHandler.ListRuns).Methods("GET")
+	adminRouter.Handle("/jobs/{job}/run", sensitive(adminHandler.RunJob)).Methods("POST")
+
+	// Производственный календарь: праздники на новые годы заводятся здесь
+	holidayHandler := handlers.NewHolidayHandler(repositories.NewHolidayRepository(config.DB))
+	adminRouter.HandleFunc("/holidays", holidayHandler.List).Methods("GET")
+	adminRouter.Handle("/holidays/{date}", sensitive(holidayHandler.Put)).Methods("PUT")
+	adminRouter.Handle("/holidays/{date}", sensitive(holidayHandler.Delete)).Methods("DELETE")
+
 	return r
 }
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+	"log"
+	"time"
+)
+
+// Не больше стольких дней догоняющего начисления по кредиту за один запуск
+const maxAccrualDays = 366
+
+// AccrueInterest начисляет проценты по каждому непогашенному кредиту за все завершившиеся дни
+// до now: остаток основного долга на конец дня × ставка × доля года за день по конвенции кредита
+// (банковское округление). Пропущенные дни догоняются, повторный запуск ничего не дублирует.
+func AccrueInterest(ctx context.Context, db *sql.DB, now time.Time) (JobResult, error) {
+	log.Println("Запуск начисления процентов...")
+	repo := repositories.NewInterestAccrualRepository(db)
+
+	var result JobResult
+	targets, err := repo.Targets(ctx)
+	if err != nil {
+		log.Println("Ошибка выборки кредитов для начисления:", err)
+		return result, err
+	}
+
+	today := utils.Date(now)
+	for _, t := range targets {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		credit := t.Credit
+		day := time.Date(t.From.Year(), t.From.Month(), t.From.Day(), 0, 0, 0, 0, today.Location())
+		for n := 0; day.Before(today) && n < maxAccrualDays; n++ {
+			principal, err := repo.OutstandingOn(ctx, credit, day)
+			if err != nil {
+				log.Printf("Кредит #%d: ошибка расчёта остатка на %s: %v\n", credit.ID, day.Format("2006-01-02"), err)
+				result.Failed++
+				break
+			}
+			next := day.AddDate(0, 0, 1)
+			rate := utils.PeriodRate(credit.DayCount, credit.AnnualRate, day, next)
+			saved, err := repo.Save(ctx, &models.InterestAccrual{
+				CreditID:    credit.ID,
+				AccrualDate: day,
+				Principal:   principal,
+				AnnualRate:  credit.AnnualRate,
+				DayCount:    credit.DayCount,
+				Amount:      principal.MulRat(rate, money.HalfEven),
+			})
+			if err != nil {
+				log.Printf("Кредит #%d: ошибка начисления за %s: %v\n", credit.ID, day.Format("2006-01-02"), err)
+				result.Failed++
+				break
+			}
+			if saved {
+				result.Processed++
+			}
+			day = next
+		}
+	}
+
+	log.Printf("Начисление процентов завершено: %d записей.\n", result.Processed)
+	return result, nil
+}
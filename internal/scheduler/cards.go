@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+	"log"
+	"time"
+)
+
+// ExpireCards переводит в expired активные и замороженные карты, срок действия которых
+// закончился до сегодняшнего дня
+func ExpireCards(ctx context.Context, db *sql.DB, now time.Time) (JobResult, error) {
+	var result JobResult
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	expired, err := repositories.NewCardRepository(db).ExpireBefore(ctx, utils.Date(now))
+	if err != nil {
+		log.Println("Ошибка истечения срока карт:", err)
+		return result, err
+	}
+	result.Processed = int(expired)
+	if expired > 0 {
+		log.Printf("Срок действия истёк у карт: %d\n", expired)
+	}
+	return result, nil
+}
+
+// ExpireHolds снимает холды авторизаций по картам, не списанных до истечения срока холда
+func ExpireHolds(ctx context.Context, db *sql.DB, now time.Time) (JobResult, error) {
+	var result JobResult
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	expired, err := repositories.NewCardAuthorizationRepository(db).ExpireBefore(ctx, now)
+	if err != nil {
+		log.Println("Ошибка истечения холдов по картам:", err)
+		return result, err
+	}
+	result.Processed = expired
+	if expired > 0 {
+		log.Printf("Истекли холды по авторизациям: %d\n", expired)
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
+	"log"
+	"os"
+	"time"
+)
+
+// JobResult — итог запуска задачи для истории scheduler_runs
+type JobResult struct {
+	Processed int
+	Failed    int
+}
+
+// Имена задач: ключ advisory lock, поле job в scheduler_runs и имя в /api/admin/jobs
+const (
+	JobPayments      = "payments"
+	JobPenalties     = "penalties"
+	JobAccruals      = "accruals"
+	JobRates         = "rates"
+	JobStatements    = "statements"
+	JobCardExpiry    = "card_expiry"
+	JobCardHolds     = "card_holds"
+	JobRevokedTokens = "revoked_tokens"
+)
+
+// RegisterJobs регистрирует фоновые задачи банка с расписанием из конфигурации
+func RegisterJobs(reg *Registry, db *sql.DB, cb services.CentralBank, pricing *services.CreditPricingService) error {
+	cfg := config.AppConfig
+	jobs := []struct {
+		name string
+		spec string
+		run  JobFunc
+	}{
+		{JobPayments, cfg.CronPayments, func(ctx context.Context) (JobResult, error) {
+			return Run(ctx, db)
+		}},
+		{JobPenalties, cfg.CronPenalties, func(ctx context.Context) (JobResult, error) {
+			return ChargePenalties(ctx, db, time.Now())
+		}},
+		{JobAccruals, cfg.CronAccruals, func(ctx context.Context) (JobResult, error) {
+			return AccrueInterest(ctx, db, time.Now())
+		}},
+		{JobRates, cfg.CronRates, func(ctx context.Context) (JobResult, error) {
+			return RefreshRates(ctx, db, cb, pricing)
+		}},
+		{JobStatements, cfg.CronStatements, func(ctx context.Context) (JobResult, error) {
+			return GenerateStatements(ctx, db, time.Now())
+		}},
+		{JobCardExpiry, cfg.CronCardExpiry, func(ctx context.Context) (JobResult, error) {
+			return ExpireCards(ctx, db, time.Now())
+		}},
+		{JobCardHolds, cfg.CronCardHolds, func(ctx context.Context) (JobResult, error) {
+			return ExpireHolds(ctx, db, time.Now())
+		}},
+		{JobRevokedTokens, cfg.CronRevokedTokens, func(ctx context.Context) (JobResult, error) {
+			return PruneRevokedTokens(ctx, db, time.Now())
+		}},
+	}
+	for _, j := range jobs {
+		if err := reg.Register(j.name, j.spec, j.run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instance — идентификатор процесса в истории запусков
+var instance = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}()
+
+// runExclusive выполняет задачу, только если она не выполняется в другом процессе (advisory lock
+// в PostgreSQL), и записывает запуск в scheduler_runs: начало, конец, число обработанных и ошибок
+func runExclusive(ctx context.Context, db *sql.DB, job string, fn func() (JobResult, error)) {
+	runs := repositories.NewSchedulerRunRepository(db)
+
+	release, err := runs.TryLock(ctx, job)
+	if err != nil {
+		log.Printf("Задача %s: не удалось взять блокировку: %v\n", job, err)
+		return
+	}
+	if release == nil {
+		log.Printf("Задача %s уже выполняется в другом процессе — пропуск\n", job)
+		return
+	}
+	defer release()
+
+	run := &models.SchedulerRun{Job: job, Instance: instance}
+	if err := runs.Start(ctx, run); err != nil {
+		log.Printf("Задача %s: ошибка записи запуска: %v\n", job, err)
+		return
+	}
+
+	result, err := fn()
+	run.Processed, run.Failed = result.Processed, result.Failed
+	run.Status = models.RunSucceeded
+	if err != nil {
+		run.Status, run.Error = models.RunFailed, err.Error()
+	}
+	// Итог записывается и для прерванного остановкой запуска
+	if err := runs.Finish(context.WithoutCancel(ctx), run); err != nil {
+		log.Printf("Задача %s: ошибка записи итога запуска: %v\n", job, err)
+	}
+}
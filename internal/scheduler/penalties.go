@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+	"log"
+	"math"
+	"time"
+)
+
+// overduePayment — просроченный платёж, по которому начисляется неустойка
+type overduePayment struct {
+	ID           int
+	CreditID     int
+	DueDate      time.Time
+	Overdue      money.Money // неоплаченные проценты и основной долг платежа
+	CreditAmount money.Money // сумма кредита — база предела неустойки
+}
+
+// ChargePenalties начисляет неустойку по всем просроченным платежам на неоплаченные проценты
+// и основной долг. Запускается после списания, чтобы неустойка не начислялась на то, что
+// удалось списать в тот же день.
+func ChargePenalties(ctx context.Context, db *sql.DB, now time.Time) (JobResult, error) {
+	log.Println("Запуск начисления неустойки...")
+
+	query := `
+		SELECT ps.id, ps.credit_id, ps.due_date, ps.amount - ps.interest_paid - ps.principal_paid, c.amount
+		FROM payment_schedules ps
+		JOIN credits c ON ps.credit_id = c.id
+		WHERE ps.paid = false AND ps.due_date < CURRENT_DATE
+		ORDER BY ps.due_date, ps.id
+	`
+
+	var result JobResult
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		log.Println("Ошибка запроса просроченных платежей:", err)
+		return result, err
+	}
+	var payments []overduePayment
+	for rows.Next() {
+		var p overduePayment
+		if err := rows.Scan(&p.ID, &p.CreditID, &p.DueDate, &p.Overdue, &p.CreditAmount); err != nil {
+			log.Println("Ошибка сканирования:", err)
+			result.Failed++
+			continue
+		}
+		payments = append(payments, p)
+	}
+	rows.Close()
+
+	repo := repositories.NewPenaltyRepository(db)
+	for _, p := range payments {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if !p.Overdue.IsPositive() {
+			continue
+		}
+		penalty, err := chargePenalties(ctx, repo, p, now)
+		if err != nil {
+			log.Printf("Платёж #%d: ошибка начисления штрафа: %v\n", p.ID, err)
+			result.Failed++
+			continue
+		}
+		result.Processed++
+		if penalty.IsPositive() {
+			log.Printf("Платёж #%d: начислен штраф %s\n", p.ID, penalty)
+		}
+	}
+
+	log.Println("Начисление неустойки завершено.")
+	return result, nil
+}
+
+// effectiveDailyRate — ставка политики, ограниченная законным пределом (% годовых / 365)
+func effectiveDailyRate(policy *models.PenaltyPolicy) float64 {
+	rate := policy.DailyRate
+	if legal := config.AppConfig.PenaltyMaxAnnualRate / 365; legal < rate {
+		rate = legal
+	}
+	return math.Floor(rate*10000) / 10000 // daily_rate — NUMERIC(6,4), округляем вниз, чтобы не превысить предел
+}
+
+// chargePenalties начисляет неустойку по платежу за каждый календарный день просрочки после
+// льготного периода — с дня после последнего начисления по today включительно. Каждый день —
+// отдельное событие; повторный запуск в тот же день ничего не добавляет. Сумма неустойки
+// по кредиту не превышает cap_percent от суммы кредита. Возвращает начисленное за запуск.
+func chargePenalties(ctx context.Context, repo *repositories.PenaltyRepository, p overduePayment, today time.Time) (money.Money, error) {
+	charged := money.Zero(p.Overdue.Currency)
+
+	policy, err := repo.PolicyForCredit(ctx, p.CreditID)
+	if err != nil {
+		return charged, err
+	}
+	rate := effectiveDailyRate(policy)
+	if rate <= 0 {
+		return charged, nil
+	}
+
+	// Первый день неустойки — следующий после даты платежа и льготного периода
+	day := utils.Date(p.DueDate).AddDate(0, 0, policy.GraceDays+1)
+	last, err := repo.LastEventDate(ctx, p.ID)
+	if err != nil {
+		return charged, err
+	}
+	if last != nil && !utils.Date(*last).Before(day) {
+		day = utils.Date(*last).AddDate(0, 0, 1)
+	}
+
+	total, err := repo.CreditTotal(ctx, p.CreditID, p.Overdue.Currency)
+	if err != nil {
+		return charged, err
+	}
+	room := p.CreditAmount.Percent(policy.CapPercent, money.HalfUp).Sub(total)
+
+	today = utils.Date(today)
+	for ; !day.After(today) && room.IsPositive(); day = day.AddDate(0, 0, 1) {
+		amount := money.Min(p.Overdue.Percent(rate, money.HalfUp), room)
+		if !amount.IsPositive() {
+			break
+		}
+		saved, err := repo.Record(ctx, &models.PenaltyEvent{
+			PaymentID:     p.ID,
+			CreditID:      p.CreditID,
+			PolicyID:      policy.ID,
+			EventDate:     day,
+			OverdueAmount: p.Overdue,
+			DailyRate:     rate,
+			Amount:        amount,
+		})
+		if err != nil {
+			return charged, err
+		}
+		if saved {
+			charged = charged.Add(amount)
+			room = room.Sub(amount)
+		}
+	}
+	return charged, nil
+}
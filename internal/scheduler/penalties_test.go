@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func withConfig(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	saved := config.AppConfig
+	config.AppConfig = cfg
+	t.Cleanup(func() { config.AppConfig = saved })
+}
+
+// expectPenaltySetup — политика кредита, последнее начисление по платежу и уже начисленное по кредиту
+func expectPenaltySetup(mock sqlmock.Sqlmock, policy *models.PenaltyPolicy, last *time.Time, total string) {
+	mock.ExpectQuery(regexp.QuoteMeta("FROM penalty_policies pp")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name", "daily_rate", "grace_days", "cap_percent"}).
+			AddRow(policy.ID, policy.Code, policy.Name, policy.DailyRate, policy.GraceDays, policy.CapPercent))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT MAX(event_date) FROM penalty_events")).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(last))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(amount), 0) FROM penalty_events")).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(total))
+}
+
+func expectPenaltyRecord(mock sqlmock.Sqlmock, day time.Time, amount money.Money) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO penalty_events")).
+		WithArgs(11, 5, 1, day, sqlmock.AnyArg(), sqlmock.AnyArg(), amount).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE payment_schedules SET penalty = penalty + $1")).
+		WithArgs(amount, 11).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func testPenaltyRepo(t *testing.T) (*repositories.PenaltyRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return repositories.NewPenaltyRepository(db), mock
+}
+
+// Платёж на 10 000 ₽ по кредиту на 10 000 ₽ просрочен 20 марта, политика — 0,1% в день
+func testOverdue() overduePayment {
+	return overduePayment{
+		ID:           11,
+		CreditID:     5,
+		DueDate:      time.Date(2026, time.March, 20, 0, 0, 0, 0, time.UTC),
+		Overdue:      money.MustParse("10000", "RUB"),
+		CreditAmount: money.MustParse("10000", "RUB"),
+	}
+}
+
+func testPolicy(graceDays int, capPercent float64) *models.PenaltyPolicy {
+	return &models.PenaltyPolicy{ID: 1, Code: "standard", Name: "Стандартная", DailyRate: 0.1, GraceDays: graceDays, CapPercent: capPercent}
+}
+
+func TestChargePenaltiesEachDayAfterGrace(t *testing.T) {
+	withConfig(t, &config.Config{PenaltyMaxAnnualRate: 365})
+	repo, mock := testPenaltyRepo(t)
+	p := testOverdue()
+	expectPenaltySetup(mock, testPolicy(1, 10), nil, "0")
+	// Льготный день 21 марта пропускается, неустойка — за 22 и 23 марта по 10 ₽
+	daily := money.MustParse("10", "RUB")
+	expectPenaltyRecord(mock, time.Date(2026, time.March, 22, 0, 0, 0, 0, time.UTC), daily)
+	expectPenaltyRecord(mock, time.Date(2026, time.March, 23, 0, 0, 0, 0, time.UTC), daily)
+
+	charged, err := chargePenalties(context.Background(), repo, p, time.Date(2026, time.March, 23, 15, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("chargePenalties: %v", err)
+	}
+	if want := money.MustParse("20", "RUB"); charged != want {
+		t.Errorf("charged = %s, want %s", charged, want)
+	}
+}
+
+func TestChargePenaltiesCappedAtCapPercent(t *testing.T) {
+	withConfig(t, &config.Config{PenaltyMaxAnnualRate: 365})
+	repo, mock := testPenaltyRepo(t)
+	p := testOverdue()
+	// Предел 1% от 10 000 ₽ = 100 ₽, 95 ₽ уже начислено: за 21 марта — только 5 ₽, дальше ничего
+	expectPenaltySetup(mock, testPolicy(0, 1), nil, "95")
+	expectPenaltyRecord(mock, time.Date(2026, time.March, 21, 0, 0, 0, 0, time.UTC), money.MustParse("5", "RUB"))
+
+	charged, err := chargePenalties(context.Background(), repo, p, time.Date(2026, time.March, 25, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("chargePenalties: %v", err)
+	}
+	if want := money.MustParse("5", "RUB"); charged != want {
+		t.Errorf("charged = %s, want %s", charged, want)
+	}
+}
+
+func TestChargePenaltiesNothingWithinGrace(t *testing.T) {
+	withConfig(t, &config.Config{PenaltyMaxAnnualRate: 365})
+	repo, mock := testPenaltyRepo(t)
+	expectPenaltySetup(mock, testPolicy(5, 10), nil, "0")
+
+	charged, err := chargePenalties(context.Background(), repo, testOverdue(), time.Date(2026, time.March, 25, 0, 0, 0, 0, time.UTC))
+	if err != nil || !charged.IsZero() {
+		t.Fatalf("chargePenalties = %s, %v; want nothing within grace period", charged, err)
+	}
+}
+
+func TestEffectiveDailyRateLimitedByLaw(t *testing.T) {
+	withConfig(t, &config.Config{PenaltyMaxAnnualRate: 20})
+	// 20% годовых / 365 = 0,0547…% в день, округляется вниз до 0,0547
+	if rate := effectiveDailyRate(testPolicy(0, 10)); rate != 0.0547 {
+		t.Errorf("rate = %v, want 0.0547", rate)
+	}
+	withConfig(t, &config.Config{PenaltyMaxAnnualRate: 365})
+	if rate := effectiveDailyRate(testPolicy(0, 10)); rate != 0.1 {
+		t.Errorf("rate = %v, want policy rate 0.1", rate)
+	}
+}
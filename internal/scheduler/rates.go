@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
+	"log"
+	"time"
+)
+
+// RefreshRates загружает курсы ЦБ и пересчитывает кредиты с плавающей ставкой
+// при изменении ключевой ставки
+func RefreshRates(ctx context.Context, db *sql.DB, cb services.CentralBank, pricing *services.CreditPricingService) (JobResult, error) {
+	result := FetchRates(ctx, db, cb)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if err := pricing.RepriceFloating(ctx); err != nil {
+		log.Println("Ошибка пересчёта кредитов с плавающей ставкой:", err)
+		return result, err
+	}
+	return result, nil
+}
+
+// FetchRates загружает курсы на сегодня и на завтра: ЦБ публикует курс следующего дня
+// накануне. Если курса на завтра ещё нет, ЦБ возвращает действующий — он просто перезапишется.
+func FetchRates(ctx context.Context, db *sql.DB, cb services.CentralBank) JobResult {
+	repo := repositories.NewExchangeRateRepository(db)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var result JobResult
+	for _, date := range []time.Time{today, today.AddDate(0, 0, 1)} {
+		if ctx.Err() != nil {
+			break
+		}
+		rates, err := cb.CurrencyRates(ctx, date)
+		if err != nil {
+			log.Printf("Ошибка загрузки курсов на %s: %v\n", date.Format("2006-01-02"), err)
+			result.Failed++
+			continue
+		}
+		saved := 0
+		for _, rate := range rates {
+			if err := repo.Save(ctx, rate); err != nil {
+				log.Printf("Ошибка сохранения курса %s: %v\n", rate.Currency, err)
+				result.Failed++
+				continue
+			}
+			saved++
+		}
+		log.Printf("Курсы ЦБ на %s: сохранено %d\n", rates[0].RateDate.Format("2006-01-02"), saved)
+		result.Processed += saved
+	}
+	return result
+}
@@ -0,0 +1,169 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	ErrUnknownJob       = errors.New("unknown job")
+	ErrSchedulerStopped = errors.New("scheduler is stopped")
+)
+
+// JobFunc — тело фоновой задачи. Контекст отменяется при остановке планировщика: задача
+// прерывается между записями и возвращает то, что успела обработать.
+type JobFunc func(ctx context.Context) (JobResult, error)
+
+type job struct {
+	name  string
+	spec  string
+	run   JobFunc
+	entry cron.EntryID
+}
+
+// JobInfo — задача реестра для админского API
+type JobInfo struct {
+	Name    string     `json:"name"`
+	Spec    string     `json:"spec"`
+	NextRun *time.Time `json:"next_run,omitempty"`
+	PrevRun *time.Time `json:"prev_run,omitempty"`
+}
+
+// Registry — реестр фоновых задач с расписанием в формате cron. Каждый запуск (по расписанию
+// или вручную) выполняется через runExclusive: в одном процессе из всех и с записью в scheduler_runs.
+type Registry struct {
+	db   *sql.DB
+	cron *cron.Cron
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	order   []string
+	stopped bool
+	running sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewRegistry(db *sql.DB) *Registry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Registry{
+		db:     db,
+		cron:   cron.New(),
+		jobs:   make(map[string]*job),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Register добавляет задачу с расписанием spec: пять полей cron («0 3 * * *»)
+// или дескриптор («@daily», «@every 6h»)
+func (r *Registry) Register(name, spec string, run JobFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.jobs[name]; exists {
+		return fmt.Errorf("job %q is already registered", name)
+	}
+	j := &job{name: name, spec: spec, run: run}
+	entry, err := r.cron.AddFunc(spec, func() { r.execute(j) })
+	if err != nil {
+		return fmt.Errorf("job %q: invalid schedule %q: %w", name, spec, err)
+	}
+	j.entry = entry
+	r.jobs[name] = j
+	r.order = append(r.order, name)
+	return nil
+}
+
+// Start запускает выполнение задач по расписанию
+func (r *Registry) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range r.order {
+		log.Printf("Задача %s: расписание %q\n", name, r.jobs[name].spec)
+	}
+	r.cron.Start()
+}
+
+// Trigger запускает задачу вне расписания в фоне. Если она уже выполняется в этом
+// или другом процессе, запуск пропускается (см. runExclusive).
+func (r *Registry) Trigger(name string) error {
+	r.mu.Lock()
+	j, ok := r.jobs[name]
+	stopped := r.stopped
+	r.mu.Unlock()
+
+	if !ok {
+		return ErrUnknownJob
+	}
+	if stopped {
+		return ErrSchedulerStopped
+	}
+	go r.execute(j)
+	return nil
+}
+
+// Jobs — зарегистрированные задачи в порядке регистрации с ближайшим и последним запуском
+func (r *Registry) Jobs() []JobInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	infos := make([]JobInfo, 0, len(r.order))
+	for _, name := range r.order {
+		j := r.jobs[name]
+		info := JobInfo{Name: j.name, Spec: j.spec}
+		entry := r.cron.Entry(j.entry)
+		if !entry.Next.IsZero() {
+			info.NextRun = &entry.Next
+		}
+		if !entry.Prev.IsZero() {
+			info.PrevRun = &entry.Prev
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Shutdown останавливает расписание, отменяет контекст выполняющихся задач и ждёт их завершения
+// (текущая запись каждой задачи дорабатывается). Возвращает ошибку ctx, если ждать дольше нельзя.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.stopped = true
+	r.mu.Unlock()
+
+	r.cron.Stop()
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Registry) execute(j *job) {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.running.Add(1)
+	r.mu.Unlock()
+	defer r.running.Done()
+
+	runExclusive(r.ctx, r.db, j.name, func() (JobResult, error) { return j.run(r.ctx) })
+}
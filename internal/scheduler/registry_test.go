@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return db, mock
+}
+
+func expectJobLock(mock sqlmock.Sqlmock, job string, locked bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock")).WithArgs(job).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(locked))
+}
+
+func expectJobRun(mock sqlmock.Sqlmock, job, status string, processed, failed int, errText string) {
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO scheduler_runs")).WithArgs(job, instance, models.RunRunning).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "started_at"}).AddRow(1, time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE scheduler_runs")).WithArgs(status, processed, failed, errText, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"finished_at"}).AddRow(time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock")).WithArgs(job).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestRunExclusiveRecordsRun(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectJobLock(mock, "payments", true)
+	expectJobRun(mock, "payments", models.RunSucceeded, 3, 1, "")
+
+	ran := false
+	runExclusive(context.Background(), db, "payments", func() (JobResult, error) {
+		ran = true
+		return JobResult{Processed: 3, Failed: 1}, nil
+	})
+	if !ran {
+		t.Error("job did not run")
+	}
+}
+
+func TestRunExclusiveRecordsFailure(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectJobLock(mock, "payments", true)
+	expectJobRun(mock, "payments", models.RunFailed, 2, 0, "connection lost")
+
+	runExclusive(context.Background(), db, "payments", func() (JobResult, error) {
+		return JobResult{Processed: 2}, errors.New("connection lost")
+	})
+}
+
+func TestRunExclusiveSkipsJobLockedElsewhere(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectJobLock(mock, "payments", false)
+
+	runExclusive(context.Background(), db, "payments", func() (JobResult, error) {
+		t.Error("job ran while locked by another process")
+		return JobResult{}, nil
+	})
+}
+
+func noopJob(ctx context.Context) (JobResult, error) { return JobResult{}, nil }
+
+func TestRegisterRejectsDuplicateAndInvalidSchedule(t *testing.T) {
+	reg := NewRegistry(nil)
+	if err := reg.Register("payments", "0 */12 * * *", noopJob); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := reg.Register("payments", "@daily", noopJob); err == nil {
+		t.Error("duplicate job registered")
+	}
+	if err := reg.Register("rates", "every six hours", noopJob); err == nil {
+		t.Error("invalid schedule accepted")
+	}
+	if jobs := reg.Jobs(); len(jobs) != 1 || jobs[0].Name != "payments" || jobs[0].Spec != "0 */12 * * *" {
+		t.Errorf("jobs = %+v, want only payments", jobs)
+	}
+}
+
+func TestTriggerRejected(t *testing.T) {
+	reg := NewRegistry(nil)
+	if err := reg.Register("payments", "@daily", noopJob); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Trigger("unknown"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Trigger unknown: err = %v, want %v", err, ErrUnknownJob)
+	}
+	if err := reg.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := reg.Trigger("payments"); !errors.Is(err, ErrSchedulerStopped) {
+		t.Errorf("Trigger after shutdown: err = %v, want %v", err, ErrSchedulerStopped)
+	}
+}
+
+func TestShutdownWaitsForRunningJob(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectJobLock(mock, "statements", true)
+	expectJobRun(mock, "statements", models.RunSucceeded, 0, 0, "")
+
+	reg := NewRegistry(db)
+	started, finished := make(chan struct{}), make(chan struct{})
+	err := reg.Register("statements", "@daily", func(ctx context.Context) (JobResult, error) {
+		close(started)
+		<-ctx.Done() // задача прерывается отменой контекста при остановке
+		close(finished)
+		return JobResult{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Trigger("statements"); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := reg.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("Shutdown returned before the running job finished")
+	}
+}
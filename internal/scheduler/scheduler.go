@@ -1,106 +1,66 @@
 package scheduler
 
 import (
+	"context"
 	"database/sql"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/services"
 	"log"
-	"time"
 )
 
-// Старт автоматического шедулера
-func StartScheduler(db *sql.DB, intervalHours int) {
-	log.Printf("Шедулер запущен. Интервал: %d часов.\n", intervalHours)
-
-	ticker := time.NewTicker(time.Duration(intervalHours) * time.Hour)
-	defer ticker.Stop()
-
-	// Первый запуск сразу
-	Run(db)
-
-	for {
-		select {
-		case <-ticker.C:
-			Run(db)
-		}
-	}
-}
-
-// Одноразовая обработка платежей
-func Run(db *sql.DB) {
+// Одноразовое списание просроченных платежей: каждый платёж списывается с доступного остатка
+// счёта (в том числе частично). Неустойка по неоплаченному остатку — отдельная задача ChargePenalties.
+func Run(ctx context.Context, db *sql.DB) (JobResult, error) {
 	log.Println("Запуск обработки просроченных платежей...")
 
 	query := `
-		SELECT ps.id, ps.credit_id, ps.amount, c.account_id
+		SELECT ps.id
 		FROM payment_schedules ps
-		JOIN credits c ON ps.credit_id = c.id
 		WHERE ps.paid = false AND ps.due_date < CURRENT_DATE
+		ORDER BY ps.due_date, ps.id
 	`
 
-	rows, err := db.Query(query)
+	var result JobResult
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		log.Println("Ошибка запроса платежей:", err)
-		return
+		return result, err
 	}
-	defer rows.Close()
-
+	var payments []int
 	for rows.Next() {
-		var (
-			paymentID int
-			creditID  int
-			amount    float64
-			accountID int
-			balance   float64
-		)
-
-		err := rows.Scan(&paymentID, &creditID, &amount, &accountID)
-		if err != nil {
+		var id int
+		if err := rows.Scan(&id); err != nil {
 			log.Println("Ошибка сканирования:", err)
+			result.Failed++
 			continue
 		}
+		payments = append(payments, id)
+	}
+	rows.Close()
 
-		err = db.QueryRow("SELECT balance FROM accounts WHERE id = $1", accountID).Scan(&balance)
+	collector := services.NewCollectionService(repositories.NewPaymentScheduleRepository(db))
+	for _, id := range payments {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		c, err := collector.Collect(ctx, id)
 		if err != nil {
-			log.Println("Ошибка получения баланса:", err)
+			log.Println("Ошибка транзакции списания:", err)
+			result.Failed++
 			continue
 		}
-
-		if balance >= amount {
-			tx, err := db.Begin()
-			if err != nil {
-				log.Println("Ошибка начала транзакции:", err)
-				continue
-			}
-
-			_, err = tx.Exec("UPDATE accounts SET balance = balance - $1 WHERE id = $2", amount, accountID)
-			if err == nil {
-				_, err = tx.Exec(`
-					UPDATE payment_schedules
-					SET paid = true, paid_at = $1
-					WHERE id = $2
-				`, time.Now(), paymentID)
-			}
-
-			if err != nil {
-				log.Println("Ошибка транзакции списания:", err)
-				tx.Rollback()
-			} else {
-				tx.Commit()
-				log.Printf("Платёж #%d успешно списан\n", paymentID)
-			}
-		} else {
-			penalty := amount * 0.10
-			_, err := db.Exec(`
-				UPDATE payment_schedules
-				SET penalty = penalty + $1
-				WHERE id = $2
-			`, penalty, paymentID)
-
-			if err != nil {
-				log.Println("Ошибка начисления штрафа:", err)
-			} else {
-				log.Printf("Недостаточно средств. Платёж #%d: начислен штраф %.2f\n", paymentID, penalty)
-			}
+		if c.Skipped {
+			log.Printf("Платёж #%d списывается другим процессом — пропуск\n", id)
+			continue
+		}
+		result.Processed++
+		if c.Paid {
+			log.Printf("Платёж #%d успешно списан\n", id)
+		} else if c.Collected.IsPositive() {
+			log.Printf("Платёж #%d: списано частично %s\n", id, c.Collected)
 		}
 	}
 
 	log.Println("Шедулер завершил обработку.")
+	return result, nil
 }
@@ -0,0 +1,29 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/repositories"
+	"log"
+	"time"
+)
+
+// PruneRevokedTokens удаляет записи об отозванных access-токенах, срок действия которых истёк:
+// такие токены и так отклоняются по exp
+func PruneRevokedTokens(ctx context.Context, db *sql.DB, now time.Time) (JobResult, error) {
+	var result JobResult
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	deleted, err := repositories.NewSessionRepository(db).DeleteExpiredRevokedTokens(ctx, now)
+	if err != nil {
+		log.Println("Ошибка очистки отозванных токенов:", err)
+		return result, err
+	}
+	result.Processed = int(deleted)
+	if deleted > 0 {
+		log.Printf("Удалены истёкшие отозванные токены: %d\n", deleted)
+	}
+	return result, nil
+}
@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"gobankapi/internal/repositories"
+	"log"
+	"time"
+)
+
+// GenerateStatements формирует выписки по всем счетам за предыдущий календарный месяц.
+// Повторный запуск в том же месяце ничего не дублирует.
+func GenerateStatements(ctx context.Context, db *sql.DB, now time.Time) (JobResult, error) {
+	var result JobResult
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	from := to.AddDate(0, -1, 0)
+	log.Printf("Формирование выписок за %s...\n", from.Format("2006-01"))
+
+	created, err := repositories.NewStatementRepository(db).Generate(ctx, from, to)
+	if err != nil {
+		log.Println("Ошибка формирования выписок:", err)
+		return result, err
+	}
+	result.Processed = int(created)
+	log.Printf("Сформировано выписок: %d\n", created)
+	return result, nil
+}
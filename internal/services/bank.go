@@ -2,45 +2,199 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+
 	"github.com/beevik/etree"
 )
 
-func buildSOAPRequest() string {
-	fromDate := time.Now().AddDate(0, 0, -30).Format("2006-01-02")
+// CentralBank — данные ЦБ РФ, которые нужны остальному коду. Реализация по умолчанию —
+// CentralBankClient; в тестах подменяется заглушкой.
+type CentralBank interface {
+	// KeyRate — текущая ключевая ставка ЦБ, % годовых
+	KeyRate(ctx context.Context) (float64, error)
+	// LendingRate — ключевая ставка плюс маржа банка: базовая ставка по кредитам
+	LendingRate(ctx context.Context) (float64, error)
+	// CurrencyRates — официальные курсы валют на дату
+	CurrencyRates(ctx context.Context, date time.Time) ([]*models.ExchangeRate, error)
+}
+
+var ErrCentralBankUnavailable = errors.New("central bank service unavailable")
+
+// SOAPFault — ошибка, которую вернул сам веб-сервис (элемент soap:Fault)
+type SOAPFault struct {
+	Code   string // soap:Sender — ошибка в запросе, soap:Receiver — сбой на стороне ЦБ
+	Reason string
+}
+
+func (f *SOAPFault) Error() string {
+	return fmt.Sprintf("SOAP fault %s: %s", f.Code, f.Reason)
+}
+
+// Temporary — сбой на стороне сервиса, запрос имеет смысл повторить
+func (f *SOAPFault) Temporary() bool {
+	return !strings.HasSuffix(f.Code, "Sender") && !strings.HasSuffix(f.Code, "Client")
+}
+
+// StatusError — ответ с HTTP-статусом, отличным от 200, без SOAP Fault в теле
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("неожиданный статус ответа: %s", e.Status)
+}
+
+// Temporary — 5xx и 429: сбой или перегрузка сервиса, запрос имеет смысл повторить.
+// Остальные 4xx — ошибка в самом запросе, повтор вернёт то же.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// temporary — можно ли повторить запрос после ошибки err
+func temporary(err error) bool {
+	var t interface{ Temporary() bool }
+	if errors.As(err, &t) {
+		return t.Temporary()
+	}
+	// Сетевые ошибки и ошибки чтения ответа
+	return true
+}
+
+type cachedResponse struct {
+	body      []byte
+	expiresAt time.Time
+}
+
+// CentralBankClient — SOAP-клиент веб-сервиса DailyInfo ЦБ РФ с повторами и кэшем ответов
+type CentralBankClient struct {
+	BaseURL       string
+	HTTPClient    *http.Client
+	Retries       int           // число повторов после первой неудачной попытки
+	Backoff       time.Duration // пауза перед первым повтором, далее удваивается
+	CacheTTL      time.Duration
+	Margin        float64 // маржа банка к ключевой ставке, п.п.
+	KeyRateWindow int     // за сколько дней запрашивается история ключевой ставки
+
+	mu    sync.Mutex
+	cache map[string]cachedResponse
+}
+
+func NewCentralBankClient() *CentralBankClient {
+	return &CentralBankClient{
+		BaseURL:       config.AppConfig.CBRURL,
+		HTTPClient:    &http.Client{Timeout: time.Duration(config.AppConfig.CBRTimeoutSeconds) * time.Second},
+		Retries:       config.AppConfig.CBRRetries,
+		Backoff:       time.Duration(config.AppConfig.CBRRetryBackoffMs) * time.Millisecond,
+		CacheTTL:      time.Duration(config.AppConfig.CBRCacheTTLMinutes) * time.Minute,
+		Margin:        config.AppConfig.BankRateMargin,
+		KeyRateWindow: config.AppConfig.CBRKeyRateWindowDays,
+		cache:         make(map[string]cachedResponse),
+	}
+}
+
+func (c *CentralBankClient) KeyRate(ctx context.Context) (float64, error) {
+	fromDate := time.Now().AddDate(0, 0, -c.KeyRateWindow).Format("2006-01-02")
 	toDate := time.Now().Format("2006-01-02")
-	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
-        <soap12:Envelope xmlns:soap12="http://www.w3.org/2003/05/soap-envelope">
-            <soap12:Body>
-                <KeyRate xmlns="http://web.cbr.ru/">
+	body := fmt.Sprintf(`<KeyRate xmlns="http://web.cbr.ru/">
                     <fromDate>%s</fromDate>
                     <ToDate>%s</ToDate>
-                </KeyRate>
-            </soap12:Body>
-        </soap12:Envelope>`, fromDate, toDate)
+                </KeyRate>`, fromDate, toDate)
+
+	rawBody, err := c.call(ctx, "KeyRate", body)
+	if err != nil {
+		return 0, err
+	}
+	return parseKeyRateResponse(rawBody)
+}
+
+func (c *CentralBankClient) LendingRate(ctx context.Context) (float64, error) {
+	rate, err := c.KeyRate(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return rate + c.Margin, nil
 }
 
-func sendRequest(soapRequest string) ([]byte, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
+func (c *CentralBankClient) CurrencyRates(ctx context.Context, date time.Time) ([]*models.ExchangeRate, error) {
+	body := fmt.Sprintf(`<GetCursOnDate xmlns="http://web.cbr.ru/">
+                    <On_date>%s</On_date>
+                </GetCursOnDate>`, date.Format("2006-01-02"))
 
-	req, err := http.NewRequest(
-		"POST",
-		"https://cbr.ru/DailyInfoWebServ/DailyInfo.asmx",
-		bytes.NewBuffer([]byte(soapRequest)),
-	)
+	rawBody, err := c.call(ctx, "GetCursOnDate", body)
 	if err != nil {
 		return nil, err
 	}
+	return parseCursOnDateResponse(rawBody, date)
+}
+
+// call отправляет SOAP-запрос. Одинаковые запросы в пределах CacheTTL берутся из кэша,
+// сетевые ошибки, ответы 5xx и 429 и временные SOAP Fault повторяются с экспоненциальной паузой,
+// остальные ответы 4xx и SOAP Fault отправителя возвращаются сразу. Отмена ctx прерывает и текущий запрос, и ожидание перед повтором.
+func (c *CentralBankClient) call(ctx context.Context, action, body string) ([]byte, error) {
+	key := action + "|" + body
+	if cached, ok := c.cached(key); ok {
+		return cached, nil
+	}
 
+	envelope := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+        <soap12:Envelope xmlns:soap12="http://www.w3.org/2003/05/soap-envelope">
+            <soap12:Body>
+                %s
+            </soap12:Body>
+        </soap12:Envelope>`, body)
+
+	var lastErr error
+	backoff := c.Backoff
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("ЦБ: повтор %s (%d/%d) через %s: %v", action, attempt, c.Retries, backoff, lastErr)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			backoff *= 2
+		}
+
+		rawBody, err := c.send(ctx, action, envelope)
+		if err == nil {
+			c.store(key, rawBody)
+			return rawBody, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if !temporary(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", ErrCentralBankUnavailable, lastErr)
+}
+
+func (c *CentralBankClient) send(ctx context.Context, action, envelope string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewBufferString(envelope))
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
-	req.Header.Set("SOAPAction", "http://web.cbr.ru/KeyRate")
+	req.Header.Set("SOAPAction", "http://web.cbr.ru/"+action)
 
-	resp, err := client.Do(req)
+	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса: %v", err)
 	}
@@ -51,10 +205,62 @@ func sendRequest(soapRequest string) ([]byte, error) {
 		return nil, fmt.Errorf("ошибка чтения ответа: %v", err)
 	}
 
+	// SOAP 1.2 передаёт Fault с кодом 500, поэтому тело проверяется до статуса
+	if fault := parseSOAPFault(rawBody); fault != nil {
+		return nil, fault
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
 	return rawBody, nil
 }
 
-func parseXMLResponse(rawBody []byte) (float64, error) {
+func (c *CentralBankClient) cached(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(c.cache, key)
+		return nil, false
+	}
+	return entry.body, true
+}
+
+func (c *CentralBankClient) store(key string, body []byte) {
+	if c.CacheTTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache[key] = cachedResponse{body: body, expiresAt: time.Now().Add(c.CacheTTL)}
+}
+
+// parseSOAPFault возвращает Fault из ответа (SOAP 1.2 или 1.1) либо nil
+func parseSOAPFault(rawBody []byte) *SOAPFault {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(rawBody); err != nil {
+		return nil
+	}
+	fault := doc.FindElement("//Body/Fault")
+	if fault == nil {
+		return nil
+	}
+
+	f := &SOAPFault{}
+	if code := fault.FindElement("./Code/Value"); code != nil {
+		f.Code = strings.TrimSpace(code.Text())
+	} else if code := fault.FindElement("./faultcode"); code != nil {
+		f.Code = strings.TrimSpace(code.Text())
+	}
+	if reason := fault.FindElement("./Reason/Text"); reason != nil {
+		f.Reason = strings.TrimSpace(reason.Text())
+	} else if reason := fault.FindElement("./faultstring"); reason != nil {
+		f.Reason = strings.TrimSpace(reason.Text())
+	}
+	return f
+}
+
+func parseKeyRateResponse(rawBody []byte) (float64, error) {
 	doc := etree.NewDocument()
 	if err := doc.ReadFromBytes(rawBody); err != nil {
 		return 0, fmt.Errorf("ошибка парсинга XML: %v", err)
@@ -77,18 +283,61 @@ func parseXMLResponse(rawBody []byte) (float64, error) {
 	return rate, nil
 }
 
-func GetCentralBankRate() (float64, error) {
-	soapRequest := buildSOAPRequest()
-	rawBody, err := sendRequest(soapRequest)
-	if err != nil {
-		return 0, err
+// Источник курсов в таблице exchange_rates
+const RateSourceCBR = "cbr"
+
+// parseCursOnDateResponse разбирает ответ GetCursOnDate: курсы валют к рублю за Vnom единиц
+func parseCursOnDateResponse(rawBody []byte, date time.Time) ([]*models.ExchangeRate, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(rawBody); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга XML: %v", err)
 	}
-	rate, err := parseXMLResponse(rawBody)
-	if err != nil {
-		return 0, err
+
+	data := doc.FindElement("//diffgram/ValuteData")
+	if data == nil {
+		return nil, errors.New("данные по курсам не найдены")
+	}
+	// Дата, на которую ЦБ установил курсы, может отличаться от запрошенной (выходные)
+	rateDate := date
+	if onDate := data.SelectAttrValue("OnDate", ""); onDate != "" {
+		if parsed, err := time.Parse("20060102", onDate); err == nil {
+			rateDate = parsed
+		}
 	}
 
-	// Добавим маржу банка
-	rate += 5
-	return rate, nil
+	var rates []*models.ExchangeRate
+	for _, v := range data.SelectElements("ValuteCursOnDate") {
+		code := strings.TrimSpace(elementText(v, "VchCode"))
+		curs := strings.TrimSpace(elementText(v, "Vcurs"))
+		nominal, err := strconv.Atoi(strings.TrimSpace(elementText(v, "Vnom")))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка конвертации номинала %s: %v", code, err)
+		}
+
+		rate := &models.ExchangeRate{
+			Currency: code,
+			Rate:     curs,
+			Nominal:  nominal,
+			RateDate: rateDate,
+			Source:   RateSourceCBR,
+		}
+		if len(code) != 3 {
+			return nil, fmt.Errorf("некорректный код валюты %q", code)
+		}
+		if _, err := rate.PerUnit(); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if len(rates) == 0 {
+		return nil, errors.New("данные по курсам не найдены")
+	}
+	return rates, nil
+}
+
+func elementText(parent *etree.Element, tag string) string {
+	if el := parent.SelectElement(tag); el != nil {
+		return el.Text()
+	}
+	return ""
 }
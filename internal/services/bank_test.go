@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeCBR — локальный SOAP-сервер, который отдаёт записанные ответы ЦБ по SOAPAction
+func fakeCBR(t *testing.T) *httptest.Server {
+	t.Helper()
+	fixtures := map[string]string{
+		"http://web.cbr.ru/KeyRate":       "cbr_keyrate.xml",
+		"http://web.cbr.ru/GetCursOnDate": "cbr_curs_on_date.xml",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := fixtures[r.Header.Get("SOAPAction")]
+		if !ok {
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil || !strings.Contains(string(body), "soap12:Envelope") {
+			http.Error(w, "bad envelope", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+		http.ServeFile(w, r, filepath.Join("testdata", name))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestCentralBank(url string) *CentralBankClient {
+	return &CentralBankClient{
+		BaseURL:       url,
+		HTTPClient:    &http.Client{Timeout: 5 * time.Second},
+		Backoff:       time.Millisecond,
+		Margin:        3,
+		KeyRateWindow: 30,
+		cache:         make(map[string]cachedResponse),
+	}
+}
+
+func TestCentralBankKeyRate(t *testing.T) {
+	cb := newTestCentralBank(fakeCBR(t).URL)
+
+	rate, err := cb.KeyRate(context.Background())
+	if err != nil {
+		t.Fatalf("KeyRate: %v", err)
+	}
+	if rate != 21 {
+		t.Errorf("KeyRate = %v, want 21 (latest record)", rate)
+	}
+
+	lending, err := cb.LendingRate(context.Background())
+	if err != nil {
+		t.Fatalf("LendingRate: %v", err)
+	}
+	if lending != 24 {
+		t.Errorf("LendingRate = %v, want 24", lending)
+	}
+}
+
+func TestCentralBankCurrencyRates(t *testing.T) {
+	cb := newTestCentralBank(fakeCBR(t).URL)
+
+	requested := time.Date(2024, time.October, 27, 0, 0, 0, 0, time.UTC)
+	rates, err := cb.CurrencyRates(context.Background(), requested)
+	if err != nil {
+		t.Fatalf("CurrencyRates: %v", err)
+	}
+
+	want := map[string]struct {
+		rate    string
+		nominal int
+	}{
+		"USD": {"96.2538", 1},
+		"EUR": {"104.0006", 1},
+		"KZT": {"19.8297", 100},
+		"JPY": {"63.4523", 100},
+	}
+	if len(rates) != len(want) {
+		t.Fatalf("got %d rates, want %d", len(rates), len(want))
+	}
+	onDate := time.Date(2024, time.October, 26, 0, 0, 0, 0, time.UTC)
+	for _, r := range rates {
+		w, ok := want[r.Currency]
+		if !ok {
+			t.Errorf("unexpected currency %q", r.Currency)
+			continue
+		}
+		if r.Rate != w.rate || r.Nominal != w.nominal {
+			t.Errorf("%s = %s/%d, want %s/%d", r.Currency, r.Rate, r.Nominal, w.rate, w.nominal)
+		}
+		if !r.RateDate.Equal(onDate) {
+			t.Errorf("%s rate date %s, want OnDate %s", r.Currency, r.RateDate, onDate)
+		}
+		if r.Source != RateSourceCBR {
+			t.Errorf("%s source %q, want %q", r.Currency, r.Source, RateSourceCBR)
+		}
+	}
+}
+
+func TestParseFixturesDirectly(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "cbr_curs_on_date.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseKeyRateResponse(raw); err == nil {
+		t.Error("parseKeyRateResponse accepted a GetCursOnDate response")
+	}
+	if fault := parseSOAPFault(raw); fault != nil {
+		t.Errorf("parseSOAPFault found %v in a successful response", fault)
+	}
+}
+
+const (
+	soap12Fault = `<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body><soap:Fault><soap:Code><soap:Value>%s</soap:Value></soap:Code><soap:Reason><soap:Text xml:lang="ru">%s</soap:Text></soap:Reason></soap:Fault></soap:Body></soap:Envelope>`
+	soap11Fault = `<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault><faultcode>%s</faultcode><faultstring>%s</faultstring></soap:Fault></soap:Body></soap:Envelope>`
+)
+
+// flakyCBR отвечает failures раз статусом status с телом body, затем — записанным ответом KeyRate
+func flakyCBR(t *testing.T, failures int32, status int, body string) (*httptest.Server, *int32) {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "cbr_keyrate.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+			return
+		}
+		w.Write(raw)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestCentralBankRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		status    int
+		body      string
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"recovers after 5xx", 2, http.StatusServiceUnavailable, "", 3, false},
+		{"gives up after retries", 10, http.StatusBadGateway, "", 3, true},
+		{"429 is retried", 1, http.StatusTooManyRequests, "", 2, false},
+		{"4xx is not retried", 10, http.StatusNotFound, "", 1, true},
+		{"temporary fault is retried", 1, http.StatusInternalServerError, fmt.Sprintf(soap12Fault, "soap:Receiver", "Server busy"), 2, false},
+		{"sender fault is not retried", 10, http.StatusInternalServerError, fmt.Sprintf(soap12Fault, "soap:Sender", "Bad date"), 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := flakyCBR(t, tt.failures, tt.status, tt.body)
+			cb := newTestCentralBank(srv.URL)
+			cb.Retries = 2
+
+			rate, err := cb.KeyRate(context.Background())
+			if got := atomic.LoadInt32(calls); got != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("KeyRate = %v, want error", rate)
+				}
+				return
+			}
+			if err != nil || rate != 21 {
+				t.Fatalf("KeyRate = %v, %v; want 21", rate, err)
+			}
+		})
+	}
+}
+
+func TestCentralBankErrorKinds(t *testing.T) {
+	srv, _ := flakyCBR(t, 10, http.StatusBadRequest, "")
+	cb := newTestCentralBank(srv.URL)
+	cb.Retries = 2
+	_, err := cb.KeyRate(context.Background())
+	var status *StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusBadRequest {
+		t.Errorf("4xx error = %v, want *StatusError 400", err)
+	}
+	if errors.Is(err, ErrCentralBankUnavailable) {
+		t.Errorf("4xx error %v reported as unavailable", err)
+	}
+
+	srv, _ = flakyCBR(t, 10, http.StatusServiceUnavailable, "")
+	cb = newTestCentralBank(srv.URL)
+	cb.Retries = 1
+	if _, err := cb.KeyRate(context.Background()); !errors.Is(err, ErrCentralBankUnavailable) {
+		t.Errorf("5xx error = %v, want ErrCentralBankUnavailable", err)
+	}
+}
+
+func TestParseSOAPFault(t *testing.T) {
+	tests := []struct {
+		body      string
+		code      string
+		reason    string
+		temporary bool
+	}{
+		{fmt.Sprintf(soap12Fault, "soap:Receiver", "Server was unable to process request"), "soap:Receiver", "Server was unable to process request", true},
+		{fmt.Sprintf(soap12Fault, "soap:Sender", "Invalid date"), "soap:Sender", "Invalid date", false},
+		{fmt.Sprintf(soap11Fault, "soap:Server", "Timeout"), "soap:Server", "Timeout", true},
+		{fmt.Sprintf(soap11Fault, "soap:Client", "Bad request"), "soap:Client", "Bad request", false},
+	}
+	for _, tt := range tests {
+		fault := parseSOAPFault([]byte(tt.body))
+		if fault == nil {
+			t.Errorf("no fault parsed from %s", tt.body)
+			continue
+		}
+		if fault.Code != tt.code || fault.Reason != tt.reason {
+			t.Errorf("fault = %q/%q, want %q/%q", fault.Code, fault.Reason, tt.code, tt.reason)
+		}
+		if fault.Temporary() != tt.temporary {
+			t.Errorf("%s Temporary() = %v, want %v", fault.Code, fault.Temporary(), tt.temporary)
+		}
+	}
+	if fault := parseSOAPFault([]byte("not xml")); fault != nil {
+		t.Errorf("fault %v parsed from garbage", fault)
+	}
+}
+
+func TestCentralBankCacheTTL(t *testing.T) {
+	srv, calls := flakyCBR(t, 0, http.StatusOK, "")
+	cb := newTestCentralBank(srv.URL)
+	cb.CacheTTL = time.Hour
+
+	for i := 0; i < 3; i++ {
+		if _, err := cb.KeyRate(context.Background()); err != nil {
+			t.Fatalf("KeyRate: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Fatalf("server called %d times within TTL, want 1", got)
+	}
+
+	// Истечение срока: запись в кэше становится просроченной
+	cb.mu.Lock()
+	for key, entry := range cb.cache {
+		entry.expiresAt = time.Now().Add(-time.Second)
+		cb.cache[key] = entry
+	}
+	cb.mu.Unlock()
+
+	if _, err := cb.KeyRate(context.Background()); err != nil {
+		t.Fatalf("KeyRate: %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("server called %d times after TTL expiry, want 2", got)
+	}
+}
+
+func TestCentralBankNoCache(t *testing.T) {
+	srv, calls := flakyCBR(t, 0, http.StatusOK, "")
+	cb := newTestCentralBank(srv.URL)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cb.KeyRate(context.Background()); err != nil {
+			t.Fatalf("KeyRate: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("server called %d times with CacheTTL 0, want 2", got)
+	}
+}
+
+func TestCentralBankCancel(t *testing.T) {
+	srv, calls := flakyCBR(t, 10, http.StatusServiceUnavailable, "")
+	cb := newTestCentralBank(srv.URL)
+	cb.Retries = 5
+	cb.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := cb.KeyRate(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("KeyRate error = %v, want context.DeadlineExceeded", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("server called %d times, want 1 before cancellation", got)
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"time"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AuthorizationRequest — запрос платёжной системы на авторизацию платежа по карте
+type AuthorizationRequest struct {
+	Number   string
+	Expiry   string // MM/YY
+	CVV      string
+	Amount   money.Money
+	Merchant string
+	MCC      string // код категории продавца
+	Channel  string // models.ChannelEcommerce или models.ChannelPOS
+}
+
+// CardProcessingService — авторизация платежей по картам: холды, списания, отмены
+type CardProcessingService struct {
+	Cards    *CardService
+	AuthRepo *repositories.CardAuthorizationRepository
+	HoldTTL  time.Duration
+}
+
+func NewCardProcessingService(cards *CardService, authRepo *repositories.CardAuthorizationRepository) *CardProcessingService {
+	return &CardProcessingService{
+		Cards:    cards,
+		AuthRepo: authRepo,
+		HoldTTL:  time.Duration(config.AppConfig.CardHoldTTLHours) * time.Hour,
+	}
+}
+
+// Authorize проверяет карту (номер по HMAC, срок действия, CVV по bcrypt-хешу, статус),
+// её ограничения (лимиты, MCC, канал, одноразовость) и ставит холд на сумму на счёте карты. Отказ возвращается авторизацией со статусом declined и причиной;
+// отказы по известной карте сохраняются. Какой из реквизитов не совпал, не сообщается.
+func (s *CardProcessingService) Authorize(ctx context.Context, req AuthorizationRequest) (*models.CardAuthorization, error) {
+	auth := &models.CardAuthorization{
+		Amount:         req.Amount,
+		CapturedAmount: money.Zero(req.Amount.Currency),
+		Merchant:       req.Merchant,
+		MCC:            req.MCC,
+		Channel:        req.Channel,
+	}
+
+	card, err := s.Cards.CardRepo.FindByHMAC(ctx, s.Cards.Keys.NumberHMAC(req.Number))
+	if errors.Is(err, repositories.ErrCardNotFound) {
+		auth.Status, auth.DeclineReason = models.AuthDeclined, models.DeclineInvalidCard
+		return auth, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	auth.CardID, auth.AccountID = card.ID, card.AccountID
+
+	secret, err := s.Cards.Keys.Open(card)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	switch {
+	case subtle.ConstantTimeCompare([]byte(secret.Expiry), []byte(req.Expiry)) != 1,
+		bcrypt.CompareHashAndPassword([]byte(card.CVVHash), []byte(req.CVV)) != nil:
+		auth.DeclineReason = models.DeclineInvalidCard
+	case card.Status == models.CardExpired || (card.ExpiresOn != nil && card.ExpiresOn.Before(utils.Date(now))):
+		auth.DeclineReason = models.DeclineCardExpired
+	case card.Status != models.CardActive:
+		auth.DeclineReason = models.DeclineCardInactive
+	}
+	if auth.DeclineReason != "" {
+		return auth, s.AuthRepo.Decline(ctx, auth)
+	}
+
+	controls, err := s.Cards.ControlsRepo.Find(ctx, card.ID)
+	if err != nil {
+		return nil, err
+	}
+	if auth.DeclineReason = controls.Violation(req.Amount, req.MCC, req.Channel); auth.DeclineReason != "" {
+		return auth, s.AuthRepo.Decline(ctx, auth)
+	}
+
+	if auth.AuthCode, err = utils.GenerateOTPCode(); err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(s.HoldTTL)
+	auth.ExpiresAt = &expiresAt
+
+	// Статус и ограничения проверяются повторно под блокировкой карты
+	err = s.AuthRepo.Approve(ctx, auth)
+	var violation *repositories.ControlViolation
+	switch {
+	case errors.Is(err, repositories.ErrCardInactive):
+		auth.DeclineReason = models.DeclineCardInactive
+	case errors.Is(err, repositories.ErrCardAccountMismatch):
+		auth.DeclineReason = models.DeclineInvalidCard
+	case errors.As(err, &violation):
+		auth.DeclineReason = violation.Reason
+	case errors.Is(err, repositories.ErrInsufficientFunds):
+		auth.DeclineReason = models.DeclineInsufficientFunds
+	case errors.Is(err, repositories.ErrCurrencyMismatch):
+		auth.DeclineReason = models.DeclineCurrencyMismatch
+	case err != nil:
+		return nil, err
+	default:
+		return auth, nil
+	}
+	auth.AuthCode, auth.ExpiresAt = "", nil
+	return auth, s.AuthRepo.Decline(ctx, auth)
+}
+
+// Charge — оплата в одно действие: авторизация с теми же проверками и сразу полное списание.
+// Если списать не удалось, холд отменяется.
+func (s *CardProcessingService) Charge(ctx context.Context, req AuthorizationRequest) (*models.CardAuthorization, error) {
+	auth, err := s.Authorize(ctx, req)
+	if err != nil || auth.Status != models.AuthApproved {
+		return auth, err
+	}
+	captured, err := s.AuthRepo.Capture(ctx, auth.ID, nil, time.Now())
+	if err != nil {
+		if _, reverseErr := s.AuthRepo.Reverse(context.WithoutCancel(ctx), auth.ID, time.Now()); reverseErr != nil {
+			log.Printf("Отмена авторизации %d после ошибки списания: %v\n", auth.ID, reverseErr)
+		}
+		return nil, err
+	}
+	return captured, nil
+}
+
+// Capture списывает по авторизации сумму amount в её валюте (пустая строка — всю сумму холда).
+// Частичное списание завершает авторизацию: остаток холда освобождается.
+func (s *CardProcessingService) Capture(ctx context.Context, id int, amount string) (*models.CardAuthorization, error) {
+	if amount == "" {
+		return s.AuthRepo.Capture(ctx, id, nil, time.Now())
+	}
+	auth, err := s.AuthRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := money.Parse(amount, auth.Amount.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return s.AuthRepo.Capture(ctx, id, &parsed, time.Now())
+}
+
+// Reverse отменяет авторизацию и освобождает холд
+func (s *CardProcessingService) Reverse(ctx context.Context, id int) (*models.CardAuthorization, error) {
+	return s.AuthRepo.Reverse(ctx, id, time.Now())
+}
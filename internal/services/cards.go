@@ -0,0 +1,440 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUnknownCardKey        = errors.New("card is encrypted with an unknown master key version")
+	ErrInvalidPassword       = errors.New("invalid password")
+	ErrInvalidCardTransition = errors.New("card status does not allow this operation")
+	ErrInvalidBlockReason    = errors.New("unknown block reason")
+	ErrInvalidCardControls   = errors.New("invalid card controls")
+)
+
+// cardTransitions — из каких статусов разрешён переход в статус-ключ
+var cardTransitions = map[string][]string{
+	models.CardFrozen:   {models.CardActive},
+	models.CardActive:   {models.CardFrozen},
+	models.CardBlocked:  {models.CardActive, models.CardFrozen},
+	models.CardReissued: {models.CardActive, models.CardFrozen, models.CardBlocked, models.CardExpired},
+}
+
+// canTransition — разрешён ли переход карты из from в to
+func canTransition(from, to string) bool {
+	for _, allowed := range cardTransitions[to] {
+		if allowed == from {
+			return true
+		}
+	}
+	return false
+}
+
+// CardSecret — открытые данные карты, которые хранятся только в зашифрованном виде
+type CardSecret struct {
+	Number string `json:"number"`
+	Expiry string `json:"expiry"`
+}
+
+// IssuedCard — новая карта с CVV, который показывается один раз при выпуске и нигде не хранится
+type IssuedCard struct {
+	Card *models.Card
+	CardSecret
+	CVV string
+}
+
+// CardKeyring — версии мастер-ключей карт. Новые данные шифруются активной версией,
+// старые версии нужны, чтобы расшифровать карты до перешифрования командой rotate-card-keys.
+type CardKeyring struct {
+	keys    map[int][]byte
+	active  int
+	hmacKey []byte
+}
+
+// NewCardKeyring читает мастер-ключи из CARD_MASTER_KEYS_FILE или CARD_MASTER_KEYS
+// (строки «версия:base64» — в файле по одной на строку, в переменной через запятую).
+// Активная версия — CARD_KEY_VERSION, по умолчанию наибольшая.
+func NewCardKeyring() (*CardKeyring, error) {
+	cfg := config.AppConfig
+	entries := strings.Split(cfg.CardMasterKeys, ",")
+	if cfg.CardMasterKeysFile != "" {
+		data, err := os.ReadFile(cfg.CardMasterKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("card master keys: %w", err)
+		}
+		entries = strings.Split(string(data), "\n")
+	}
+
+	keys, err := ParseCardKeys(entries)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		if !cfg.DevMode {
+			return nil, errors.New("CARD_MASTER_KEYS or CARD_MASTER_KEYS_FILE must be set (set DEV_MODE=true to derive a key for development)")
+		}
+		log.Println("CARD_MASTER_KEYS не заданы — мастер-ключ карт выведен из секрета приложения (DEV_MODE)")
+		sum := sha256.Sum256([]byte("card-master-key:" + cfg.JWTSecret))
+		keys = map[int][]byte{1: sum[:]}
+	}
+
+	active := cfg.CardKeyVersion
+	if active == 0 {
+		for version := range keys {
+			if version > active {
+				active = version
+			}
+		}
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("card master key version %d is not configured", active)
+	}
+
+	hmacKey := []byte(cfg.CardHMACKey)
+	if len(hmacKey) == 0 {
+		if !cfg.DevMode {
+			return nil, errors.New("CARD_HMAC_KEY must be set (set DEV_MODE=true to derive a key for development)")
+		}
+		log.Println("CARD_HMAC_KEY не задан — ключ HMAC номеров карт выведен из секрета приложения (DEV_MODE)")
+		sum := sha256.Sum256([]byte("card-hmac-key:" + cfg.JWTSecret))
+		hmacKey = sum[:]
+	}
+	return &CardKeyring{keys: keys, active: active, hmacKey: hmacKey}, nil
+}
+
+// ParseCardKeys разбирает строки «версия:base64». Пустые строки и строки с # пропускаются,
+// ключ должен быть ровно 32 байта (AES-256).
+func ParseCardKeys(entries []string) (map[int][]byte, error) {
+	keys := make(map[int][]byte)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		versionStr, encoded, ok := strings.Cut(entry, ":")
+		version, err := strconv.Atoi(strings.TrimSpace(versionStr))
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("card master key %q: expected <version>:<base64>", versionStr)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("card master key version %d: expected 32 bytes in base64", version)
+		}
+		if _, exists := keys[version]; exists {
+			return nil, fmt.Errorf("card master key version %d is duplicated", version)
+		}
+		keys[version] = key
+	}
+	return keys, nil
+}
+
+// ActiveVersion — версия мастер-ключа, которой шифруются новые данные
+func (k *CardKeyring) ActiveVersion() int {
+	return k.active
+}
+
+// Seal шифрует данные карты новым ключом данных под активным мастер-ключом
+// и заполняет зашифрованные поля, last4 и HMAC номера
+func (k *CardKeyring) Seal(card *models.Card, secret CardSecret) error {
+	plaintext, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+	ciphertext, wrappedKey, err := utils.SealEnvelope(k.keys[k.active], plaintext)
+	if err != nil {
+		return err
+	}
+	card.EncryptedData = ciphertext
+	card.WrappedKey = wrappedKey
+	card.KeyVersion = k.active
+	card.First6 = secret.Number[:6]
+	card.Last4 = secret.Number[len(secret.Number)-4:]
+	card.Brand = utils.CardBrand(secret.Number)
+	card.HMAC = k.NumberHMAC(secret.Number)
+	return nil
+}
+
+// Open расшифровывает данные карты. Карты, выпущенные до шифрования, возвращаются как есть.
+func (k *CardKeyring) Open(card *models.Card) (CardSecret, error) {
+	if card.KeyVersion == 0 {
+		return CardSecret{Number: card.LegacyNumber, Expiry: card.LegacyExpiry}, nil
+	}
+	kek, ok := k.keys[card.KeyVersion]
+	if !ok {
+		return CardSecret{}, ErrUnknownCardKey
+	}
+	plaintext, err := utils.OpenEnvelope(kek, card.EncryptedData, card.WrappedKey)
+	if err != nil {
+		return CardSecret{}, err
+	}
+	var secret CardSecret
+	err = json.Unmarshal(plaintext, &secret)
+	return secret, err
+}
+
+// NumberHMAC — HMAC номера карты для поиска по номеру без расшифровки
+func (k *CardKeyring) NumberHMAC(number string) string {
+	return utils.ComputeHMAC(number, k.hmacKey)
+}
+
+// RevealRequest — запрос полного номера карты с повторно введённым паролем владельца
+type RevealRequest struct {
+	Token     string
+	UserID    int
+	Password  string
+	IP        string
+	UserAgent string
+}
+
+type CardService struct {
+	CardRepo     *repositories.CardRepository
+	UserRepo     *repositories.UserRepository
+	ControlsRepo *repositories.CardControlRepository
+	Keys         *CardKeyring
+}
+
+func NewCardService(
+	repo *repositories.CardRepository,
+	userRepo *repositories.UserRepository,
+	controlsRepo *repositories.CardControlRepository,
+) (*CardService, error) {
+	keys, err := NewCardKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return &CardService{CardRepo: repo, UserRepo: userRepo, ControlsRepo: controlsRepo, Keys: keys}, nil
+}
+
+// Issue выпускает карту к счёту пользователя (чужой счёт — repositories.ErrAccountNotFound):
+// номер и срок действия сохраняются зашифрованными, от CVV остаётся только bcrypt-хеш
+func (s *CardService) Issue(ctx context.Context, userID, accountID int) (*IssuedCard, error) {
+	issued, err := s.newCard(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.CardRepo.Create(ctx, issued.Card); err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// newCard генерирует номер, срок действия, CVV и токен новой карты и шифрует её данные
+func (s *CardService) newCard(userID, accountID int) (*IssuedCard, error) {
+	secret := CardSecret{Number: utils.GenerateCardNumber(), Expiry: utils.GenerateExpiryDate()}
+	expiresOn, err := utils.ExpiryEnd(secret.Expiry)
+	if err != nil {
+		return nil, err
+	}
+	cvv := utils.GenerateCVV()
+	cvvHash, err := utils.HashCVV(cvv)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateCardToken()
+	if err != nil {
+		return nil, err
+	}
+
+	card := &models.Card{
+		Token:     token,
+		UserID:    userID,
+		AccountID: accountID,
+		CVVHash:   cvvHash,
+		ExpiresOn: &expiresOn,
+	}
+	if err := s.Keys.Seal(card, secret); err != nil {
+		return nil, err
+	}
+	return &IssuedCard{Card: card, CardSecret: secret, CVV: cvv}, nil
+}
+
+// Freeze временно замораживает активную карту
+func (s *CardService) Freeze(ctx context.Context, token string, userID int) (*models.Card, error) {
+	return s.transition(ctx, token, userID, models.CardFrozen, "")
+}
+
+// Unfreeze возвращает замороженную карту в работу
+func (s *CardService) Unfreeze(ctx context.Context, token string, userID int) (*models.Card, error) {
+	return s.transition(ctx, token, userID, models.CardActive, "")
+}
+
+// Block навсегда блокирует активную или замороженную карту с причиной (models.BlockLost и др.)
+func (s *CardService) Block(ctx context.Context, token string, userID int, reason string) (*models.Card, error) {
+	if !models.IsBlockReason(reason) {
+		return nil, ErrInvalidBlockReason
+	}
+	return s.transition(ctx, token, userID, models.CardBlocked, reason)
+}
+
+func (s *CardService) transition(ctx context.Context, token string, userID int, status, reason string) (*models.Card, error) {
+	card, err := s.CardRepo.FindByToken(ctx, token, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(card.Status, status) {
+		return nil, ErrInvalidCardTransition
+	}
+	if err := s.CardRepo.SetStatus(ctx, card, status, reason); err != nil {
+		if errors.Is(err, repositories.ErrCardStatusChanged) {
+			return nil, ErrInvalidCardTransition
+		}
+		return nil, err
+	}
+	return card, nil
+}
+
+// Reissue выпускает к тому же счёту новую карту с новыми номером, сроком и CVV вместо карты token.
+// Старая карта переводится в reissued и больше не работает, новая ссылается на неё.
+func (s *CardService) Reissue(ctx context.Context, token string, userID int) (*IssuedCard, error) {
+	old, err := s.CardRepo.FindByToken(ctx, token, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(old.Status, models.CardReissued) {
+		return nil, ErrInvalidCardTransition
+	}
+	issued, err := s.newCard(userID, old.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.CardRepo.Reissue(ctx, old, issued.Card); err != nil {
+		if errors.Is(err, repositories.ErrCardStatusChanged) {
+			return nil, ErrInvalidCardTransition
+		}
+		return nil, err
+	}
+	return issued, nil
+}
+
+// Reveal расшифровывает номер и срок действия карты пользователя после проверки его пароля.
+// Каждая попытка по существующей карте пишется в card_reveal_audit; если запись не удалась,
+// данные не отдаются.
+func (s *CardService) Reveal(ctx context.Context, req RevealRequest) (CardSecret, error) {
+	card, err := s.CardRepo.FindByToken(ctx, req.Token, req.UserID)
+	if err != nil {
+		return CardSecret{}, err
+	}
+	audit := &models.CardRevealAudit{CardID: card.ID, UserID: req.UserID, IP: req.IP, UserAgent: req.UserAgent}
+
+	user, err := s.UserRepo.FindByID(ctx, req.UserID)
+	if err != nil {
+		return CardSecret{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		audit.Reason = "invalid_password"
+		if err := s.CardRepo.RecordReveal(ctx, audit); err != nil {
+			return CardSecret{}, err
+		}
+		return CardSecret{}, ErrInvalidPassword
+	}
+
+	secret, err := s.Keys.Open(card)
+	if err != nil {
+		audit.Reason = "decryption_failed"
+		if auditErr := s.CardRepo.RecordReveal(ctx, audit); auditErr != nil {
+			log.Printf("Журнал просмотров карты %d: %v\n", card.ID, auditErr)
+		}
+		return CardSecret{}, err
+	}
+	audit.Success = true
+	if err := s.CardRepo.RecordReveal(ctx, audit); err != nil {
+		return CardSecret{}, err
+	}
+	return secret, nil
+}
+
+// RotateKeys перешифровывает под активным мастер-ключом карты со старой версией ключа
+// и карты с открытыми данными; при all — все карты (например, после смены CARD_HMAC_KEY).
+// Каждая карта получает новый ключ данных и пересчитанный HMAC. Возвращает число
+// перешифрованных карт; прерывается между картами при отмене ctx.
+func (s *CardService) RotateKeys(ctx context.Context, all bool) (int, error) {
+	version := s.Keys.ActiveVersion()
+	if all {
+		version = -1 // не совпадает ни с одной версией
+	}
+	cards, err := s.CardRepo.FindNotUnderKey(ctx, version)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, card := range cards {
+		if err := ctx.Err(); err != nil {
+			return rotated, err
+		}
+		secret, err := s.Keys.Open(card)
+		if err != nil {
+			return rotated, fmt.Errorf("card %d: %w", card.ID, err)
+		}
+		fromVersion := card.KeyVersion
+		if err := s.Keys.Seal(card, secret); err != nil {
+			return rotated, fmt.Errorf("card %d: %w", card.ID, err)
+		}
+		ok, err := s.CardRepo.Reencrypt(ctx, card, fromVersion)
+		if err != nil {
+			return rotated, fmt.Errorf("card %d: %w", card.ID, err)
+		}
+		if ok {
+			rotated++
+		}
+	}
+	return rotated, nil
+}
+
+// Controls — ограничения карты пользователя
+func (s *CardService) Controls(ctx context.Context, token string, userID int) (*models.CardControls, error) {
+	card, err := s.CardRepo.FindByToken(ctx, token, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.ControlsRepo.Find(ctx, card.ID)
+}
+
+// SetControls заменяет ограничения карты пользователя. Лимиты должны быть положительными
+// и в валюте счёта карты, MCC — из четырёх цифр.
+func (s *CardService) SetControls(ctx context.Context, token string, userID int, controls *models.CardControls) error {
+	card, err := s.CardRepo.FindByToken(ctx, token, userID)
+	if err != nil {
+		return err
+	}
+	current, err := s.ControlsRepo.Find(ctx, card.ID)
+	if err != nil {
+		return err
+	}
+	for _, limit := range []*money.Money{controls.TransactionLimit, controls.DailyLimit, controls.MonthlyLimit} {
+		if limit == nil {
+			continue
+		}
+		if !limit.IsPositive() {
+			return fmt.Errorf("%w: limits must be positive", ErrInvalidCardControls)
+		}
+		if limit.Currency != current.Currency {
+			return fmt.Errorf("%w: limits must be in account currency", ErrInvalidCardControls)
+		}
+	}
+	for _, mcc := range append(append([]string{}, controls.AllowedMCCs...), controls.BlockedMCCs...) {
+		if !utils.IsMCC(mcc) {
+			return fmt.Errorf("%w: MCC %q must be 4 digits", ErrInvalidCardControls, mcc)
+		}
+	}
+	controls.CardID, controls.Currency = card.ID, current.Currency
+	return s.ControlsRepo.Save(ctx, controls)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/events"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+)
+
+// Очерёдность погашения по умолчанию: неустойка → проценты → основной долг
+var DefaultWaterfall = []string{models.ComponentPenalty, models.ComponentInterest, models.ComponentPrincipal}
+
+// CollectionService списывает просроченные платежи по кредитам с доступного остатка счёта,
+// в том числе частично
+type CollectionService struct {
+	ScheduleRepo *repositories.PaymentScheduleRepository
+	Waterfall    []string
+}
+
+func NewCollectionService(scheduleRepo *repositories.PaymentScheduleRepository) *CollectionService {
+	return &CollectionService{
+		ScheduleRepo: scheduleRepo,
+		Waterfall:    ParseWaterfall(config.AppConfig.CollectionWaterfall),
+	}
+}
+
+// ParseWaterfall разбирает очерёдность вида "penalty,interest,principal". Каждая часть должна
+// встретиться ровно один раз, иначе используется порядок по умолчанию.
+func ParseWaterfall(s string) []string {
+	var order []string
+	seen := map[string]bool{}
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		switch c {
+		case models.ComponentPenalty, models.ComponentInterest, models.ComponentPrincipal:
+		default:
+			log.Printf("COLLECTION_WATERFALL: неизвестная часть платежа %q, используется %v", c, DefaultWaterfall)
+			return DefaultWaterfall
+		}
+		if seen[c] {
+			log.Printf("COLLECTION_WATERFALL: %q указана дважды, используется %v", c, DefaultWaterfall)
+			return DefaultWaterfall
+		}
+		seen[c] = true
+		order = append(order, c)
+	}
+	if len(order) != len(DefaultWaterfall) {
+		log.Printf("COLLECTION_WATERFALL: нужны все части платежа, используется %v", DefaultWaterfall)
+		return DefaultWaterfall
+	}
+	return order
+}
+
+// Collect списывает по одному платежу всё, что позволяет баланс
+func (s *CollectionService) Collect(ctx context.Context, paymentID int) (*repositories.Collection, error) {
+	return s.ScheduleRepo.Collect(ctx, paymentID, s.Waterfall)
+}
+
+// CollectAccount списывает просроченные платежи кредитов счёта, начиная со старых, пока хватает
+// баланса. Списание идёт тем же Collect, что и у шедулера: платёж, который уже списывает
+// другой процесс, пропускается, поэтому дважды он не спишется.
+func (s *CollectionService) CollectAccount(ctx context.Context, accountID int) (money.Money, error) {
+	ids, err := s.ScheduleRepo.FindOverdue(ctx, accountID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	var total money.Money
+	for _, id := range ids {
+		c, err := s.Collect(ctx, id)
+		if err != nil {
+			return total, err
+		}
+		if c.Skipped {
+			continue
+		}
+		if c.Collected.IsPositive() {
+			if total.Currency == "" {
+				total = c.Collected
+			} else {
+				total = total.Add(c.Collected)
+			}
+		}
+		if !c.Paid {
+			// Баланс исчерпан — более поздние платежи ждут следующего пополнения
+			break
+		}
+	}
+	if total.IsPositive() {
+		log.Printf("Счёт #%d: в счёт просроченных платежей списано %s %s", accountID, total, total.Currency)
+	}
+	return total, nil
+}
+
+// Subscribe подписывает списание на зачисления: просроченные платежи гасятся сразу после
+// пополнения или входящего перевода, не дожидаясь очередного запуска шедулера
+func (s *CollectionService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.AccountFundedEvent, func(ctx context.Context, e events.Event) {
+		funded := e.(events.AccountFunded)
+		if _, err := s.CollectAccount(ctx, funded.AccountID); err != nil {
+			log.Printf("Счёт #%d: ошибка списания просроченных платежей после зачисления: %v", funded.AccountID, err)
+		}
+	})
+}
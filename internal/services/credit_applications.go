@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"time"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+)
+
+var (
+	ErrCreditCurrency       = errors.New("credits are issued only to RUB accounts")
+	ErrUnknownRepaymentType = errors.New("unknown repayment type")
+)
+
+// CreditApplicationService ведёт заявку на кредит: скоринг по истории операций
+// и текущей кредитной нагрузке, решение и выдачу
+type CreditApplicationService struct {
+	AppRepo      *repositories.CreditApplicationRepository
+	AccountRepo  *repositories.AccountRepository
+	CreditRepo   *repositories.CreditRepository
+	TxRepo       *repositories.TransactionRepository
+	HolidayRepo  *repositories.HolidayRepository
+	Pricing      *CreditPricingService
+	FX           *ExchangeService
+	MaxDTI       float64
+	IncomeMonths int
+}
+
+// NewCreditApplicationService — сервис заявок с пределами скоринга из конфигурации;
+// CREDIT_INCOME_MONTHS меньше 1 или неположительный CREDIT_MAX_DTI — ошибка настройки
+func NewCreditApplicationService(
+	appRepo *repositories.CreditApplicationRepository,
+	accountRepo *repositories.AccountRepository,
+	creditRepo *repositories.CreditRepository,
+	txRepo *repositories.TransactionRepository,
+	holidayRepo *repositories.HolidayRepository,
+	pricing *CreditPricingService,
+	fx *ExchangeService,
+) (*CreditApplicationService, error) {
+	// Скоринг делит доход на число месяцев и сравнивает нагрузку с пределом — без них он не работает
+	if config.AppConfig.CreditIncomeMonths < 1 {
+		return nil, fmt.Errorf("CREDIT_INCOME_MONTHS must be at least 1, got %d", config.AppConfig.CreditIncomeMonths)
+	}
+	if config.AppConfig.CreditMaxDTI <= 0 {
+		return nil, fmt.Errorf("CREDIT_MAX_DTI must be positive, got %v", config.AppConfig.CreditMaxDTI)
+	}
+	return &CreditApplicationService{
+		AppRepo:      appRepo,
+		AccountRepo:  accountRepo,
+		CreditRepo:   creditRepo,
+		TxRepo:       txRepo,
+		HolidayRepo:  holidayRepo,
+		Pricing:      pricing,
+		FX:           fx,
+		MaxDTI:       config.AppConfig.CreditMaxDTI,
+		IncomeMonths: config.AppConfig.CreditIncomeMonths,
+	}, nil
+}
+
+// Submit принимает заявку и сразу проводит её по процессу:
+// submitted → scored → approved → disbursed либо scored → rejected
+func (s *CreditApplicationService) Submit(ctx context.Context, userID, accountID int, amount money.Money, termMonths int, product, repaymentType string) (*models.CreditApplication, error) {
+	if repaymentType != models.RepaymentAnnuity && repaymentType != models.RepaymentDifferentiated {
+		return nil, ErrUnknownRepaymentType
+	}
+	currency, err := s.AccountRepo.GetCurrency(ctx, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if currency != BaseCurrency || amount.Currency != BaseCurrency {
+		return nil, ErrCreditCurrency
+	}
+
+	offer, err := s.Pricing.Offer(ctx, product, amount, termMonths)
+	if err != nil {
+		return nil, err
+	}
+	// Даты платежей — в тот же день месяца, что и выдача, с переносом с нерабочих дней
+	start := utils.Date(time.Now())
+	calendar, err := s.HolidayRepo.Calendar(ctx, start, utils.AddMonths(start, termMonths+1))
+	if err != nil {
+		return nil, err
+	}
+	dueDates := utils.DueDates(start, termMonths, calendar)
+	installments := utils.DatedSchedule(repaymentType, amount, offer.AnnualRate, offer.Product.DayCount, start, dueDates)
+
+	app := &models.CreditApplication{
+		UserID:         userID,
+		AccountID:      accountID,
+		ProductID:      offer.Product.ID,
+		Amount:         amount,
+		TermMonths:     termMonths,
+		RepaymentType:  repaymentType,
+		AnnualRate:     offer.AnnualRate,
+		KeyRate:        offer.KeyRate,
+		Spread:         offer.Spread,
+		MonthlyPayment: installments[0].Payment, // для дифференцированного — наибольший, первый платёж
+	}
+	if err := s.AppRepo.Create(ctx, app); err != nil {
+		return nil, err
+	}
+
+	if err := s.score(ctx, app); err != nil {
+		return app, err
+	}
+
+	status, reason := s.decide(app)
+	if err := s.AppRepo.Decide(ctx, app, status, reason); err != nil {
+		return app, err
+	}
+	if status == models.ApplicationRejected {
+		return app, nil
+	}
+
+	return app, s.disburse(ctx, app, offer, installments)
+}
+
+// score: средний месячный доход за IncomeMonths, текущий долг и платежи по кредитам → DTI
+func (s *CreditApplicationService) score(ctx context.Context, app *models.CreditApplication) error {
+	since := time.Now().AddDate(0, -s.IncomeMonths, 0)
+	incomes, err := s.TxRepo.IncomeSince(ctx, app.UserID, since)
+	if err != nil {
+		return err
+	}
+	total := money.Zero(BaseCurrency)
+	for _, income := range incomes {
+		converted, err := s.FX.ToBase(ctx, income)
+		if err != nil {
+			// Поступления без актуального курса в доход не засчитываются
+			log.Printf("Заявка #%d: доход в %s не учтён: %v", app.ID, income.Currency, err)
+			continue
+		}
+		total = total.Add(converted)
+	}
+
+	existing, err := s.CreditRepo.GetActiveCreditLoad(ctx, app.UserID)
+	if err != nil {
+		return err
+	}
+	payments, err := s.CreditRepo.GetActiveMonthlyPayments(ctx, app.UserID)
+	if err != nil {
+		return err
+	}
+
+	app.MonthlyIncome = total.MulRat(big.NewRat(1, int64(s.IncomeMonths)), money.HalfUp)
+	app.ExistingDebt = existing
+	if app.MonthlyIncome.IsPositive() {
+		// (платежи по текущим кредитам + платёж по новому) / месячный доход
+		monthly := payments.Add(app.MonthlyPayment).Rat()
+		dti, _ := new(big.Rat).Quo(monthly, app.MonthlyIncome.Rat()).Float64()
+		app.DTI = math.Round(dti*10000) / 10000
+	}
+
+	return s.AppRepo.SaveScoring(ctx, app)
+}
+
+func (s *CreditApplicationService) decide(app *models.CreditApplication) (string, string) {
+	if !app.MonthlyIncome.IsPositive() {
+		return models.ApplicationRejected, fmt.Sprintf("no income in the last %d months", s.IncomeMonths)
+	}
+	if app.DTI > s.MaxDTI {
+		return models.ApplicationRejected, fmt.Sprintf("debt-to-income %.2f exceeds limit %.2f", app.DTI, s.MaxDTI)
+	}
+	if !app.MonthlyPayment.LessThan(app.MonthlyIncome) {
+		return models.ApplicationRejected, "monthly payment is not lower than monthly income"
+	}
+	return models.ApplicationApproved, ""
+}
+
+func (s *CreditApplicationService) disburse(ctx context.Context, app *models.CreditApplication, offer *CreditOffer, installments []utils.Installment) error {
+	credit := &models.Credit{
+		UserID:         app.UserID,
+		AccountID:      app.AccountID,
+		Amount:         app.Amount,
+		TermMonths:     app.TermMonths,
+		AnnualRate:     app.AnnualRate,
+		MonthlyPayment: app.MonthlyPayment,
+		ProductID:      &offer.Product.ID,
+		RateType:       offer.Product.RateType,
+		RepaymentType:  app.RepaymentType,
+		DayCount:       offer.Product.DayCount,
+		Spread:         app.Spread,
+		KeyRate:        app.KeyRate,
+	}
+
+	schedule := make([]*models.PaymentSchedule, 0, len(installments))
+	for _, inst := range installments {
+		schedule = append(schedule, &models.PaymentSchedule{
+			DueDate:   inst.DueDate,
+			Amount:    inst.Payment,
+			Principal: inst.Principal,
+			Interest:  inst.Interest,
+			Remaining: inst.Remaining,
+			Penalty:   money.Zero(app.Amount.Currency),
+		})
+	}
+
+	return s.AppRepo.Disburse(ctx, app, credit, schedule)
+}
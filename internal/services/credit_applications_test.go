@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+
+	"gobankapi/internal/config"
+)
+
+func TestCreditApplicationServiceScoringConfig(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	tests := []struct {
+		incomeMonths int
+		maxDTI       float64
+		ok           bool
+	}{
+		{3, 0.5, true},
+		{1, 0.1, true},
+		{0, 0.5, false},
+		{-1, 0.5, false},
+		{3, 0, false},
+		{3, -0.5, false},
+	}
+	for _, tt := range tests {
+		config.AppConfig = &config.Config{CreditIncomeMonths: tt.incomeMonths, CreditMaxDTI: tt.maxDTI}
+		s, err := NewCreditApplicationService(nil, nil, nil, nil, nil, nil, nil)
+		if tt.ok && (err != nil || s.IncomeMonths != tt.incomeMonths) {
+			t.Errorf("months %d, DTI %v: err = %v, want service", tt.incomeMonths, tt.maxDTI, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("months %d, DTI %v: want error", tt.incomeMonths, tt.maxDTI)
+		}
+	}
+}
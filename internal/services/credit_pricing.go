@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+)
+
+var (
+	ErrCreditProductNotFound = errors.New("credit product not found")
+	ErrCreditTermsOutOfRange = errors.New("credit amount or term is outside product limits")
+)
+
+// CreditOffer — ставка, которую банк предлагает по продукту сейчас
+type CreditOffer struct {
+	Product    *models.CreditProduct `json:"product"`
+	KeyRate    float64               `json:"key_rate"`
+	Spread     float64               `json:"spread"` // маржа банка + спред продукта, п.п.
+	AnnualRate float64               `json:"annual_rate"`
+}
+
+// CreditPricingService считает ставки по кредитам от ключевой ставки ЦБ
+// и пересчитывает кредиты с плавающей ставкой при её изменении
+type CreditPricingService struct {
+	ProductRepo *repositories.CreditProductRepository
+	CreditRepo  *repositories.CreditRepository
+	UserRepo    *repositories.UserRepository
+	Bank        CentralBank
+	Mailer      *Mailer
+}
+
+func NewCreditPricingService(
+	productRepo *repositories.CreditProductRepository,
+	creditRepo *repositories.CreditRepository,
+	userRepo *repositories.UserRepository,
+	bank CentralBank,
+	mailer *Mailer,
+) *CreditPricingService {
+	return &CreditPricingService{
+		ProductRepo: productRepo,
+		CreditRepo:  creditRepo,
+		UserRepo:    userRepo,
+		Bank:        bank,
+		Mailer:      mailer,
+	}
+}
+
+// Offers — предложения по всем активным продуктам
+func (s *CreditPricingService) Offers(ctx context.Context) ([]*CreditOffer, error) {
+	products, err := s.ProductRepo.FindActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keyRate, lendingRate, err := s.rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	offers := make([]*CreditOffer, 0, len(products))
+	for _, p := range products {
+		offers = append(offers, newOffer(p, keyRate, lendingRate))
+	}
+	return offers, nil
+}
+
+// Offer — предложение по продукту с проверкой суммы и срока по его лимитам
+func (s *CreditPricingService) Offer(ctx context.Context, code string, amount money.Money, termMonths int) (*CreditOffer, error) {
+	product, err := s.ProductRepo.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, ErrCreditProductNotFound
+	}
+	if amount.LessThan(product.MinAmount) || amount.GreaterThan(product.MaxAmount) ||
+		termMonths < product.MinTermMonths || termMonths > product.MaxTermMonths {
+		return nil, ErrCreditTermsOutOfRange
+	}
+
+	keyRate, lendingRate, err := s.rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newOffer(product, keyRate, lendingRate), nil
+}
+
+// RepriceFloating пересчитывает графики кредитов с плавающей ставкой, рассчитанных
+// по прежней ключевой ставке, и сообщает клиентам новый ежемесячный платёж
+func (s *CreditPricingService) RepriceFloating(ctx context.Context) error {
+	keyRate, err := s.Bank.KeyRate(ctx)
+	if err != nil {
+		return err
+	}
+	credits, err := s.CreditRepo.FindFloatingForRepricing(ctx, keyRate)
+	if err != nil {
+		return err
+	}
+
+	for _, credit := range credits {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		oldRate := credit.AnnualRate
+		newRate := roundRate(keyRate + credit.Spread)
+		changed, err := s.CreditRepo.Reprice(ctx, credit, keyRate, newRate)
+		if err != nil {
+			log.Printf("Ошибка пересчёта кредита #%d: %v", credit.ID, err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		log.Printf("Кредит #%d: ставка %.2f%% → %.2f%%, платёж %s", credit.ID, oldRate, newRate, credit.MonthlyPayment)
+
+		user, err := s.UserRepo.FindByID(ctx, credit.UserID)
+		if err != nil || user == nil {
+			log.Printf("Кредит #%d: не удалось найти заёмщика для уведомления: %v", credit.ID, err)
+			continue
+		}
+		if err := s.Mailer.SendCreditRateChange(ctx, user.Email, credit.ID, oldRate, newRate, credit.MonthlyPayment); err != nil {
+			log.Printf("Кредит #%d: ошибка отправки уведомления: %v", credit.ID, err)
+		}
+	}
+	return nil
+}
+
+// rates — ключевая ставка и базовая ставка банка (ключевая + маржа)
+func (s *CreditPricingService) rates(ctx context.Context) (float64, float64, error) {
+	keyRate, err := s.Bank.KeyRate(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ключевая ставка недоступна: %w", err)
+	}
+	lendingRate, err := s.Bank.LendingRate(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ключевая ставка недоступна: %w", err)
+	}
+	return keyRate, lendingRate, nil
+}
+
+func newOffer(p *models.CreditProduct, keyRate, lendingRate float64) *CreditOffer {
+	rate := roundRate(lendingRate + p.Spread)
+	return &CreditOffer{
+		Product:    p,
+		KeyRate:    keyRate,
+		Spread:     roundRate(rate - keyRate),
+		AnnualRate: rate,
+	}
+}
+
+// Ставки хранятся в NUMERIC(5,2)
+func roundRate(rate float64) float64 {
+	return math.Round(rate*100) / 100
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+)
+
+// Курсы в таблице — к рублю; кросс-курс округляется до этого числа знаков
+const (
+	BaseCurrency      = "RUB"
+	crossRateDecimals = 10
+)
+
+var ErrRateUnavailable = errors.New("no fresh exchange rate for currency pair")
+
+// ExchangeService даёт курсы конвертации по сохранённым официальным курсам
+type ExchangeService struct {
+	RateRepo *repositories.ExchangeRateRepository
+	MaxAge   time.Duration
+}
+
+func NewExchangeService(rateRepo *repositories.ExchangeRateRepository) *ExchangeService {
+	return &ExchangeService{
+		RateRepo: rateRepo,
+		MaxAge:   time.Duration(config.AppConfig.FXRateMaxAgeHours) * time.Hour,
+	}
+}
+
+// Quote — курс from → to: сколько единиц to дают за единицу from.
+// Курс старше MaxAge не используется: операция отклоняется с ErrRateUnavailable.
+func (s *ExchangeService) Quote(ctx context.Context, from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	fromRub, err := s.rubPerUnit(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toRub, err := s.rubPerUnit(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	cross := new(big.Rat).Quo(fromRub, toRub)
+	rounded, _ := new(big.Rat).SetString(cross.FloatString(crossRateDecimals))
+	if rounded.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %s/%s", ErrRateUnavailable, from, to)
+	}
+	return rounded, nil
+}
+
+// ToBase переводит сумму в рубли (для лимитов и порогов)
+func (s *ExchangeService) ToBase(ctx context.Context, amount money.Money) (money.Money, error) {
+	rate, err := s.Quote(ctx, amount.Currency, BaseCurrency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return amount.Convert(rate, BaseCurrency, money.HalfUp), nil
+}
+
+func (s *ExchangeService) rubPerUnit(ctx context.Context, currency string) (*big.Rat, error) {
+	if currency == BaseCurrency {
+		return big.NewRat(1, 1), nil
+	}
+	rate, err := s.RateRepo.Latest(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	if rate == nil || time.Since(rate.RateDate) > s.MaxAge {
+		return nil, fmt.Errorf("%w: %s", ErrRateUnavailable, currency)
+	}
+	return rate.PerUnit()
+}
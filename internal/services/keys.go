@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Минимальный интервал перечитывания ключей из БД при встрече незнакомого kid
+const keyReloadCooldown = 30 * time.Second
+
+// JWKSMaxAge — сколько клиенты кэшируют /.well-known/jwks.json
+const JWKSMaxAge = 5 * time.Minute
+
+type signingKey struct {
+	kid       string
+	private   crypto.Signer
+	public    crypto.PublicKey
+	notBefore time.Time
+	notAfter  time.Time
+	retireAt  time.Time
+}
+
+// KeyManager хранит ключи подписи JWT, ротирует их и отдаёт открытые ключи в JWKS.
+// Ключи лежат в БД, поэтому все экземпляры сервиса подписывают и проверяют одним набором.
+type KeyManager struct {
+	Repo     *repositories.JWTKeyRepository
+	Alg      string
+	Rotation time.Duration
+	Overlap  time.Duration
+	// За сколько до конца подписи текущим ключом создаётся и публикуется следующий:
+	// кэш JWKS у клиентов и перечитывание ключей другими экземплярами успевают обновиться
+	Lead time.Duration
+
+	method     jwt.SigningMethod
+	encKey     []byte
+	hmacSecret []byte
+
+	mu         sync.RWMutex
+	keys       []*signingKey // новые — первыми
+	lastReload time.Time
+}
+
+func NewKeyManager(repo *repositories.JWTKeyRepository) (*KeyManager, error) {
+	m := &KeyManager{
+		Repo:     repo,
+		Alg:      config.AppConfig.JWTAlgorithm,
+		Rotation: time.Duration(config.AppConfig.JWTKeyRotationHours) * time.Hour,
+		Overlap:  time.Duration(config.AppConfig.JWTKeyOverlapHours) * time.Hour,
+	}
+
+	switch m.Alg {
+	case "RS256":
+		m.method = jwt.SigningMethodRS256
+	case "EdDSA":
+		m.method = jwt.SigningMethodEdDSA
+	case "HS256":
+		// Режим совместимости: общий секрет, без ротации и без публикации в JWKS
+		m.method = jwt.SigningMethodHS256
+		m.hmacSecret = []byte(config.AppConfig.JWTSecret)
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q", m.Alg)
+	}
+
+	// Старый ключ должен приниматься не меньше срока жизни выданных им access-токенов
+	accessTTL := time.Duration(config.AppConfig.AccessTokenTTLMinutes) * time.Minute
+	if m.Overlap < accessTTL {
+		m.Overlap = accessTTL
+	}
+
+	refresh := time.Duration(config.AppConfig.JWTKeyRefreshMinutes) * time.Minute
+	m.Lead = JWKSMaxAge + refresh + keyReloadCooldown
+	if m.Lead >= m.Rotation {
+		return nil, fmt.Errorf("JWT_KEY_ROTATION_HOURS must exceed key publication lead time %s", m.Lead)
+	}
+
+	encKey, err := encryptionKey("JWT_KEY_ENCRYPTION_KEY", config.AppConfig.JWTKeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	m.encKey = encKey
+	return m, nil
+}
+
+// Load читает ключи из БД и создаёт первый ключ, если действующего нет
+func (m *KeyManager) Load(ctx context.Context) error {
+	return m.RotateIfDue(ctx)
+}
+
+// RotateIfDue перечитывает ключи (их мог ротировать другой экземпляр) и заранее, за Lead до конца
+// подписи последним ключом, создаёт следующий: он сразу публикуется в JWKS, а подписывать начинает,
+// когда предыдущий перестаёт. Если подписывающего ключа нет (первый запуск или долгий простой),
+// новый ключ подписывает сразу.
+func (m *KeyManager) RotateIfDue(ctx context.Context) error {
+	if m.hmacSecret != nil {
+		return nil
+	}
+	if err := m.reload(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	start := now
+	if _, err := m.current(); err == nil {
+		last := m.latest()
+		if last.notAfter.Sub(now) > m.Lead {
+			return nil
+		}
+		start = last.notAfter
+	}
+	if err := m.rotate(ctx, start); err != nil {
+		return err
+	}
+	return m.Repo.DeleteRetired(ctx)
+}
+
+// StartRotation — фоновая проверка ротации ключей до отмены ctx
+func (m *KeyManager) StartRotation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.RotateIfDue(ctx); err != nil {
+				log.Println("Ошибка ротации ключей JWT:", err)
+			}
+		}
+	}
+}
+
+// Sign подписывает claims текущим ключом и указывает его kid в заголовке
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(m.method, claims)
+	if m.hmacSecret != nil {
+		return token.SignedString(m.hmacSecret)
+	}
+
+	key, err := m.current()
+	if err != nil {
+		return "", err
+	}
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Keyfunc для jwt.Parse: ключ проверки по kid из того же набора, что публикуется в JWKS
+func (m *KeyManager) Keyfunc(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != m.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	if m.hmacSecret != nil {
+		return m.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if key := m.find(kid); key != nil {
+		return key.public, nil
+	}
+
+	// Ключ мог появиться после ротации на другом экземпляре
+	if m.reloadAllowed() {
+		if err := m.reload(ctx); err != nil {
+			return nil, err
+		}
+		if key := m.find(kid); key != nil {
+			return key.public, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKS — открытые ключи, которые ещё принимаются при проверке
+func (m *KeyManager) JWKS() (*utils.JWKSet, error) {
+	set := &utils.JWKSet{Keys: []utils.JWK{}}
+	if m.hmacSecret != nil {
+		return set, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	for _, k := range m.keys {
+		if !k.retireAt.After(now) {
+			continue
+		}
+		jwk, err := utils.PublicJWK(k.kid, m.Alg, k.public)
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// current — ключ, который подписывает сейчас; из нескольких — начавший подписывать последним
+func (m *KeyManager) current() (*signingKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	for _, k := range m.keys {
+		if !k.notBefore.After(now) && k.notAfter.After(now) {
+			return k, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// latest — ключ с самым поздним концом подписи, включая ещё не начавший подписывать
+func (m *KeyManager) latest() *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var last *signingKey
+	for _, k := range m.keys {
+		if last == nil || k.notAfter.After(last.notAfter) {
+			last = k
+		}
+	}
+	return last
+}
+
+func (m *KeyManager) find(kid string) *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	for _, k := range m.keys {
+		if k.kid == kid && k.retireAt.After(now) {
+			return k
+		}
+	}
+	return nil
+}
+
+func (m *KeyManager) reloadAllowed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Since(m.lastReload) < keyReloadCooldown {
+		return false
+	}
+	m.lastReload = time.Now()
+	return true
+}
+
+func (m *KeyManager) reload(ctx context.Context) error {
+	stored, err := m.Repo.FindActive(ctx, m.Alg)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(stored))
+	for _, s := range stored {
+		der, err := utils.DecryptAESGCM(m.encKey, s.PrivateKeyEnc)
+		if err != nil {
+			return fmt.Errorf("ключ %s: %v", s.KID, err)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return fmt.Errorf("ключ %s: %v", s.KID, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return fmt.Errorf("ключ %s: неподдерживаемый тип %T", s.KID, parsed)
+		}
+		keys = append(keys, &signingKey{
+			kid:       s.KID,
+			private:   signer,
+			public:    signer.Public(),
+			notBefore: s.NotBefore,
+			notAfter:  s.NotAfter,
+			retireAt:  s.RetireAt,
+		})
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+	return nil
+}
+
+// rotate создаёт новый ключ. Он публикуется сразу, подписывает токены в течение Rotation
+// начиная со start, затем ещё Overlap принимается при проверке, чтобы выданные токены дожили до истечения.
+func (m *KeyManager) rotate(ctx context.Context, start time.Time) error {
+	signer, err := m.generate()
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return err
+	}
+	privateEnc, err := utils.EncryptAESGCM(m.encKey, der)
+	if err != nil {
+		return err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+	kid, err := utils.GenerateOpaqueToken(12)
+	if err != nil {
+		return err
+	}
+
+	stored := &models.JWTKey{
+		KID:           kid,
+		Alg:           m.Alg,
+		PrivateKeyEnc: privateEnc,
+		PublicKey:     publicDER,
+		NotBefore:     start,
+		NotAfter:      start.Add(m.Rotation),
+		RetireAt:      start.Add(m.Rotation + m.Overlap),
+	}
+	if err := m.Repo.Create(ctx, stored); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.keys = append([]*signingKey{{
+		kid:       kid,
+		private:   signer,
+		public:    signer.Public(),
+		notBefore: stored.NotBefore,
+		notAfter:  stored.NotAfter,
+		retireAt:  stored.RetireAt,
+	}}, m.keys...)
+	m.mu.Unlock()
+
+	log.Printf("Создан новый ключ подписи JWT %s (%s), подписывает с %s", kid, m.Alg, start.Format(time.RFC3339))
+	return nil
+}
+
+func (m *KeyManager) generate() (crypto.Signer, error) {
+	switch m.Alg {
+	case "RS256":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q", m.Alg)
+	}
+}
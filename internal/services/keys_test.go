@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testSigningKey(t *testing.T, kid string, notBefore, notAfter, retireAt time.Time) *signingKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signingKey{kid: kid, private: priv, public: priv.Public(), notBefore: notBefore, notAfter: notAfter, retireAt: retireAt}
+}
+
+// testKeyManager — набор в середине ротации: previous уже не подписывает, но ещё принимается,
+// current подписывает, next опубликован заранее, retired выведен из оборота.
+// Перечитывание из БД отключено недавней отметкой lastReload.
+func testKeyManager(t *testing.T) *KeyManager {
+	now := time.Now()
+	hour := time.Hour
+	return &KeyManager{
+		Alg:        "EdDSA",
+		method:     jwt.SigningMethodEdDSA,
+		lastReload: now,
+		keys: []*signingKey{
+			testSigningKey(t, "next", now.Add(hour), now.Add(3*hour), now.Add(4*hour)),
+			testSigningKey(t, "current", now.Add(-hour), now.Add(hour), now.Add(2*hour)),
+			testSigningKey(t, "previous", now.Add(-3*hour), now.Add(-hour), now.Add(hour)),
+			testSigningKey(t, "retired", now.Add(-5*hour), now.Add(-3*hour), now.Add(-2*hour)),
+		},
+	}
+}
+
+func signWith(t *testing.T, m *KeyManager, kid string) string {
+	t.Helper()
+	// Ключ из набора, в том числе выведенный; незнакомый kid — посторонний ключ
+	key := testSigningKey(t, kid, time.Time{}, time.Time{}, time.Time{})
+	for _, k := range m.keys {
+		if k.kid == kid {
+			key = k
+		}
+	}
+	token := jwt.NewWithClaims(m.method, jwt.RegisteredClaims{Subject: "7", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func parseWith(m *KeyManager, signed string) (*jwt.Token, error) {
+	return jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+		return m.Keyfunc(context.Background(), token)
+	})
+}
+
+func TestSignUsesCurrentKey(t *testing.T) {
+	m := testKeyManager(t)
+	signed, err := m.Sign(jwt.RegisteredClaims{Subject: "7"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	token, err := parseWith(m, signed)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if kid := token.Header["kid"]; kid != "current" {
+		t.Errorf("kid = %v, want current", kid)
+	}
+}
+
+func TestJWKSPublishesAcceptedKeys(t *testing.T) {
+	m := testKeyManager(t)
+	set, err := m.JWKS()
+	if err != nil {
+		t.Fatalf("JWKS: %v", err)
+	}
+	published := map[string]bool{}
+	for _, k := range set.Keys {
+		published[k.Kid] = true
+	}
+	for kid, want := range map[string]bool{"next": true, "current": true, "previous": true, "retired": false} {
+		if published[kid] != want {
+			t.Errorf("key %s published = %v, want %v", kid, published[kid], want)
+		}
+	}
+}
+
+func TestTokenOfPreviousKeyAcceptedDuringOverlap(t *testing.T) {
+	m := testKeyManager(t)
+	if _, err := parseWith(m, signWith(t, m, "previous")); err != nil {
+		t.Errorf("token of rotated key rejected: %v", err)
+	}
+}
+
+func TestTokenOfRetiredKeyRejected(t *testing.T) {
+	m := testKeyManager(t)
+	for _, kid := range []string{"retired", "unknown"} {
+		if _, err := parseWith(m, signWith(t, m, kid)); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("kid %s: err = %v, want %v", kid, err, ErrUnknownKey)
+		}
+	}
+}
+
+func TestTokenWithOtherAlgorithmRejected(t *testing.T) {
+	m := testKeyManager(t)
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "7"}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseWith(m, signed); err == nil {
+		t.Error("HS256 token accepted by EdDSA key set")
+	}
+}
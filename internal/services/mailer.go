@@ -1,10 +1,14 @@
 package services
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
 	"os"
+	"time"
+
+	"gobankapi/internal/money"
 
 	"github.com/go-mail/mail/v2"
 )
@@ -33,20 +37,70 @@ func NewMailer() *Mailer {
 	}
 }
 
-func (m *Mailer) SendPaymentConfirmation(to string, amount float64) error {
+func (m *Mailer) SendPaymentConfirmation(ctx context.Context, to string, amount money.Money) error {
 	content := fmt.Sprintf(`
 		<h1>Спасибо за оплату!</h1>
-		<p>Сумма: <strong>%.2f RUB</strong></p>
+		<p>Сумма: <strong>%s %s</strong></p>
 		<small>Это автоматическое уведомление</small>
-	`, amount)
+	`, amount, amount.Currency)
+
+	return m.send(ctx, to, "Платеж успешно проведен", content)
+}
+
+func (m *Mailer) SendCreditRateChange(ctx context.Context, to string, creditID int, oldRate, newRate float64, payment money.Money) error {
+	content := fmt.Sprintf(`
+		<h1>Изменение ставки по кредиту</h1>
+		<p>Ключевая ставка ЦБ изменилась, поэтому ставка по кредиту №%d с плавающей ставкой
+		пересчитана: <strong>%.2f%%</strong> вместо %.2f%% годовых.</p>
+		<p>Новый ежемесячный платёж: <strong>%s %s</strong></p>
+		<small>Это автоматическое уведомление</small>
+	`, creditID, newRate, oldRate, payment, payment.Currency)
+
+	return m.send(ctx, to, "Изменение ставки по кредиту", content)
+}
+
+func (m *Mailer) SendTwoFactorCode(ctx context.Context, to, code string, ttlMinutes int) error {
+	content := fmt.Sprintf(`
+		<h1>Код подтверждения</h1>
+		<p>Ваш код: <strong>%s</strong></p>
+		<p>Код действует %d мин. Никому его не сообщайте.</p>
+		<small>Если вы не запрашивали код, смените пароль</small>
+	`, code, ttlMinutes)
+
+	return m.send(ctx, to, "Код подтверждения GoBank", content)
+}
+
+// send отправляет письмо. Библиотека SMTP не принимает контекст, поэтому срок ctx ограничивает
+// таймаут соединения, а при отмене ctx вызов возвращается сразу, не дожидаясь сервера.
+func (m *Mailer) send(ctx context.Context, to, subject, content string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	msg := mail.NewMessage()
 	msg.SetHeader("From", m.from)
 	msg.SetHeader("To", to)
-	msg.SetHeader("Subject", "Платеж успешно проведен")
+	msg.SetHeader("Subject", subject)
 	msg.SetBody("text/html", content)
 
-	if err := m.dialer.DialAndSend(msg); err != nil {
+	dialer := *m.dialer
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); dialer.Timeout == 0 || left < dialer.Timeout {
+			dialer.Timeout = left
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- dialer.DialAndSend(msg) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		log.Printf("SMTP: отправка на %s прервана: %v", to, ctx.Err())
+		return ctx.Err()
+	}
+	if err != nil {
 		log.Printf("SMTP error: %v", err)
 		return fmt.Errorf("email sending failed")
 	}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // срок жизни access-токена, секунды
+	SessionID    string `json:"session_id"`
+}
+
+// TokenService выдаёт пары access/refresh токенов и ведёт серверные сессии
+type TokenService struct {
+	SessionRepo *repositories.SessionRepository
+	Keys        *KeyManager
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+}
+
+func NewTokenService(sessionRepo *repositories.SessionRepository, keys *KeyManager) *TokenService {
+	return &TokenService{
+		SessionRepo: sessionRepo,
+		Keys:        keys,
+		AccessTTL:   time.Duration(config.AppConfig.AccessTokenTTLMinutes) * time.Minute,
+		RefreshTTL:  time.Duration(config.AppConfig.RefreshTokenTTLHours) * time.Hour,
+	}
+}
+
+// Issue открывает новую сессию (устройство) и выдаёт для неё пару токенов
+func (s *TokenService) Issue(ctx context.Context, userID int, twoFAVerified bool, userAgent, ip string) (*TokenPair, error) {
+	sessionID, err := utils.GenerateOpaqueToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		ID:               sessionID,
+		UserID:           userID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		TwoFAVerified:    twoFAVerified,
+		UserAgent:        userAgent,
+		IP:               ip,
+		ExpiresAt:        time.Now().Add(s.RefreshTTL),
+	}
+	if err := s.SessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.pair(ctx, session, refreshToken)
+}
+
+// Refresh обменивает refresh-токен на новую пару. Старый токен становится недействительным;
+// его повторное предъявление считается кражей и отзывает всю сессию.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	hash := utils.HashToken(refreshToken)
+	session, reused, err := s.SessionRepo.FindByTokenHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || !session.Active(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if reused {
+		if err := s.SessionRepo.Revoke(ctx, session.ID, session.UserID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	newToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.SessionRepo.Rotate(ctx, session.ID, hash, utils.HashToken(newToken), time.Now().Add(s.RefreshTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.pair(ctx, session, newToken)
+}
+
+// Logout отзывает сессию и текущий access-токен
+func (s *TokenService) Logout(ctx context.Context, userID int, sessionID, jti string, accessExpires time.Time) error {
+	if err := s.SessionRepo.Revoke(ctx, sessionID, userID); err != nil {
+		return err
+	}
+	return s.SessionRepo.RevokeToken(ctx, jti, accessExpires)
+}
+
+func (s *TokenService) pair(ctx context.Context, session *models.Session, refreshToken string) (*TokenPair, error) {
+	claims, err := utils.NewAccessClaims(strconv.Itoa(session.UserID), session.ID, session.TwoFAVerified, s.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.Keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.AccessTTL.Seconds()),
+		SessionID:    session.ID,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testTokenService — сервис токенов поверх БД-заглушки с подписью HS256
+func testTokenService(t *testing.T) (*TokenService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	keys := &KeyManager{Alg: "HS256", method: jwt.SigningMethodHS256, hmacSecret: []byte("test-secret")}
+	return &TokenService{
+		SessionRepo: repositories.NewSessionRepository(db),
+		Keys:        keys,
+		AccessTTL:   15 * time.Minute,
+		RefreshTTL:  time.Hour,
+	}, mock
+}
+
+// expectSession — поиск сессии по хешу refresh-токena: current — текущий хеш, previous — ротированный
+func expectSession(mock sqlmock.Sqlmock, hash, current, previous string, revokedAt *time.Time) {
+	rows := sqlmock.NewRows([]string{"id", "user_id", "refresh_token_hash", "previous_token_hash", "twofa_verified",
+		"user_agent", "ip", "created_at", "last_used_at", "expires_at", "revoked_at"})
+	if current != "" {
+		now := time.Now()
+		rows.AddRow("sess-1", 7, current, previous, true, "test", "127.0.0.1", now, now, now.Add(time.Hour), revokedAt)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE refresh_token_hash = $1 OR previous_token_hash = $1")).
+		WithArgs(hash).WillReturnRows(rows)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	s, mock := testTokenService(t)
+	hash := utils.HashToken("refresh-1")
+	expectSession(mock, hash, hash, "", nil)
+	mock.ExpectExec(regexp.QuoteMeta("SET previous_token_hash = refresh_token_hash")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "sess-1", hash).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	pair, err := s.Refresh(context.Background(), "refresh-1")
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if pair.RefreshToken == "" || pair.RefreshToken == "refresh-1" || pair.SessionID != "sess-1" {
+		t.Errorf("pair = %+v, want new refresh token for sess-1", pair)
+	}
+	var claims utils.Claims
+	if _, err := jwt.ParseWithClaims(pair.AccessToken, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte("test-secret"), nil
+	}); err != nil || claims.SessionID != "sess-1" || claims.Subject != "7" || !claims.TwoFAVerified {
+		t.Errorf("access token claims = %+v, %v", claims, err)
+	}
+}
+
+func TestRefreshReplayedTokenRevokesSession(t *testing.T) {
+	s, mock := testTokenService(t)
+	hash := utils.HashToken("refresh-1")
+	expectSession(mock, hash, utils.HashToken("refresh-2"), hash, nil)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sessions SET revoked_at = NOW()")).
+		WithArgs("sess-1", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := s.Refresh(context.Background(), "refresh-1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh with rotated token: err = %v, want %v", err, ErrRefreshTokenReused)
+	}
+}
+
+func TestRefreshRejected(t *testing.T) {
+	revoked := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock, hash string)
+	}{
+		{"unknown token", func(mock sqlmock.Sqlmock, hash string) {
+			expectSession(mock, hash, "", "", nil)
+		}},
+		{"revoked session", func(mock sqlmock.Sqlmock, hash string) {
+			expectSession(mock, hash, hash, "", &revoked)
+		}},
+		{"lost concurrent rotation", func(mock sqlmock.Sqlmock, hash string) {
+			expectSession(mock, hash, hash, "", nil)
+			mock.ExpectExec(regexp.QuoteMeta("SET previous_token_hash = refresh_token_hash")).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := testTokenService(t)
+			tt.expect(mock, utils.HashToken("refresh-1"))
+			if _, err := s.Refresh(context.Background(), "refresh-1"); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("err = %v, want %v", err, ErrInvalidRefreshToken)
+			}
+		})
+	}
+}
+
+func TestLogoutRevokesSessionAndAccessToken(t *testing.T) {
+	s, mock := testTokenService(t)
+	expires := time.Now().Add(10 * time.Minute)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sessions SET revoked_at = NOW()")).
+		WithArgs("sess-1", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO revoked_tokens")).
+		WithArgs("jti-1", expires).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.Logout(context.Background(), 7, "sess-1", "jti-1", expires); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+}
+
+func TestLogoutForeignSession(t *testing.T) {
+	s, mock := testTokenService(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sessions SET revoked_at = NOW()")).
+		WithArgs("sess-1", 8).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := s.Logout(context.Background(), 8, "sess-1", "jti-1", time.Now()); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Logout of another user's session: err = %v, want %v", err, sql.ErrNoRows)
+	}
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gobankapi/internal/config"
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+	"gobankapi/internal/repositories"
+	"gobankapi/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Назначение 2FA-запроса при входе. Для критичных операций назначением служит
+// отпечаток операции (см. OperationPurpose), чтобы код нельзя было применить к другой операции.
+const TwoFAPurposeLogin = "login"
+
+// Способ подтверждения: код из письма или код приложения-аутентификатора (либо код восстановления)
+const (
+	TwoFAMethodEmail = "email"
+	TwoFAMethodTOTP  = "totp"
+)
+
+const recoveryCodesCount = 10
+
+var (
+	ErrTwoFAChallengeNotFound = errors.New("2fa challenge not found")
+	ErrTwoFACodeExpired       = errors.New("2fa code expired")
+	ErrTwoFAInvalidCode       = errors.New("invalid 2fa code")
+	ErrTwoFAPurposeMismatch   = errors.New("2fa challenge was issued for another operation")
+	ErrTwoFALocked            = errors.New("too many invalid 2fa attempts, try again later")
+	ErrTOTPAlreadyEnabled     = errors.New("totp is already enabled")
+	ErrTOTPNotEnrolled        = errors.New("totp enrollment not started")
+)
+
+type TwoFAChallenge struct {
+	ID        string    `json:"challenge_id"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorService struct {
+	UserRepo     *repositories.UserRepository
+	RecoveryRepo *repositories.RecoveryCodeRepository
+	Mailer       *Mailer
+	FX           *ExchangeService
+	CodeTTL      time.Duration
+	MaxAttempts  int
+	Lockout      time.Duration
+	Threshold    money.Money
+	LoginEmail   bool // подтверждать вход кодом из письма, если TOTP не подключён
+	Issuer       string
+	totpKey      []byte
+	codeKey      []byte // ключ HMAC кодов из писем
+}
+
+func NewTwoFactorService(
+	userRepo *repositories.UserRepository,
+	recoveryRepo *repositories.RecoveryCodeRepository,
+	mailer *Mailer,
+	fx *ExchangeService,
+) (*TwoFactorService, error) {
+	totpKey, err := encryptionKey("TOTP_ENCRYPTION_KEY", config.AppConfig.TOTPEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorService{
+		UserRepo:     userRepo,
+		RecoveryRepo: recoveryRepo,
+		Mailer:       mailer,
+		FX:           fx,
+		CodeTTL:      time.Duration(config.AppConfig.TwoFACodeTTLMinutes) * time.Minute,
+		MaxAttempts:  config.AppConfig.TwoFAMaxAttempts,
+		Lockout:      time.Duration(config.AppConfig.TwoFALockoutMinutes) * time.Minute,
+		Threshold:    money.FromFloat(config.AppConfig.TwoFAThreshold, BaseCurrency, money.HalfUp),
+		LoginEmail:   config.AppConfig.TwoFALoginEmail,
+		Issuer:       config.AppConfig.TOTPIssuer,
+		totpKey:      totpKey,
+		codeKey:      deriveKey(totpKey, "twofa-email-code"),
+	}, nil
+}
+
+// deriveKey — отдельный ключ для другого назначения, чтобы не использовать ключ шифрования как ключ HMAC
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// codeHash — HMAC кода из письма, привязанный к запросу: в БД хранится только он
+func (s *TwoFactorService) codeHash(challengeID, code string) string {
+	mac := hmac.New(sha256.New, s.codeKey)
+	mac.Write([]byte(challengeID + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Ключ шифрования AES-256: 32 байта в base64. Без ключа данные в БД фактически не зашифрованы,
+// поэтому отсутствующий или некорректный ключ — ошибка запуска. Только в режиме разработки
+// (DEV_MODE) ключ выводится из строки (или JWT_SECRET).
+func encryptionKey(name, encoded string) ([]byte, error) {
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if !config.AppConfig.DevMode {
+		return nil, fmt.Errorf("%s must be 32 bytes in base64 (set DEV_MODE=true to derive it for development)", name)
+	}
+	log.Printf("%s не задан или некорректен — ключ выведен из секрета приложения (DEV_MODE)", name)
+	if encoded == "" {
+		encoded = config.AppConfig.JWTSecret
+	}
+	sum := sha256.Sum256([]byte(encoded))
+	return sum[:], nil
+}
+
+// OperationPurpose — отпечаток критичной операции, к которому привязывается код
+func OperationPurpose(operation string, params ...interface{}) string {
+	return fmt.Sprintf("%s%v", operation, params)
+}
+
+// LoginRequiresChallenge — включён ли у пользователя второй фактор при входе:
+// подключён TOTP либо вход подтверждается кодом из письма
+func (s *TwoFactorService) LoginRequiresChallenge(user *models.User) bool {
+	return user.TOTPEnabled || s.LoginEmail
+}
+
+// RequiresStepUp — нужно ли подтверждать операцию на указанную сумму вторым фактором.
+// Сумма в другой валюте сравнивается с порогом по курсу; без курса подтверждение требуется всегда.
+func (s *TwoFactorService) RequiresStepUp(ctx context.Context, amount money.Money) bool {
+	if amount.Currency != s.Threshold.Currency {
+		if s.FX == nil {
+			return true
+		}
+		converted, err := s.FX.ToBase(ctx, amount)
+		if err != nil || converted.Currency != s.Threshold.Currency {
+			return true
+		}
+		amount = converted
+	}
+	return amount.GreaterThan(s.Threshold)
+}
+
+// StartChallenge создаёт 2FA-запрос и отправляет код на email пользователя
+func (s *TwoFactorService) StartChallenge(ctx context.Context, user *models.User, purpose string) (*TwoFAChallenge, error) {
+	now := time.Now()
+	if user.TwoFALocked(now) {
+		return nil, ErrTwoFALocked
+	}
+
+	challengeID, err := utils.GenerateChallengeID()
+	if err != nil {
+		return nil, err
+	}
+	expires := now.Add(s.CodeTTL)
+
+	// С подключённым приложением-аутентификатором письмо не отправляется
+	if user.TOTPEnabled {
+		if err := s.UserRepo.SetTwoFAChallenge(ctx, user.ID, challengeID, "", purpose, expires); err != nil {
+			return nil, err
+		}
+		return &TwoFAChallenge{ID: challengeID, Method: TwoFAMethodTOTP, ExpiresAt: expires}, nil
+	}
+
+	code, err := utils.GenerateOTPCode()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.SetTwoFAChallenge(ctx, user.ID, challengeID, s.codeHash(challengeID, code), purpose, expires); err != nil {
+		return nil, err
+	}
+
+	if err := s.Mailer.SendTwoFactorCode(ctx, user.Email, code, int(s.CodeTTL.Minutes())); err != nil {
+		return nil, err
+	}
+
+	return &TwoFAChallenge{ID: challengeID, Method: TwoFAMethodEmail, ExpiresAt: expires}, nil
+}
+
+// StartChallengeForUser — то же, что StartChallenge, но по ID пользователя из токена
+func (s *TwoFactorService) StartChallengeForUser(ctx context.Context, userID int, purpose string) (*TwoFAChallenge, error) {
+	user, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrTwoFAChallengeNotFound
+	}
+	return s.StartChallenge(ctx, user, purpose)
+}
+
+// VerifyChallenge проверяет код. Неверный код увеличивает счётчик попыток,
+// после MaxAttempts ввод блокируется на время Lockout.
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, challengeID, code, purpose string) (*models.User, error) {
+	if challengeID == "" {
+		return nil, ErrTwoFAChallengeNotFound
+	}
+
+	user, err := s.UserRepo.FindByTwoFAChallenge(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrTwoFAChallengeNotFound
+	}
+
+	now := time.Now()
+	if user.TwoFALocked(now) {
+		return nil, ErrTwoFALocked
+	}
+
+	if user.TwoFAPurpose != purpose {
+		return nil, ErrTwoFAPurposeMismatch
+	}
+
+	if user.TwoFAExpires == nil || user.TwoFAExpires.Before(now) {
+		if _, err := s.UserRepo.ClearTwoFAChallenge(ctx, user.ID, challengeID, false); err != nil {
+			return nil, err
+		}
+		return nil, ErrTwoFACodeExpired
+	}
+
+	ok, err := s.checkCode(ctx, user, code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.registerFailure(ctx, user.ID, now)
+	}
+
+	// Запрос гасится только если он ещё не погашен: из параллельных запросов с тем же кодом
+	// успешен ровно один
+	cleared, err := s.UserRepo.ClearTwoFAChallenge(ctx, user.ID, challengeID, true)
+	if err != nil {
+		return nil, err
+	}
+	if !cleared {
+		return nil, ErrTwoFAChallengeNotFound
+	}
+	return user, nil
+}
+
+// checkCode сверяет код из письма (по HMAC), либо код TOTP / код восстановления для пользователей с TOTP
+func (s *TwoFactorService) checkCode(ctx context.Context, user *models.User, code string, now time.Time) (bool, error) {
+	if code == "" {
+		return false, nil
+	}
+	if !user.TOTPEnabled {
+		return user.TwoFACode != "" && hmac.Equal([]byte(user.TwoFACode), []byte(s.codeHash(user.TwoFAChallengeID, code))), nil
+	}
+
+	secret, err := utils.DecryptAESGCM(s.totpKey, user.TOTPSecretEnc)
+	if err != nil {
+		return false, err
+	}
+	if step, ok := utils.ValidateTOTP(string(secret), code, now, 1); ok {
+		return s.UserRepo.UseTOTPStep(ctx, user.ID, step)
+	}
+
+	return s.useRecoveryCode(ctx, user.ID, code)
+}
+
+func (s *TwoFactorService) useRecoveryCode(ctx context.Context, userID int, code string) (bool, error) {
+	codes, err := s.RecoveryRepo.FindUnused(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(c.CodeHash), []byte(code)) == nil {
+			return s.RecoveryRepo.MarkUsed(ctx, c.ID)
+		}
+	}
+	return false, nil
+}
+
+func (s *TwoFactorService) registerFailure(ctx context.Context, userID int, now time.Time) error {
+	lockedUntil, err := s.UserRepo.RegisterFailedTwoFAAttempt(ctx, userID, s.MaxAttempts, now.Add(s.Lockout))
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil && lockedUntil.After(now) {
+		return ErrTwoFALocked
+	}
+	return ErrTwoFAInvalidCode
+}
+
+// EnrollTOTP генерирует секрет и сохраняет его до подтверждения первым кодом
+func (s *TwoFactorService) EnrollTOTP(ctx context.Context, userID int) (*TOTPEnrollment, error) {
+	user, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrTwoFAChallengeNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	secretEnc, err := utils.EncryptAESGCM(s.totpKey, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.SetPendingTOTPSecret(ctx, userID, secretEnc); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPURI(s.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает TOTP после проверки первого кода и выдаёт коды восстановления.
+// Коды возвращаются один раз, в базе хранятся только их bcrypt-хеши.
+func (s *TwoFactorService) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	user, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	now := time.Now()
+	if user.TwoFALocked(now) {
+		return nil, ErrTwoFALocked
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if len(user.TOTPSecretEnc) == 0 {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	secret, err := utils.DecryptAESGCM(s.totpKey, user.TOTPSecretEnc)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := utils.ValidateTOTP(string(secret), code, now, 1)
+	if !ok {
+		return nil, s.registerFailure(ctx, user.ID, now)
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hash, err := utils.HashRecoveryCode(c)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	enabled, err := s.UserRepo.EnableTOTP(ctx, user.ID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+)
+
+func testTwoFactorService() *TwoFactorService {
+	key := make([]byte, 32)
+	return &TwoFactorService{totpKey: key, codeKey: deriveKey(key, "twofa-email-code")}
+}
+
+func TestEmailCodeStoredAsHMAC(t *testing.T) {
+	s := testTwoFactorService()
+	stored := s.codeHash("challenge-1", "123456")
+	if strings.Contains(stored, "123456") || len(stored) != 64 {
+		t.Fatalf("stored code = %q, want hex HMAC", stored)
+	}
+	if s.codeHash("challenge-2", "123456") == stored {
+		t.Error("HMAC does not depend on challenge")
+	}
+}
+
+func TestCheckEmailCode(t *testing.T) {
+	s := testTwoFactorService()
+	user := &models.User{TwoFAChallengeID: "challenge-1", TwoFACode: s.codeHash("challenge-1", "123456")}
+
+	tests := []struct {
+		name string
+		user *models.User
+		code string
+		ok   bool
+	}{
+		{"valid code", user, "123456", true},
+		{"wrong code", user, "654321", false},
+		{"empty code", user, "", false},
+		{"stored hash as code", user, user.TwoFACode, false},
+		{"code of another challenge", &models.User{TwoFAChallengeID: "challenge-2", TwoFACode: user.TwoFACode}, "123456", false},
+		{"no pending code", &models.User{TwoFAChallengeID: "challenge-1"}, "123456", false},
+	}
+	for _, tt := range tests {
+		ok, err := s.checkCode(context.Background(), tt.user, tt.code, time.Now())
+		if err != nil || ok != tt.ok {
+			t.Errorf("%s: checkCode = %v, %v; want %v", tt.name, ok, err, tt.ok)
+		}
+	}
+}
@@ -2,10 +2,13 @@ package utils
 
 import (
 	"crypto/hmac"
+	crand "crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -44,6 +47,15 @@ func GenerateExpiryDate() string {
 	return expiry.Format("01/06")         // MM/YY
 }
 
+// ExpiryEnd — последний день срока действия MM/YY: карта действует до конца месяца
+func ExpiryEnd(expiry string) (time.Time, error) {
+	month, err := time.Parse("01/06", expiry)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return month.AddDate(0, 1, -1), nil
+}
+
 func GenerateCVV() string {
 	return fmt.Sprintf("%03d", rand.Intn(1000))
 }
@@ -58,3 +70,61 @@ func ComputeHMAC(data string, secret []byte) string {
 	h.Write([]byte(data))
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// GenerateCardToken — непрозрачный токен карты для ссылок на неё вместо номера и внутреннего ID
+func GenerateCardToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return "card_" + hex.EncodeToString(b), nil
+}
+
+// CardBrand — платёжная система по первым цифрам номера (BIN)
+func CardBrand(number string) string {
+	prefix := func(n int) int {
+		if len(number) < n {
+			return -1
+		}
+		v, err := strconv.Atoi(number[:n])
+		if err != nil {
+			return -1
+		}
+		return v
+	}
+	switch p2, p4 := prefix(2), prefix(4); {
+	case p4 >= 2200 && p4 <= 2204:
+		return "mir"
+	case number != "" && number[0] == '4':
+		return "visa"
+	case p2 >= 51 && p2 <= 55, p4 >= 2221 && p4 <= 2720:
+		return "mastercard"
+	case p2 == 34 || p2 == 37:
+		return "amex"
+	case p4 == 6011 || p2 == 65:
+		return "discover"
+	case p4 >= 3528 && p4 <= 3589:
+		return "jcb"
+	case p2 == 62:
+		return "unionpay"
+	}
+	return "unknown"
+}
+
+// IsMCC проверяет код категории продавца (ISO 18245): четыре цифры
+func IsMCC(mcc string) bool {
+	if len(mcc) != 4 {
+		return false
+	}
+	for _, c := range mcc {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MaskPAN — 16-значный номер карты с открытыми первыми 6 и последними 4 цифрами: 220012******3456
+func MaskPAN(first6, last4 string) string {
+	return first6 + strings.Repeat("*", 6) + last4
+}
@@ -1,16 +1,131 @@
 package utils
 
 import (
-	"math"
+	"math/big"
+	"time"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
 )
 
-// Расчёт аннуитетного ежемесячного платежа
-func CalculateAnnuity(amount float64, annualRate float64, months int) float64 {
-	if annualRate == 0 {
-		return amount / float64(months)
+// Платёж по графику: сумма и её разбивка на основной долг и проценты
+type Installment struct {
+	Payment   money.Money
+	Principal money.Money
+	Interest  money.Money
+	Remaining money.Money // остаток долга после платежа
+	DueDate   time.Time   // дата платежа (только в DatedSchedule)
+}
+
+// Месячная ставка как точная дробь: годовая ставка в процентах / 12 / 100
+func monthlyRate(annualRate float64) *big.Rat {
+	return new(big.Rat).Quo(money.RatFromFloat(annualRate), big.NewRat(1200, 1))
+}
+
+// Расчёт аннуитетного ежемесячного платежа. Формула считается в точных дробях,
+// результат округляется до копейки половиной вверх. При months <= 0 платёж нулевой.
+func CalculateAnnuity(amount money.Money, annualRate float64, months int) money.Money {
+	if months <= 0 {
+		return money.Zero(amount.Currency)
+	}
+	rate := monthlyRate(annualRate)
+	if rate.Sign() == 0 {
+		return amount.MulRat(big.NewRat(1, int64(months)), money.HalfUp)
+	}
+
+	// P * r * (1+r)^n / ((1+r)^n - 1)
+	growth := new(big.Rat).Add(big.NewRat(1, 1), rate)
+	pow := new(big.Rat).SetInt64(1)
+	for i := 0; i < months; i++ {
+		pow.Mul(pow, growth)
+	}
+	factor := new(big.Rat).Mul(rate, pow)
+	factor.Quo(factor, new(big.Rat).Sub(pow, big.NewRat(1, 1)))
+	return amount.MulRat(factor, money.HalfUp)
+}
+
+// AnnuitySchedule строит аннуитетный график. Проценты за месяц начисляются на остаток
+// с банковским округлением, последний платёж закрывает остаток долга целиком, поэтому
+// сумма платежей ровно равна сумме кредита плюс сумме начисленных процентов.
+func AnnuitySchedule(amount money.Money, annualRate float64, months int) []Installment {
+	return RepaymentSchedule(models.RepaymentAnnuity, amount, annualRate, months)
+}
+
+// DifferentiatedSchedule строит дифференцированный график: основной долг гасится равными долями
+// (округление половиной вверх, последний платёж закрывает остаток), проценты начисляются
+// на остаток с банковским округлением, поэтому платежи убывают.
+func DifferentiatedSchedule(amount money.Money, annualRate float64, months int) []Installment {
+	return RepaymentSchedule(models.RepaymentDifferentiated, amount, annualRate, months)
+}
+
+// RepaymentSchedule — график по виду погашения кредита (models.RepaymentAnnuity по умолчанию)
+// с процентами за месяц как 1/12 годовой ставки
+func RepaymentSchedule(repaymentType string, amount money.Money, annualRate float64, months int) []Installment {
+	if months <= 0 {
+		return nil
+	}
+	rates := make([]*big.Rat, months)
+	for i := range rates {
+		rates[i] = monthlyRate(annualRate)
+	}
+	return buildSchedule(repaymentType, amount, annualRate, rates)
+}
+
+// DatedSchedule — график с платежами в даты dueDates: проценты каждого периода начисляются
+// за фактические дни от предыдущей даты (первый период — от start) по конвенции dayCount.
+// Аннуитетный платёж считается по номинальной месячной ставке, разница в процентах
+// из-за разной длины месяцев уходит в последний платёж.
+func DatedSchedule(repaymentType string, amount money.Money, annualRate float64, dayCount string, start time.Time, dueDates []time.Time) []Installment {
+	if len(dueDates) == 0 {
+		return nil
+	}
+	rates := make([]*big.Rat, len(dueDates))
+	prev := start
+	for i, due := range dueDates {
+		rates[i] = PeriodRate(dayCount, annualRate, prev, due)
+		prev = due
+	}
+	schedule := buildSchedule(repaymentType, amount, annualRate, rates)
+	for i := range schedule {
+		schedule[i].DueDate = dueDates[i]
+	}
+	return schedule
+}
+
+// buildSchedule — общий расчёт графика по ставкам периодов rates
+func buildSchedule(repaymentType string, amount money.Money, annualRate float64, rates []*big.Rat) []Installment {
+	months := len(rates)
+	if months == 0 {
+		return nil
+	}
+	payment := CalculateAnnuity(amount, annualRate, months)
+	share := amount.MulRat(big.NewRat(1, int64(months)), money.HalfUp)
+
+	schedule := make([]Installment, 0, months)
+	remaining := amount
+	for i, rate := range rates {
+		interest := remaining.MulRat(rate, money.HalfEven)
+		principal := share
+		if repaymentType != models.RepaymentDifferentiated {
+			principal = payment.Sub(interest)
+		}
+		if i == months-1 || principal.GreaterThan(remaining) {
+			principal = remaining
+		}
+		if principal.IsNegative() {
+			// Проценты длинного периода больше аннуитета — долг в этом месяце не гасится
+			principal = money.Zero(amount.Currency)
+		}
+		remaining = remaining.Sub(principal)
+		schedule = append(schedule, Installment{
+			Payment:   principal.Add(interest),
+			Principal: principal,
+			Interest:  interest,
+			Remaining: remaining,
+		})
+		if remaining.IsZero() {
+			break
+		}
 	}
-	monthlyRate := annualRate / 12 / 100
-	payment := amount * (monthlyRate * math.Pow(1+monthlyRate, float64(months))) /
-		(math.Pow(1+monthlyRate, float64(months)) - 1)
-	return math.Round(payment*100) / 100
+	return schedule
 }
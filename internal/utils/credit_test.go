@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"gobankapi/internal/models"
+	"gobankapi/internal/money"
+)
+
+// checkSchedule проверяет, что платежи в сумме ровно равны кредиту плюс начисленным процентам
+// и что основной долг погашен полностью
+func checkSchedule(t *testing.T, name string, amount money.Money, schedule []Installment) {
+	t.Helper()
+	if len(schedule) == 0 {
+		t.Fatalf("%s: empty schedule", name)
+	}
+	payments := money.Zero(amount.Currency)
+	principal := money.Zero(amount.Currency)
+	interest := money.Zero(amount.Currency)
+	for i, p := range schedule {
+		if p.Payment != p.Principal.Add(p.Interest) {
+			t.Errorf("%s: installment %d: payment %s != principal %s + interest %s", name, i, p.Payment, p.Principal, p.Interest)
+		}
+		payments = payments.Add(p.Payment)
+		principal = principal.Add(p.Principal)
+		interest = interest.Add(p.Interest)
+	}
+	if principal != amount {
+		t.Errorf("%s: principal sum %s, want %s", name, principal, amount)
+	}
+	if want := amount.Add(interest); payments != want {
+		t.Errorf("%s: payments sum %s, want principal + interest %s", name, payments, want)
+	}
+	if last := schedule[len(schedule)-1]; !last.Remaining.IsZero() {
+		t.Errorf("%s: remaining after last payment %s", name, last.Remaining)
+	}
+}
+
+func TestRepaymentScheduleSums(t *testing.T) {
+	tests := []struct {
+		amount string
+		rate   float64
+		months int
+	}{
+		{"100000", 12, 12},
+		{"100000.01", 13.7, 36},
+		{"1000", 0, 7},
+		{"250000", 9.99, 1},
+		{"3000000", 21.5, 240},
+	}
+	for _, tt := range tests {
+		amount := money.MustParse(tt.amount, "RUB")
+		for _, kind := range []string{models.RepaymentAnnuity, models.RepaymentDifferentiated} {
+			schedule := RepaymentSchedule(kind, amount, tt.rate, tt.months)
+			if len(schedule) != tt.months {
+				t.Errorf("%s %s/%v/%d: %d installments, want %d", kind, tt.amount, tt.rate, tt.months, len(schedule), tt.months)
+			}
+			checkSchedule(t, kind+" "+tt.amount, amount, schedule)
+		}
+	}
+}
+
+func TestAnnuityPaymentsEqual(t *testing.T) {
+	amount := money.MustParse("100000", "RUB")
+	schedule := AnnuitySchedule(amount, 12, 12)
+	payment := CalculateAnnuity(amount, 12, 12)
+	if want := money.MustParse("8884.88", "RUB"); payment != want {
+		t.Fatalf("CalculateAnnuity = %s, want %s", payment, want)
+	}
+	for i, p := range schedule[:len(schedule)-1] {
+		if p.Payment != payment {
+			t.Errorf("installment %d: payment %s, want %s", i, p.Payment, payment)
+		}
+	}
+}
+
+func TestDifferentiatedPaymentsDecrease(t *testing.T) {
+	schedule := DifferentiatedSchedule(money.MustParse("120000", "RUB"), 15, 12)
+	for i := 1; i < len(schedule); i++ {
+		if !schedule[i].Payment.LessThan(schedule[i-1].Payment) {
+			t.Errorf("installment %d: payment %s not below previous %s", i, schedule[i].Payment, schedule[i-1].Payment)
+		}
+	}
+}
+
+func TestDatedScheduleSums(t *testing.T) {
+	amount := money.MustParse("500000", "RUB")
+	start := time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)
+	var due []time.Time
+	for i := 1; i <= 24; i++ {
+		due = append(due, start.AddDate(0, i, 0))
+	}
+	for _, kind := range []string{models.RepaymentAnnuity, models.RepaymentDifferentiated} {
+		for _, dc := range []string{DayCountACT365, DayCountACTACT, DayCount30360} {
+			checkSchedule(t, kind+" "+dc, amount, DatedSchedule(kind, amount, 17.5, dc, start, due))
+		}
+	}
+}
+
+func TestNonPositiveMonths(t *testing.T) {
+	amount := money.MustParse("1000", "RUB")
+	for _, months := range []int{0, -1} {
+		if got := CalculateAnnuity(amount, 10, months); !got.IsZero() {
+			t.Errorf("CalculateAnnuity(months=%d) = %s, want 0", months, got)
+		}
+		if got := CalculateAnnuity(amount, 0, months); !got.IsZero() {
+			t.Errorf("CalculateAnnuity(rate=0, months=%d) = %s, want 0", months, got)
+		}
+		if got := RepaymentSchedule(models.RepaymentAnnuity, amount, 10, months); got != nil {
+			t.Errorf("RepaymentSchedule(months=%d) = %v, want nil", months, got)
+		}
+	}
+	if got := buildSchedule(models.RepaymentDifferentiated, amount, 10, nil); got != nil {
+		t.Errorf("buildSchedule(no periods) = %v, want nil", got)
+	}
+}
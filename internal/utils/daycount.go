@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"math/big"
+	"time"
+
+	"gobankapi/internal/money"
+)
+
+// Конвенции подсчёта дней для начисления процентов
+const (
+	DayCountACT365 = "ACT/365" // фактические дни / 365
+	DayCountACTACT = "ACT/ACT" // фактические дни / дни в году (ISDA: период делится по календарным годам)
+	DayCount30360  = "30/360"  // месяц — 30 дней, год — 360 (30E/360)
+)
+
+// IsDayCount проверяет, что конвенция поддерживается
+func IsDayCount(convention string) bool {
+	switch convention {
+	case DayCountACT365, DayCountACTACT, DayCount30360:
+		return true
+	}
+	return false
+}
+
+// Date — полночь календарного дня t в его часовом поясе
+func Date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween — число календарных дней от from до to (по датам, без учёта времени)
+func daysBetween(from, to time.Time) int64 {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int64(b.Sub(a).Hours() / 24)
+}
+
+func daysInYear(year int) int64 {
+	if time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay() == 366 {
+		return 366
+	}
+	return 365
+}
+
+// YearFraction — доля года между датами from и to по конвенции (неизвестная считается ACT/365)
+func YearFraction(convention string, from, to time.Time) *big.Rat {
+	if !to.After(from) {
+		return new(big.Rat)
+	}
+	switch convention {
+	case DayCount30360:
+		d1, d2 := from.Day(), to.Day()
+		if d1 == 31 {
+			d1 = 30
+		}
+		if d2 == 31 {
+			d2 = 30
+		}
+		days := 360*(to.Year()-from.Year()) + 30*(int(to.Month())-int(from.Month())) + (d2 - d1)
+		return big.NewRat(int64(days), 360)
+	case DayCountACTACT:
+		fraction := new(big.Rat)
+		for start := from; start.Before(to); {
+			nextYear := time.Date(start.Year()+1, time.January, 1, 0, 0, 0, 0, start.Location())
+			end := to
+			if nextYear.Before(to) {
+				end = nextYear
+			}
+			fraction.Add(fraction, big.NewRat(daysBetween(start, end), daysInYear(start.Year())))
+			start = end
+		}
+		return fraction
+	default:
+		return big.NewRat(daysBetween(from, to), 365)
+	}
+}
+
+// PeriodRate — ставка за период from → to: годовая ставка в процентах × доля года
+func PeriodRate(convention string, annualRate float64, from, to time.Time) *big.Rat {
+	rate := new(big.Rat).Quo(money.RatFromFloat(annualRate), big.NewRat(100, 1))
+	return rate.Mul(rate, YearFraction(convention, from, to))
+}
+
+// AccruedInterest — проценты на остаток principal, начисленные с from по to по конвенции
+func AccruedInterest(principal money.Money, convention string, annualRate float64, from, to time.Time) money.Money {
+	return principal.MulRat(PeriodRate(convention, annualRate, from, to), money.HalfEven)
+}
+
+// AddMonths прибавляет n месяцев без перескока через конец месяца:
+// 31 января + 1 месяц = 28 (29) февраля, а не 3 марта
+func AddMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// Calendar — производственный календарь: выходные и праздничные дни не являются рабочими
+type Calendar struct {
+	holidays map[string]bool
+}
+
+func NewCalendar(holidays []time.Time) *Calendar {
+	c := &Calendar{holidays: make(map[string]bool, len(holidays))}
+	for _, h := range holidays {
+		c.holidays[h.Format("2006-01-02")] = true
+	}
+	return c
+}
+
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	return c == nil || !c.holidays[t.Format("2006-01-02")]
+}
+
+// Adjust переносит нерабочую дату по правилу modified following: на следующий рабочий день,
+// а если он уже в другом месяце — на предыдущий рабочий
+func (c *Calendar) Adjust(t time.Time) time.Time {
+	next := t
+	for !c.IsBusinessDay(next) {
+		next = next.AddDate(0, 0, 1)
+	}
+	if next.Month() == t.Month() {
+		return next
+	}
+	prev := t
+	for !c.IsBusinessDay(prev) {
+		prev = prev.AddDate(0, 0, -1)
+	}
+	return prev
+}
+
+// DueDates — даты платежей на months месяцев вперёд от start. Каждая дата отсчитывается от start,
+// а не от предыдущей, поэтому перенос с конца месяца или праздника не накапливается.
+func DueDates(start time.Time, months int, cal *Calendar) []time.Time {
+	start = Date(start)
+	dates := make([]time.Time, 0, months)
+	for i := 1; i <= months; i++ {
+		dates = append(dates, cal.Adjust(AddMonths(start, i)))
+	}
+	return dates
+}
@@ -0,0 +1,29 @@
+package utils
+
+import "crypto/rand"
+
+// SealEnvelope — конвертное шифрование: plaintext шифруется новым случайным ключом данных
+// (AES-256-GCM), а ключ данных — мастер-ключом kek. Мастер-ключ не покидает приложение,
+// в БД хранятся только шифртекст и обёрнутый ключ данных.
+func SealEnvelope(kek, plaintext []byte) (ciphertext, wrappedKey []byte, err error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, nil, err
+	}
+	if ciphertext, err = EncryptAESGCM(dek, plaintext); err != nil {
+		return nil, nil, err
+	}
+	if wrappedKey, err = EncryptAESGCM(kek, dek); err != nil {
+		return nil, nil, err
+	}
+	return ciphertext, wrappedKey, nil
+}
+
+// OpenEnvelope разворачивает ключ данных мастер-ключом kek и расшифровывает им ciphertext
+func OpenEnvelope(kek, ciphertext, wrappedKey []byte) ([]byte, error) {
+	dek, err := DecryptAESGCM(kek, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptAESGCM(dek, ciphertext)
+}
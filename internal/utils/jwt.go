@@ -1,18 +1,54 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWTToken(userID string, secret string) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Subject:   userID,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+// Claims — стандартные поля JWT (jti в ID), сессия и признак пройденной двухфакторной проверки
+type Claims struct {
+	SessionID     string `json:"sid"`
+	TwoFAVerified bool   `json:"2fa-verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Claims короткоживущего access-токена, привязанного к сессии.
+// Подпись выполняет services.KeyManager (ключ и алгоритм зависят от конфигурации).
+func NewAccessClaims(userID, sessionID string, twoFAVerified bool, ttl time.Duration) (*Claims, error) {
+	jti, err := GenerateOpaqueToken(16)
+	if err != nil {
+		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	now := time.Now()
+	return &Claims{
+		SessionID:     sessionID,
+		TwoFAVerified: twoFAVerified,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}, nil
+}
+
+// Случайная строка для refresh-токенов, идентификаторов сессий и jti
+func GenerateOpaqueToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SHA-256 хеш токена для хранения в БД
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,7 @@
+-- Двухфакторная аутентификация: код, срок действия, попытки и блокировка
+ALTER TABLE users ADD COLUMN IF NOT EXISTS twofacode VARCHAR(6);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS twofaexpires TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS twofa_challenge_id TEXT UNIQUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS twofa_purpose TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS twofa_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS twofa_locked_until TIMESTAMP;
@@ -0,0 +1,14 @@
+-- TOTP (приложение-аутентификатор) и одноразовые коды восстановления
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret_enc BYTEA;      -- секрет, зашифрованный AES-GCM
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0; -- защита от повторного кода
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id         SERIAL PRIMARY KEY,
+    user_id    INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash  TEXT NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes(user_id) WHERE used_at IS NULL;
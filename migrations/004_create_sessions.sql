@@ -0,0 +1,24 @@
+-- Сессии с ротируемыми refresh-токенами (хранятся только SHA-256 хеши)
+CREATE TABLE IF NOT EXISTS sessions (
+    id                  TEXT PRIMARY KEY,
+    user_id             INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash  TEXT NOT NULL UNIQUE,
+    previous_token_hash TEXT,                    -- для обнаружения повторного использования
+    twofa_verified      BOOLEAN NOT NULL DEFAULT FALSE,
+    user_agent          TEXT,
+    ip                  TEXT,
+    created_at          TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at        TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at          TIMESTAMP NOT NULL,
+    revoked_at          TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_hash ON sessions(previous_token_hash);
+
+-- Отозванные access-токены (по jti) до истечения их срока действия
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
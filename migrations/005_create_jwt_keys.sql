@@ -0,0 +1,11 @@
+-- Ключи подписи JWT (RS256/EdDSA) с ротацией.
+-- Ключ подписывает токены до not_after и публикуется в JWKS до retire_at (окно перекрытия).
+CREATE TABLE IF NOT EXISTS jwt_keys (
+    kid             TEXT PRIMARY KEY,
+    alg             TEXT NOT NULL,
+    private_key_enc BYTEA NOT NULL,  -- PKCS#8, зашифрован AES-GCM ключом JWT_KEY_ENCRYPTION_KEY
+    public_key      BYTEA NOT NULL,  -- PKIX DER
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    not_after       TIMESTAMP NOT NULL,
+    retire_at       TIMESTAMP NOT NULL
+);
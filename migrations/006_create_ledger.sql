@@ -0,0 +1,46 @@
+-- Двойная запись: каждая операция — проводка (entry) со сбалансированными дебетом и кредитом.
+-- Счёт клиента (account_id) — пассив банка: кредит увеличивает баланс, дебет уменьшает.
+-- Внутренние счета банка (gl_account): cash, loans, opening_balance.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id          SERIAL PRIMARY KEY,
+    type        TEXT NOT NULL,
+    description TEXT,
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id         SERIAL PRIMARY KEY,
+    entry_id   INT NOT NULL REFERENCES ledger_entries(id),
+    account_id INT REFERENCES accounts(id),
+    gl_account TEXT,
+    direction  TEXT NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount     NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    CHECK ((account_id IS NULL) <> (gl_account IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account ON ledger_postings(account_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_entry ON ledger_postings(entry_id);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS ledger_entry_id INT REFERENCES ledger_entries(id);
+
+-- Входящие остатки: существующие балансы переносятся в журнал, чтобы сверка сходилась
+DO $$
+DECLARE
+    acc RECORD;
+    entry INT;
+BEGIN
+    FOR acc IN
+        SELECT a.id, a.balance FROM accounts a
+        WHERE a.balance <> 0
+          AND NOT EXISTS (SELECT 1 FROM ledger_postings p WHERE p.account_id = a.id)
+    LOOP
+        INSERT INTO ledger_entries (type, description)
+        VALUES ('opening_balance', 'Входящий остаток при переходе на двойную запись')
+        RETURNING id INTO entry;
+
+        INSERT INTO ledger_postings (entry_id, account_id, direction, amount)
+        VALUES (entry, acc.id, CASE WHEN acc.balance > 0 THEN 'credit' ELSE 'debit' END, ABS(acc.balance));
+        INSERT INTO ledger_postings (entry_id, gl_account, direction, amount)
+        VALUES (entry, 'opening_balance', CASE WHEN acc.balance > 0 THEN 'debit' ELSE 'credit' END, ABS(acc.balance));
+    END LOOP;
+END $$;
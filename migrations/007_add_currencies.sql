@@ -0,0 +1,25 @@
+-- Мультивалютные счета: валюта счёта, проводки и операции в валюте, курсы обмена.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB'
+    CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE ledger_postings ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+-- Для конвертации: сумма во второй валюте и применённый курс (из валюты amount в counter_currency)
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS counter_amount NUMERIC(15,2);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS counter_currency CHAR(3);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC(20,10);
+
+-- Официальные курсы: сколько рублей стоят nominal единиц валюты на дату rate_date
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id         SERIAL PRIMARY KEY,
+    currency   CHAR(3) NOT NULL,
+    rate       NUMERIC(20,8) NOT NULL CHECK (rate > 0),
+    nominal    INT NOT NULL DEFAULT 1 CHECK (nominal > 0),
+    rate_date  DATE NOT NULL,
+    source     TEXT NOT NULL DEFAULT 'manual',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (currency, rate_date, source)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_currency_date ON exchange_rates(currency, rate_date DESC);
@@ -0,0 +1,31 @@
+-- Кредитные продукты: ставка = ключевая ставка ЦБ + маржа банка + спред продукта.
+-- fixed — ставка фиксируется при выдаче, floating — пересчитывается при изменении ключевой ставки.
+CREATE TABLE IF NOT EXISTS credit_products (
+    id              SERIAL PRIMARY KEY,
+    code            TEXT UNIQUE NOT NULL,
+    name            TEXT NOT NULL,
+    rate_type       TEXT NOT NULL CHECK (rate_type IN ('fixed', 'floating')),
+    spread          NUMERIC(5,2) NOT NULL,
+    min_amount      NUMERIC(15,2) NOT NULL,
+    max_amount      NUMERIC(15,2) NOT NULL,
+    min_term_months INT NOT NULL,
+    max_term_months INT NOT NULL,
+    active          BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO credit_products (code, name, rate_type, spread, min_amount, max_amount, min_term_months, max_term_months)
+VALUES
+    ('consumer', 'Потребительский кредит', 'fixed', 3.00, 10000, 3000000, 6, 60),
+    ('consumer_floating', 'Потребительский кредит с плавающей ставкой', 'floating', 2.00, 10000, 3000000, 6, 60),
+    ('mortgage_floating', 'Ипотека с плавающей ставкой', 'floating', 1.00, 500000, 30000000, 12, 360)
+ON CONFLICT (code) DO NOTHING;
+
+-- Параметры ставки кредита: спред к ключевой ставке и ключевая ставка последнего расчёта
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS product_id INT REFERENCES credit_products(id);
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS rate_type TEXT NOT NULL DEFAULT 'fixed';
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS spread NUMERIC(5,2);
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS key_rate NUMERIC(5,2);
+
+-- Основной долг в платеже: по сумме неоплаченных частей пересчитывается график плавающего кредита
+ALTER TABLE payment_schedules ADD COLUMN IF NOT EXISTS principal NUMERIC(15,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,26 @@
+-- Заявки на кредит: submitted → scored → approved / rejected → disbursed.
+-- Кредит и график создаются только при выдаче, вместе с зачислением суммы на счёт.
+CREATE TABLE IF NOT EXISTS credit_applications (
+    id              SERIAL PRIMARY KEY,
+    user_id         INT NOT NULL REFERENCES users(id),
+    account_id      INT NOT NULL REFERENCES accounts(id),
+    product_id      INT NOT NULL REFERENCES credit_products(id),
+    amount          NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    term_months     INT NOT NULL CHECK (term_months > 0),
+    status          TEXT NOT NULL DEFAULT 'submitted'
+                    CHECK (status IN ('submitted', 'scored', 'approved', 'rejected', 'disbursed')),
+    annual_rate     NUMERIC(5,2),
+    key_rate        NUMERIC(5,2),
+    spread          NUMERIC(5,2),
+    monthly_payment NUMERIC(15,2),
+    -- Результат скоринга
+    monthly_income  NUMERIC(15,2),
+    existing_debt   NUMERIC(15,2),
+    dti             NUMERIC(8,4),
+    decision_reason TEXT,
+    credit_id       INT REFERENCES credits(id),
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_applications_user ON credit_applications(user_id, created_at DESC);
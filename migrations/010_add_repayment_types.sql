@@ -0,0 +1,20 @@
+-- Вид погашения: annuity — равные платежи, differentiated — равные доли основного долга,
+-- проценты на остаток (платежи убывают)
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS repayment_type TEXT NOT NULL DEFAULT 'annuity'
+    CHECK (repayment_type IN ('annuity', 'differentiated'));
+ALTER TABLE credit_applications ADD COLUMN IF NOT EXISTS repayment_type TEXT NOT NULL DEFAULT 'annuity'
+    CHECK (repayment_type IN ('annuity', 'differentiated'));
+
+-- Разбивка платежа: amount = principal + interest, remaining — остаток долга после платежа
+ALTER TABLE payment_schedules ADD COLUMN IF NOT EXISTS interest NUMERIC(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN IF NOT EXISTS remaining NUMERIC(15,2) NOT NULL DEFAULT 0;
+
+-- Заполнение для существующих графиков
+UPDATE payment_schedules SET interest = amount - principal WHERE principal > 0;
+UPDATE payment_schedules ps
+SET remaining = c.amount - t.paid_principal
+FROM (
+    SELECT id, SUM(principal) OVER (PARTITION BY credit_id ORDER BY due_date, id) AS paid_principal
+    FROM payment_schedules
+) t, credits c
+WHERE t.id = ps.id AND c.id = ps.credit_id AND ps.principal > 0;
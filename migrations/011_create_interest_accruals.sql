@@ -0,0 +1,43 @@
+-- Конвенция подсчёта дней для процентов: ACT/365, ACT/ACT или 30/360
+ALTER TABLE credit_products ADD COLUMN IF NOT EXISTS day_count TEXT NOT NULL DEFAULT 'ACT/365'
+    CHECK (day_count IN ('ACT/365', 'ACT/ACT', '30/360'));
+UPDATE credit_products SET day_count = 'ACT/ACT' WHERE code = 'mortgage_floating';
+
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS day_count TEXT NOT NULL DEFAULT 'ACT/365'
+    CHECK (day_count IN ('ACT/365', 'ACT/ACT', '30/360'));
+
+-- Праздничные нерабочие дни (выходные учитываются отдельно): дата платежа, выпавшая на них,
+-- переносится на следующий рабочий день, а в конце месяца — на предыдущий
+CREATE TABLE IF NOT EXISTS holidays (
+    date DATE PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+INSERT INTO holidays (date, name)
+SELECT make_date(y, m, d), name
+FROM generate_series(2026, 2027) AS y,
+    (VALUES
+        (1, 1, 'Новогодние каникулы'), (1, 2, 'Новогодние каникулы'), (1, 3, 'Новогодние каникулы'),
+        (1, 4, 'Новогодние каникулы'), (1, 5, 'Новогодние каникулы'), (1, 6, 'Новогодние каникулы'),
+        (1, 7, 'Рождество Христово'), (1, 8, 'Новогодние каникулы'),
+        (2, 23, 'День защитника Отечества'),
+        (3, 8, 'Международный женский день'),
+        (5, 1, 'Праздник Весны и Труда'),
+        (5, 9, 'День Победы'),
+        (6, 12, 'День России'),
+        (11, 4, 'День народного единства')
+    ) AS h(m, d, name)
+ON CONFLICT (date) DO NOTHING;
+
+-- Ежедневное начисление процентов на остаток основного долга; одна запись на кредит и день
+CREATE TABLE IF NOT EXISTS interest_accruals (
+    id           SERIAL PRIMARY KEY,
+    credit_id    INT NOT NULL REFERENCES credits(id),
+    accrual_date DATE NOT NULL,
+    principal    NUMERIC(15,2) NOT NULL,
+    annual_rate  NUMERIC(5,2) NOT NULL,
+    day_count    TEXT NOT NULL,
+    amount       NUMERIC(15,2) NOT NULL,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (credit_id, accrual_date)
+);
@@ -0,0 +1,39 @@
+-- Политики неустойки по кредитным продуктам: ставка в день на просроченную сумму,
+-- льготный период после даты платежа и предел суммы неустойки относительно суммы кредита
+CREATE TABLE IF NOT EXISTS penalty_policies (
+    id          SERIAL PRIMARY KEY,
+    code        TEXT UNIQUE NOT NULL,
+    name        TEXT NOT NULL,
+    daily_rate  NUMERIC(6,4) NOT NULL CHECK (daily_rate >= 0), -- % в день
+    grace_days  INT NOT NULL DEFAULT 0 CHECK (grace_days >= 0),
+    cap_percent NUMERIC(5,2) NOT NULL CHECK (cap_percent >= 0),  -- % от суммы кредита
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO penalty_policies (code, name, daily_rate, grace_days, cap_percent)
+VALUES
+    ('standard', 'Стандартная: 0,05% в день', 0.0500, 3, 10.00),
+    ('mortgage', 'Ипотечная: 0,02% в день', 0.0200, 5, 5.00)
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE credit_products ADD COLUMN IF NOT EXISTS penalty_policy_id INT REFERENCES penalty_policies(id);
+UPDATE credit_products SET penalty_policy_id = (SELECT id FROM penalty_policies WHERE code = 'standard')
+WHERE penalty_policy_id IS NULL AND code <> 'mortgage_floating';
+UPDATE credit_products SET penalty_policy_id = (SELECT id FROM penalty_policies WHERE code = 'mortgage')
+WHERE penalty_policy_id IS NULL AND code = 'mortgage_floating';
+
+-- Каждое начисление неустойки — отдельное событие; не больше одного на платёж в календарный день
+CREATE TABLE IF NOT EXISTS penalty_events (
+    id             SERIAL PRIMARY KEY,
+    payment_id     INT NOT NULL REFERENCES payment_schedules(id) ON DELETE CASCADE,
+    credit_id      INT NOT NULL REFERENCES credits(id),
+    policy_id      INT REFERENCES penalty_policies(id),
+    event_date     DATE NOT NULL,
+    overdue_amount NUMERIC(15,2) NOT NULL,
+    daily_rate     NUMERIC(6,4) NOT NULL, -- применённая ставка с учётом законного предела
+    amount         NUMERIC(15,2) NOT NULL,
+    created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (payment_id, event_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_penalty_events_credit ON penalty_events(credit_id);
@@ -0,0 +1,14 @@
+-- Частичное списание просроченных платежей: сколько уже погашено по каждой части платежа.
+-- amount_paid = penalty_paid + interest_paid + principal_paid; платёж оплачен, когда погашены все части.
+ALTER TABLE payment_schedules ADD COLUMN IF NOT EXISTS amount_paid    NUMERIC(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN IF NOT EXISTS penalty_paid   NUMERIC(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN IF NOT EXISTS interest_paid  NUMERIC(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN IF NOT EXISTS principal_paid NUMERIC(15,2) NOT NULL DEFAULT 0;
+
+-- Строки графиков до разбивки платежа: вся сумма считается основным долгом
+UPDATE payment_schedules SET principal = amount - interest WHERE principal = 0 AND amount > interest;
+
+-- Уже оплаченные платежи погашены полностью (штраф раньше не списывался — он остаётся долгом)
+UPDATE payment_schedules
+SET principal_paid = principal, interest_paid = interest, amount_paid = principal + interest
+WHERE paid AND amount_paid = 0;
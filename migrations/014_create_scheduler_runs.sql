@@ -0,0 +1,15 @@
+-- История запусков фоновых задач. Одновременно задача выполняется только в одном процессе:
+-- запуск держит advisory lock pg_try_advisory_lock(hashtext('gobank:' || job)).
+CREATE TABLE IF NOT EXISTS scheduler_runs (
+    id          SERIAL PRIMARY KEY,
+    job         TEXT NOT NULL,
+    instance    TEXT NOT NULL, -- hostname:pid процесса
+    status      TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'succeeded', 'failed')),
+    processed   INT NOT NULL DEFAULT 0,
+    failed      INT NOT NULL DEFAULT 0,
+    error       TEXT,
+    started_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduler_runs_job ON scheduler_runs(job, started_at DESC);
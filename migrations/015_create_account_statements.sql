@@ -0,0 +1,15 @@
+-- Ежемесячные выписки по счетам: входящий и исходящий остаток и обороты за период по журналу проводок.
+-- Период — календарный месяц [period_start, period_end]; одна выписка на счёт и период.
+CREATE TABLE IF NOT EXISTS account_statements (
+    id              SERIAL PRIMARY KEY,
+    account_id      INT NOT NULL REFERENCES accounts(id),
+    period_start    DATE NOT NULL,
+    period_end      DATE NOT NULL,
+    currency        CHAR(3) NOT NULL,
+    opening_balance NUMERIC(15,2) NOT NULL,
+    credit_turnover NUMERIC(15,2) NOT NULL, -- зачисления
+    debit_turnover  NUMERIC(15,2) NOT NULL, -- списания
+    closing_balance NUMERIC(15,2) NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (account_id, period_start)
+);
@@ -0,0 +1,13 @@
+-- Срок действия карты в открытом виде (последний день месяца MM/YY) для задачи истечения срока
+-- и статус карты: просроченная карта переводится в expired.
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS status     TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS expires_on DATE;
+
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS cards_status_check;
+ALTER TABLE cards ADD CONSTRAINT cards_status_check CHECK (status IN ('active', 'expired'));
+
+UPDATE cards
+SET expires_on = (to_date(expiry_pgp, 'MM/YY') + INTERVAL '1 month - 1 day')::date
+WHERE expires_on IS NULL AND expiry_pgp ~ '^\d{2}/\d{2}$';
+
+CREATE INDEX IF NOT EXISTS idx_cards_expires_on ON cards(expires_on) WHERE status = 'active';
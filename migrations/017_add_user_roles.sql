@@ -0,0 +1,6 @@
+-- Роли пользователей: admin получает доступ к /api/admin (управление фоновыми задачами).
+-- Назначается вручную: UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,22 @@
+-- Конвертное шифрование данных карт: номер и срок действия шифруются ключом данных карты
+-- (encrypted_data), ключ данных — мастер-ключом версии key_version (wrapped_key).
+-- Мастер-ключи задаются в конфигурации (CARD_MASTER_KEYS / CARD_MASTER_KEYS_FILE) и в БД не попадают.
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS encrypted_data BYTEA;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS wrapped_key    BYTEA;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS key_version    INT;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS last4          CHAR(4);
+
+-- Открытые number_pgp/expiry_pgp остаются только у карт, выпущенных до шифрования:
+-- go run ./cmd/rotate-card-keys шифрует их и очищает эти столбцы
+ALTER TABLE cards ALTER COLUMN number_pgp DROP NOT NULL;
+ALTER TABLE cards ALTER COLUMN expiry_pgp DROP NOT NULL;
+
+UPDATE cards SET last4 = right(number_pgp, 4) WHERE last4 IS NULL AND number_pgp IS NOT NULL;
+
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS cards_encryption_check;
+ALTER TABLE cards ADD CONSTRAINT cards_encryption_check CHECK (
+    (encrypted_data IS NOT NULL AND wrapped_key IS NOT NULL AND key_version IS NOT NULL)
+    OR number_pgp IS NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cards_key_version ON cards(key_version);
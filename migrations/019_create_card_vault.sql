@@ -0,0 +1,26 @@
+-- Токенизация карт: снаружи карта доступна только по непрозрачному токену, в списках —
+-- маскированный номер (первые 6 и последние 4 цифры) и платёжная система
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS token  TEXT;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS first6 CHAR(6);
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS brand  TEXT NOT NULL DEFAULT 'unknown';
+
+UPDATE cards SET token = 'card_' || replace(gen_random_uuid()::text, '-', '') WHERE token IS NULL;
+ALTER TABLE cards ALTER COLUMN token SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cards_token ON cards(token);
+
+-- Карты с открытыми данными заполняются здесь, зашифрованные — командой rotate-card-keys -all
+UPDATE cards SET first6 = left(number_pgp, 6) WHERE first6 IS NULL AND number_pgp IS NOT NULL;
+
+-- Журнал просмотров полного номера карты, включая отказы из-за неверного пароля
+CREATE TABLE IF NOT EXISTS card_reveal_audit (
+    id         INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    card_id    INT NOT NULL REFERENCES cards(id),
+    user_id    INT NOT NULL REFERENCES users(id),
+    success    BOOLEAN NOT NULL,
+    reason     TEXT,
+    ip         TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_reveal_audit_card ON card_reveal_audit(card_id, created_at);
@@ -0,0 +1,20 @@
+-- Жизненный цикл карты: active ⇄ frozen, блокировка с причиной (навсегда), перевыпуск с новым
+-- номером (старая карта — reissued, новая ссылается на неё через reissued_from) и истечение срока.
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS cards_status_check;
+ALTER TABLE cards ADD CONSTRAINT cards_status_check
+    CHECK (status IN ('active', 'frozen', 'blocked', 'expired', 'reissued'));
+
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS block_reason      TEXT;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS reissued_from     INT REFERENCES cards(id);
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS cards_block_reason_check;
+ALTER TABLE cards ADD CONSTRAINT cards_block_reason_check
+    CHECK (block_reason IS NULL OR block_reason IN ('lost', 'stolen', 'compromised', 'closed'));
+
+-- Карту можно перевыпустить только один раз
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cards_reissued_from ON cards(reissued_from) WHERE reissued_from IS NOT NULL;
+
+-- Истечение срока проверяется и у замороженных карт
+DROP INDEX IF EXISTS idx_cards_expires_on;
+CREATE INDEX IF NOT EXISTS idx_cards_expires_on ON cards(expires_on) WHERE status IN ('active', 'frozen');
@@ -0,0 +1,34 @@
+-- Авторизации по картам. Одобренная авторизация ставит холд на счёт карты: сумма остаётся
+-- в balance (проведённый остаток), но учитывается в held и недоступна для списаний
+-- (доступный остаток = balance - held). Холд списывается (captured) полностью или частично,
+-- отменяется (reversed) или истекает (expired). Отклонённые авторизации хранятся с причиной.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS held NUMERIC(15,2) NOT NULL DEFAULT 0;
+
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_held_check;
+ALTER TABLE accounts ADD CONSTRAINT accounts_held_check CHECK (held >= 0);
+
+CREATE TABLE IF NOT EXISTS card_authorizations (
+    id              INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    card_id         INT NOT NULL REFERENCES cards(id),
+    account_id      INT NOT NULL REFERENCES accounts(id),
+    amount          NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    currency        CHAR(3) NOT NULL,
+    captured_amount NUMERIC(15,2) NOT NULL DEFAULT 0,
+    merchant        TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL,
+    decline_reason  TEXT,
+    auth_code       CHAR(6),
+    ledger_entry_id INT REFERENCES ledger_entries(id), -- проводка списания
+    expires_at      TIMESTAMP,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT card_authorizations_status_check
+        CHECK (status IN ('approved', 'declined', 'captured', 'reversed', 'expired')),
+    CONSTRAINT card_authorizations_captured_check CHECK (captured_amount <= amount)
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_authorizations_card ON card_authorizations(card_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_card_authorizations_expiry ON card_authorizations(expires_at) WHERE status = 'approved';
+
+-- Поиск карты по HMAC номера при авторизации
+CREATE INDEX IF NOT EXISTS idx_cards_hmac ON cards(hmac);
@@ -0,0 +1,23 @@
+-- Ограничения по карте: лимиты на операцию, день и месяц (в валюте счёта карты), разрешённые
+-- и запрещённые MCC, только интернет-платежи и одноразовая карта (блокируется после первого
+-- списания). Проверяются при авторизации и списании по карте; нет строки — ограничений нет.
+CREATE TABLE IF NOT EXISTS card_controls (
+    card_id           INT PRIMARY KEY REFERENCES cards(id),
+    transaction_limit NUMERIC(15,2) CHECK (transaction_limit > 0),
+    daily_limit       NUMERIC(15,2) CHECK (daily_limit > 0),
+    monthly_limit     NUMERIC(15,2) CHECK (monthly_limit > 0),
+    allowed_mccs      TEXT[] NOT NULL DEFAULT '{}',
+    blocked_mccs      TEXT[] NOT NULL DEFAULT '{}',
+    ecommerce_only    BOOLEAN NOT NULL DEFAULT false,
+    single_use        BOOLEAN NOT NULL DEFAULT false,
+    updated_at        TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Категория продавца и канал операции (ecommerce — интернет, pos — терминал)
+ALTER TABLE card_authorizations ADD COLUMN IF NOT EXISTS mcc     CHAR(4);
+ALTER TABLE card_authorizations ADD COLUMN IF NOT EXISTS channel TEXT;
+
+-- Одноразовая карта после списания блокируется с причиной used
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS cards_block_reason_check;
+ALTER TABLE cards ADD CONSTRAINT cards_block_reason_check
+    CHECK (block_reason IS NULL OR block_reason IN ('lost', 'stolen', 'compromised', 'closed', 'used'));
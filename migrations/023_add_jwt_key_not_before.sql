@@ -0,0 +1,5 @@
+-- Ключ публикуется в JWKS сразу после создания, а подписывать начинает с not_before:
+-- к этому моменту клиенты и другие экземпляры успевают получить его открытую часть.
+ALTER TABLE jwt_keys ADD COLUMN IF NOT EXISTS not_before TIMESTAMP;
+UPDATE jwt_keys SET not_before = created_at WHERE not_before IS NULL;
+ALTER TABLE jwt_keys ALTER COLUMN not_before SET NOT NULL;
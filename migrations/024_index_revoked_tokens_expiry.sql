@@ -0,0 +1,2 @@
+-- Для удаления записей об отозванных токенах после истечения их срока (задача revoked_tokens)
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires ON revoked_tokens(expires_at);
@@ -0,0 +1,5 @@
+-- Код из письма хранится как HMAC-SHA256 (hex), а не открытым текстом; выданные ранее коды
+-- недействительны и запрашиваются заново
+ALTER TABLE users ALTER COLUMN twofacode TYPE VARCHAR(64);
+UPDATE users SET twofacode = NULL, twofa_challenge_id = NULL, twofa_purpose = NULL, twofaexpires = NULL
+WHERE twofacode IS NOT NULL;